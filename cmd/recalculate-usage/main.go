@@ -0,0 +1,43 @@
+// Command recalculate-usage rebuilds the storage counters of every user from the
+// database and storage, e.g. after files were removed by hand or by the reconciler.
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/lib/pq"
+	"log"
+	"user-personalize/internal/config"
+	"user-personalize/internal/repository"
+	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/storage"
+)
+
+func main() {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", cfg.DbConfig.Host, cfg.DbConfig.Port, cfg.DbConfig.Username, cfg.DbConfig.Password, cfg.DbConfig.Dbname)
+
+	db, err := sql.Open(cfg.DbConfig.Driver, dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	blobStore, err := storage.NewBlobStore(cfg.StorageConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	usageUC := usecase.NewUsageUC(repository.NewUsageRepository(db), repository.NewUserRepository(db), repository.NewPhotosRepository(db), blobStore, cfg.QuotaConfig)
+
+	users, err := usageUC.Recalculate()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("recalculated the usage of %d users", users)
+}
@@ -0,0 +1,278 @@
+-- Brings a database created from the first database/DDL.sql, which only had users and
+-- photos, up to the current schema. Run it once, in order with any later migration:
+--
+--   psql "$DATABASE_URL" -v ON_ERROR_STOP=1 -f database/migrations/001_baseline_to_current.sql
+--
+-- A new database only needs database/DDL.sql. Every statement is guarded, so running the
+-- file again is harmless.
+begin;
+
+-- fuzzy user search matches usernames and display names by trigram similarity
+create extension if not exists pg_trgm;
+
+alter table users add column if not exists display_name varchar not null default '';
+-- admins work the moderation queue, there is no api to grant the role
+alter table users add column if not exists role varchar not null default 'user' check (role in ('user', 'admin'));
+alter table users add column if not exists suspended_at timestamp;
+alter table users add column if not exists deactivated_at timestamp;
+alter table users add column if not exists deletion_receipt_id varchar;
+-- created_at was nullable, the user list is paged by it
+update users set created_at = coalesce(updated_at, current_timestamp) where created_at is null;
+alter table users alter column created_at set default current_timestamp;
+alter table users alter column created_at set not null;
+
+create index if not exists users_deactivated_at_idx on users (deactivated_at) where deactivated_at is not null;
+-- the user list is keyset paginated by (created_at, id) or (username, id), the username
+-- prefix and email domain filters are case insensitive
+create index if not exists users_created_at_idx on users (created_at, id);
+create index if not exists users_username_idx on users (username, id);
+create index if not exists users_username_prefix_idx on users (lower(username) text_pattern_ops);
+create index if not exists users_email_domain_idx on users (lower(split_part(email, '@', 2)));
+create index if not exists users_username_trgm_idx on users using gin (username gin_trgm_ops);
+create index if not exists users_display_name_trgm_idx on users using gin (display_name gin_trgm_ops);
+
+create table if not exists blobs (
+                       hash varchar primary key,
+                       object_key varchar not null,
+                       size bigint not null,
+                       content_type varchar not null,
+                       ref_count int not null default 0,
+                       created_at timestamp not null default current_timestamp
+);
+
+-- the reconciler looks up the stored objects it lists page by page
+create index if not exists blobs_object_key_idx on blobs (object_key);
+
+-- the photo of a user now moves to the trash, only the photo out of the trash is unique
+alter table photos drop constraint if exists photos_user_id_key;
+alter table photos add column if not exists visibility varchar not null default 'private' check (visibility in ('public', 'followers', 'private'));
+alter table photos add column if not exists moderation_status varchar not null default 'visible' check (moderation_status in ('visible', 'flagged', 'hidden'));
+alter table photos add column if not exists blob_hash varchar references blobs(hash);
+alter table photos add column if not exists phash bigint;
+alter table photos add column if not exists crop_x int not null default 0;
+alter table photos add column if not exists crop_y int not null default 0;
+alter table photos add column if not exists crop_width int not null default 0;
+alter table photos add column if not exists crop_height int not null default 0;
+alter table photos add column if not exists width int not null default 0;
+alter table photos add column if not exists height int not null default 0;
+alter table photos add column if not exists blurhash varchar;
+alter table photos add column if not exists dominant_color varchar(7);
+alter table photos add column if not exists analysis_failed_at timestamp;
+alter table photos add column if not exists search_vector tsvector generated always as (
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(caption, '')), 'B')
+) stored;
+-- existing photos get the time of the migration, they had no timestamps
+alter table photos add column if not exists created_at timestamp not null default current_timestamp;
+alter table photos add column if not exists updated_at timestamp not null default current_timestamp;
+alter table photos add column if not exists deleted_at timestamp;
+
+-- a user has one photo at a time, any number of them may wait in the trash
+create unique index if not exists photos_user_id_idx on photos (user_id) where deleted_at is null;
+create index if not exists photos_deleted_at_idx on photos (deleted_at) where deleted_at is not null;
+
+-- one index per 8 bit slice of the perceptual hash: hashes that differ in fewer
+-- than 8 bits share at least one slice, so near-duplicates are found without a full scan
+create index if not exists photos_phash_band_0_idx on photos (((phash >> 56) & 255));
+create index if not exists photos_phash_band_1_idx on photos (((phash >> 48) & 255));
+create index if not exists photos_phash_band_2_idx on photos (((phash >> 40) & 255));
+create index if not exists photos_phash_band_3_idx on photos (((phash >> 32) & 255));
+create index if not exists photos_phash_band_4_idx on photos (((phash >> 24) & 255));
+create index if not exists photos_phash_band_5_idx on photos (((phash >> 16) & 255));
+create index if not exists photos_phash_band_6_idx on photos (((phash >> 8) & 255));
+create index if not exists photos_phash_band_7_idx on photos (((phash >> 0) & 255));
+
+create index if not exists photos_search_vector_idx on photos using gin (search_vector);
+create index if not exists photos_created_at_idx on photos (created_at desc, id desc);
+
+-- storage used by a user, every reference to a blob is charged its full size
+create table if not exists user_usage (
+                            user_id varchar primary key references users(id) on delete cascade,
+                            bytes bigint not null default 0,
+                            photo_count int not null default 0,
+                            updated_at timestamp not null default current_timestamp
+);
+
+-- earlier files of a photo, each one holds a reference on its blob like the photo does
+create table if not exists photo_versions (
+                                id varchar primary key,
+                                photo_id varchar not null references photos(id) on delete cascade,
+                                blob_hash varchar not null references blobs(hash),
+                                photo_url varchar not null,
+                                title varchar,
+                                caption varchar,
+                                phash bigint,
+                                crop_x int not null default 0,
+                                crop_y int not null default 0,
+                                crop_width int not null default 0,
+                                crop_height int not null default 0,
+                                width int not null default 0,
+                                height int not null default 0,
+                                blurhash varchar,
+                                dominant_color varchar(7),
+                                created_at timestamp not null
+);
+
+create index if not exists photo_versions_photo_id_idx on photo_versions (photo_id, created_at desc, id desc);
+
+create table if not exists tags (
+                      id varchar primary key,
+                      name varchar not null unique
+);
+
+create table if not exists photo_tags (
+                            photo_id varchar not null references photos(id) on delete cascade,
+                            tag_id varchar not null references tags(id) on delete cascade,
+                            primary key (photo_id, tag_id)
+);
+
+create index if not exists photo_tags_tag_id_idx on photo_tags(tag_id);
+
+-- the two (user, created_at, other user) indexes serve the follower and following
+-- lists, follows_pkey serves the "does the viewer follow the owner" lookups
+create table if not exists follows (
+                         follower_id varchar not null references users(id) on delete cascade,
+                         followee_id varchar not null references users(id) on delete cascade,
+                         created_at timestamp not null default current_timestamp,
+                         primary key (follower_id, followee_id),
+                         check (follower_id <> followee_id)
+);
+
+create index if not exists follows_follower_created_at_idx on follows(follower_id, created_at desc, followee_id desc);
+create index if not exists follows_followee_created_at_idx on follows(followee_id, created_at desc, follower_id desc);
+
+-- a block hides both users from each other, a mute only keeps the muted user out of the muter's feed
+create table if not exists blocks (
+                        blocker_id varchar not null references users(id) on delete cascade,
+                        blocked_id varchar not null references users(id) on delete cascade,
+                        created_at timestamp not null default current_timestamp,
+                        primary key (blocker_id, blocked_id),
+                        check (blocker_id <> blocked_id)
+);
+
+create index if not exists blocks_blocked_id_idx on blocks(blocked_id, blocker_id);
+
+create table if not exists mutes (
+                       muter_id varchar not null references users(id) on delete cascade,
+                       muted_id varchar not null references users(id) on delete cascade,
+                       created_at timestamp not null default current_timestamp,
+                       primary key (muter_id, muted_id),
+                       check (muter_id <> muted_id)
+);
+
+create table if not exists photo_likes (
+                             photo_id varchar not null references photos(id) on delete cascade,
+                             user_id varchar not null references users(id) on delete cascade,
+                             created_at timestamp not null default current_timestamp,
+                             primary key (photo_id, user_id)
+);
+
+create index if not exists photo_likes_user_id_idx on photo_likes(user_id);
+
+-- deleting a comment deletes the replies to it. user_id is cleared instead when its author is
+-- erased and others replied to it, so that erasing an account keeps their replies
+create table if not exists comments (
+                          id varchar primary key,
+                          photo_id varchar not null references photos(id) on delete cascade,
+                          user_id varchar references users(id) on delete cascade,
+                          parent_id varchar references comments(id) on delete cascade,
+                          body varchar not null,
+                          created_at timestamp not null default current_timestamp,
+                          updated_at timestamp not null default current_timestamp
+);
+
+create index if not exists comments_photo_id_idx on comments(photo_id, created_at);
+create index if not exists comments_parent_id_idx on comments(parent_id);
+-- threads are paged by their top level comment
+create index if not exists comments_photo_id_root_idx on comments(photo_id, created_at, id) where parent_id is null;
+
+create table if not exists uploads (
+                         id varchar primary key,
+                         user_id varchar not null,
+                         upload_length bigint not null,
+                         upload_offset bigint not null default 0,
+                         metadata varchar not null default '',
+                         photo_id varchar,
+                         expires_at timestamp not null,
+                         created_at timestamp not null default current_timestamp,
+                         foreign key (user_id) references users(id) on delete cascade
+);
+
+create index if not exists uploads_expires_at_idx on uploads(expires_at);
+
+create table if not exists direct_uploads (
+                                id varchar primary key,
+                                user_id varchar not null,
+                                object_key varchar not null,
+                                expires_at timestamp not null,
+                                created_at timestamp not null default current_timestamp,
+                                foreign key (user_id) references users(id) on delete cascade
+);
+
+create index if not exists direct_uploads_expires_at_idx on direct_uploads(expires_at);
+
+-- reports and the audit trail outlive the photos and users they are about, so they keep
+-- plain ids instead of foreign keys. A null reporter is the content classifier.
+create table if not exists reports (
+                         id varchar primary key,
+                         photo_id varchar not null,
+                         photo_owner_id varchar not null,
+                         reporter_id varchar,
+                         reason varchar not null check (reason in ('spam', 'nudity', 'violence', 'harassment', 'hate', 'copyright', 'illegal', 'other')),
+                         details varchar not null default '',
+                         status varchar not null default 'open' check (status in ('open', 'claimed', 'resolved')),
+                         claimed_by varchar,
+                         claimed_at timestamp,
+                         resolved_by varchar,
+                         resolved_at timestamp,
+                         resolution varchar check (resolution in ('dismiss', 'hide_photo', 'delete_photo', 'suspend_user')),
+                         created_at timestamp not null default current_timestamp
+);
+
+create index if not exists reports_status_created_at_idx on reports(status, created_at, id);
+create index if not exists reports_photo_id_idx on reports(photo_id) where status <> 'resolved';
+create unique index if not exists reports_open_reporter_idx on reports(photo_id, reporter_id) where status <> 'resolved';
+
+create table if not exists moderation_actions (
+                                    id varchar primary key,
+                                    actor_id varchar,
+                                    action varchar not null,
+                                    report_id varchar,
+                                    photo_id varchar,
+                                    user_id varchar,
+                                    note varchar not null default '',
+                                    created_at timestamp not null default current_timestamp
+);
+
+create index if not exists moderation_actions_created_at_idx on moderation_actions(created_at, id);
+
+-- zip archives of a user's photos that are too large to stream on request and the
+-- json documents of everything stored about a user
+create table if not exists exports (
+                         id varchar primary key,
+                         user_id varchar not null references users(id) on delete cascade,
+                         kind varchar not null default 'photos' check (kind in ('photos', 'personal_data')),
+                         status varchar not null default 'pending' check (status in ('pending', 'running', 'done', 'failed')),
+                         object_key varchar,
+                         size bigint not null default 0,
+                         error varchar,
+                         created_at timestamp not null default current_timestamp,
+                         started_at timestamp,
+                         completed_at timestamp,
+                         expires_at timestamp
+);
+
+create index if not exists exports_status_created_at_idx on exports (status, created_at);
+create index if not exists exports_user_id_idx on exports (user_id, created_at desc);
+
+-- proof that an account was erased, subject is the sha-256 of the erased user id so the
+-- receipt itself holds no personal data
+create table if not exists erasure_receipts (
+                                  id varchar primary key,
+                                  subject varchar not null,
+                                  receipt varchar not null,
+                                  signature varchar not null,
+                                  erased_at timestamp not null
+);
+
+commit;
@@ -5,7 +5,9 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/joho/godotenv"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -20,12 +22,23 @@ type DbConfig struct {
 
 type ApiConfig struct {
 	ApiPort string
+	BaseUrl string
 }
 
 type Config struct {
-	DbConfig  DbConfig
-	ApiConfig ApiConfig
-	JwtConfig JwtConfig
+	DbConfig              DbConfig
+	ApiConfig             ApiConfig
+	JwtConfig             JwtConfig
+	StorageConfig         StorageConfig
+	ShareConfig           ShareConfig
+	ReconcileConfig       ReconcileConfig
+	UploadConfig          UploadConfig
+	TransformConfig       TransformConfig
+	TrashConfig           TrashConfig
+	QuotaConfig           QuotaConfig
+	ExportConfig          ExportConfig
+	AccountDeletionConfig AccountDeletionConfig
+	RateLimitConfig       RateLimitConfig
 }
 
 type JwtConfig struct {
@@ -34,6 +47,92 @@ type JwtConfig struct {
 	JwtExpiredTime   time.Duration
 }
 
+const (
+	StorageDriverLocal = "local"
+	StorageDriverS3    = "s3"
+)
+
+type StorageConfig struct {
+	Driver      string
+	LocalDir    string
+	S3Endpoint  string
+	S3AccessKey string
+	S3SecretKey string
+	S3Bucket    string
+	S3Region    string
+	S3UseSSL    bool
+}
+
+type ShareConfig struct {
+	SigningKey         []byte
+	DefaultExpiredTime time.Duration
+	MaxExpiredTime     time.Duration
+}
+
+type ReconcileConfig struct {
+	Interval      time.Duration
+	GracePeriod   time.Duration
+	DeleteOrphans bool
+}
+
+type UploadConfig struct {
+	MaxSize            int64
+	ExpiredTime        time.Duration
+	GcInterval         time.Duration
+	PresignExpiredTime time.Duration
+}
+
+// TransformConfig bounds the work on-the-fly image transforms may take.
+type TransformConfig struct {
+	Concurrency  int
+	MaxPixels    int64
+	QueueTimeout time.Duration
+}
+
+// TrashConfig sets how long deleted photos can be restored before they are purged.
+type TrashConfig struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+// Quota limits what a user may store, zero means unlimited.
+type Quota struct {
+	MaxBytes  int64
+	MaxPhotos int
+}
+
+// QuotaConfig holds the quota of every plan, keyed by user role.
+type QuotaConfig struct {
+	Plans map[string]Quota
+}
+
+// ExportConfig decides which photo exports are streamed right away and which become
+// jobs whose archive can be downloaded until ExpiredTime has passed.
+type ExportConfig struct {
+	AsyncThreshold int64
+	WorkerInterval time.Duration
+	ExpiredTime    time.Duration
+}
+
+// AccountDeletionConfig sets how long a deleted account can be reactivated before it is
+// erased and the key erasure receipts are signed with.
+type AccountDeletionConfig struct {
+	GracePeriod       time.Duration
+	PurgeInterval     time.Duration
+	ReceiptSigningKey []byte
+}
+
+// RateLimit lets a caller make Requests requests per Window, bursts included.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitConfig sets the limits of the endpoints that are expensive to serve.
+type RateLimitConfig struct {
+	UserSearch RateLimit
+}
+
 func NewConfig() (*Config, error) {
 	cfg := &Config{}
 	err := cfg.ConfigConfiguration()
@@ -63,6 +162,7 @@ func (c *Config) ConfigConfiguration() error {
 	// config server
 	c.ApiConfig = ApiConfig{
 		ApiPort: os.Getenv("API_PORT"),
+		BaseUrl: strings.TrimSuffix(os.Getenv("API_BASE_URL"), "/"),
 	}
 
 	// config jwt
@@ -77,6 +177,206 @@ func (c *Config) ConfigConfiguration() error {
 		JwtSigningMethod: jwt.SigningMethodHS256,
 	}
 
+	// config storage
+	c.StorageConfig = StorageConfig{
+		Driver:      os.Getenv("STORAGE_DRIVER"),
+		LocalDir:    os.Getenv("STORAGE_LOCAL_DIR"),
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("S3_SECRET_KEY"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3Region:    os.Getenv("S3_REGION"),
+		S3UseSSL:    os.Getenv("S3_USE_SSL") == "true",
+	}
+
+	if c.StorageConfig.Driver == "" {
+		c.StorageConfig.Driver = StorageDriverLocal
+	}
+
+	if c.StorageConfig.LocalDir == "" {
+		c.StorageConfig.LocalDir = "./images"
+	}
+
+	if c.StorageConfig.Driver == StorageDriverS3 && (c.StorageConfig.S3Endpoint == "" || c.StorageConfig.S3AccessKey == "" || c.StorageConfig.S3SecretKey == "" || c.StorageConfig.S3Bucket == "") {
+		return fmt.Errorf("missing required s3 storage environment variables")
+	}
+
+	// config share url
+	shareExpired, err := strconv.Atoi(os.Getenv("SHARE_URL_EXPIRED_TIME"))
+	if err != nil {
+		return fmt.Errorf("config :" + err.Error())
+	}
+
+	shareMaxExpired, err := strconv.Atoi(os.Getenv("SHARE_URL_MAX_EXPIRED_TIME"))
+	if err != nil {
+		return fmt.Errorf("config :" + err.Error())
+	}
+
+	c.ShareConfig = ShareConfig{
+		SigningKey:         []byte(os.Getenv("SHARE_URL_SECRET")),
+		DefaultExpiredTime: time.Duration(shareExpired) * time.Hour,
+		MaxExpiredTime:     time.Duration(shareMaxExpired) * time.Hour,
+	}
+
+	if len(c.ShareConfig.SigningKey) == 0 || c.ShareConfig.DefaultExpiredTime == 0 || c.ShareConfig.MaxExpiredTime < c.ShareConfig.DefaultExpiredTime {
+		return fmt.Errorf("missing required share url environment variables")
+	}
+
+	// config reconciler, a negative interval disables it
+	reconcileInterval, _ := strconv.Atoi(os.Getenv("RECONCILE_INTERVAL"))
+	if reconcileInterval == 0 {
+		reconcileInterval = 60
+	}
+
+	reconcileGrace, _ := strconv.Atoi(os.Getenv("RECONCILE_GRACE_PERIOD"))
+	if reconcileGrace == 0 {
+		reconcileGrace = 60
+	}
+
+	c.ReconcileConfig = ReconcileConfig{
+		Interval:      time.Duration(reconcileInterval) * time.Minute,
+		GracePeriod:   time.Duration(reconcileGrace) * time.Minute,
+		DeleteOrphans: os.Getenv("RECONCILE_DELETE") == "true",
+	}
+
+	// config resumable upload
+	uploadMaxSize, _ := strconv.ParseInt(os.Getenv("UPLOAD_MAX_SIZE"), 10, 64)
+	if uploadMaxSize == 0 {
+		uploadMaxSize = 50 << 20
+	}
+
+	uploadExpired, _ := strconv.Atoi(os.Getenv("UPLOAD_EXPIRED_TIME"))
+	if uploadExpired == 0 {
+		uploadExpired = 24
+	}
+
+	uploadGcInterval, _ := strconv.Atoi(os.Getenv("UPLOAD_GC_INTERVAL"))
+	if uploadGcInterval == 0 {
+		uploadGcInterval = 60
+	}
+
+	uploadPresignExpired, _ := strconv.Atoi(os.Getenv("UPLOAD_URL_EXPIRED_TIME"))
+	if uploadPresignExpired == 0 {
+		uploadPresignExpired = 15
+	}
+
+	c.UploadConfig = UploadConfig{
+		MaxSize:            uploadMaxSize,
+		ExpiredTime:        time.Duration(uploadExpired) * time.Hour,
+		GcInterval:         time.Duration(uploadGcInterval) * time.Minute,
+		PresignExpiredTime: time.Duration(uploadPresignExpired) * time.Minute,
+	}
+
+	// config image transform
+	transformConcurrency, _ := strconv.Atoi(os.Getenv("TRANSFORM_CONCURRENCY"))
+	if transformConcurrency <= 0 {
+		transformConcurrency = runtime.NumCPU()
+	}
+
+	transformMaxPixels, _ := strconv.ParseInt(os.Getenv("TRANSFORM_MAX_PIXELS"), 10, 64)
+	if transformMaxPixels == 0 {
+		transformMaxPixels = 40_000_000
+	}
+
+	transformQueueTimeout, _ := strconv.Atoi(os.Getenv("TRANSFORM_QUEUE_TIMEOUT"))
+	if transformQueueTimeout == 0 {
+		transformQueueTimeout = 10
+	}
+
+	c.TransformConfig = TransformConfig{
+		Concurrency:  transformConcurrency,
+		MaxPixels:    transformMaxPixels,
+		QueueTimeout: time.Duration(transformQueueTimeout) * time.Second,
+	}
+
+	// config trash, purge interval in minutes, a negative one disables the purge
+	trashRetention, _ := strconv.Atoi(os.Getenv("TRASH_RETENTION"))
+	if trashRetention == 0 {
+		trashRetention = 30
+	}
+
+	trashPurgeInterval, _ := strconv.Atoi(os.Getenv("TRASH_PURGE_INTERVAL"))
+	if trashPurgeInterval == 0 {
+		trashPurgeInterval = 60
+	}
+
+	c.TrashConfig = TrashConfig{
+		Retention:     time.Duration(trashRetention) * 24 * time.Hour,
+		PurgeInterval: time.Duration(trashPurgeInterval) * time.Minute,
+	}
+
+	// config quotas per role, an empty admin quota is unlimited
+	quotaUserBytes, _ := strconv.ParseInt(os.Getenv("QUOTA_USER_MAX_BYTES"), 10, 64)
+	if quotaUserBytes == 0 {
+		quotaUserBytes = 100 << 20
+	}
+
+	quotaUserPhotos, _ := strconv.Atoi(os.Getenv("QUOTA_USER_MAX_PHOTOS"))
+	if quotaUserPhotos == 0 {
+		quotaUserPhotos = 50
+	}
+
+	quotaAdminBytes, _ := strconv.ParseInt(os.Getenv("QUOTA_ADMIN_MAX_BYTES"), 10, 64)
+	quotaAdminPhotos, _ := strconv.Atoi(os.Getenv("QUOTA_ADMIN_MAX_PHOTOS"))
+
+	c.QuotaConfig = QuotaConfig{Plans: map[string]Quota{
+		"user":  {MaxBytes: quotaUserBytes, MaxPhotos: quotaUserPhotos},
+		"admin": {MaxBytes: quotaAdminBytes, MaxPhotos: quotaAdminPhotos},
+	}}
+
+	// config photo export, threshold in bytes, worker interval in seconds, expiry in hours
+	exportThreshold, _ := strconv.ParseInt(os.Getenv("EXPORT_ASYNC_THRESHOLD"), 10, 64)
+	if exportThreshold == 0 {
+		exportThreshold = 100 << 20
+	}
+
+	exportInterval, _ := strconv.Atoi(os.Getenv("EXPORT_WORKER_INTERVAL"))
+	if exportInterval == 0 {
+		exportInterval = 10
+	}
+
+	exportExpired, _ := strconv.Atoi(os.Getenv("EXPORT_EXPIRED_TIME"))
+	if exportExpired == 0 {
+		exportExpired = 24
+	}
+
+	c.ExportConfig = ExportConfig{
+		AsyncThreshold: exportThreshold,
+		WorkerInterval: time.Duration(exportInterval) * time.Second,
+		ExpiredTime:    time.Duration(exportExpired) * time.Hour,
+	}
+
+	// config account deletion, purge interval in minutes, a negative one disables the purge
+	deletionGracePeriod, _ := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"))
+	if deletionGracePeriod == 0 {
+		deletionGracePeriod = 30
+	}
+
+	deletionPurgeInterval, _ := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_PURGE_INTERVAL"))
+	if deletionPurgeInterval == 0 {
+		deletionPurgeInterval = 60
+	}
+
+	c.AccountDeletionConfig = AccountDeletionConfig{
+		GracePeriod:       time.Duration(deletionGracePeriod) * 24 * time.Hour,
+		PurgeInterval:     time.Duration(deletionPurgeInterval) * time.Minute,
+		ReceiptSigningKey: []byte(os.Getenv("ERASURE_RECEIPT_SECRET")),
+	}
+
+	if len(c.AccountDeletionConfig.ReceiptSigningKey) == 0 {
+		return fmt.Errorf("missing required erasure receipt environment variables")
+	}
+
+	// config rate limits
+	userSearchRateLimit, _ := strconv.Atoi(os.Getenv("USER_SEARCH_RATE_LIMIT"))
+	if userSearchRateLimit <= 0 {
+		userSearchRateLimit = 30
+	}
+
+	c.RateLimitConfig = RateLimitConfig{
+		UserSearch: RateLimit{Requests: userSearchRateLimit, Window: time.Minute},
+	}
+
 	if c.DbConfig.Dbname == "" || c.DbConfig.Password == "" || c.DbConfig.Username == "" || c.DbConfig.Host == "" || c.DbConfig.Port == "" || c.DbConfig.Driver == "" || c.ApiConfig.ApiPort == "" || c.JwtConfig.JwtSecretKey == nil || c.JwtConfig.JwtSigningMethod == nil || c.JwtConfig.JwtExpiredTime == 0 {
 		return fmt.Errorf("missing required environment variables")
 	}
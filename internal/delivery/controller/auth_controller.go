@@ -23,6 +23,7 @@ func NewAuthController(authUC usecase.AuthUC, rg *gin.RouterGroup) *AuthControll
 func (a *AuthController) RouteGroup() {
 	a.rg.POST("/users/login", a.Login)
 	a.rg.POST("/users/register", a.Register)
+	a.rg.POST("/users/reactivate", a.Reactivate)
 }
 
 func (a *AuthController) Login(ctx *gin.Context) {
@@ -41,6 +42,16 @@ func (a *AuthController) Login(ctx *gin.Context) {
 			response.ErrorResponse(ctx, http.StatusNotFound, "user not found")
 			return
 		}
+
+		if errors.Is(err, exception.ForbiddenErr) {
+			response.ErrorResponse(ctx, http.StatusForbidden, "account is suspended")
+			return
+		}
+
+		if errors.Is(err, exception.DeactivatedErr) {
+			response.ErrorResponse(ctx, http.StatusForbidden, "account is scheduled for deletion, reactivate it to log in")
+			return
+		}
 		response.ErrorResponse(ctx, http.StatusUnauthorized, "login failed")
 		return
 	}
@@ -54,6 +65,36 @@ func (a *AuthController) Login(ctx *gin.Context) {
 	ctx.JSON(http.StatusAccepted, webResponse)
 }
 
+func (a *AuthController) Reactivate(ctx *gin.Context) {
+	var request dto.ReactivateRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "request invalid")
+		return
+	}
+
+	loginRes, err := a.authUC.Reactivate(request)
+	if err != nil {
+		log.Println(err)
+		switch {
+		case errors.Is(err, exception.NotFoundErr):
+			response.ErrorResponse(ctx, http.StatusNotFound, "user not found")
+		case errors.Is(err, exception.ForbiddenErr):
+			response.ErrorResponse(ctx, http.StatusForbidden, "account is suspended")
+		case errors.Is(err, exception.ConflictErr):
+			response.ErrorResponse(ctx, http.StatusConflict, "account is not scheduled for deletion")
+		case errors.Is(err, exception.ExpiredErr):
+			response.ErrorResponse(ctx, http.StatusGone, "account can no longer be reactivated")
+		default:
+			response.ErrorResponse(ctx, http.StatusUnauthorized, "reactivation failed")
+		}
+		return
+	}
+
+	response.SuccessResponse(ctx, "account reactivated", loginRes)
+}
+
 func (a *AuthController) Register(ctx *gin.Context) {
 	var req dto.UserRequest
 	err := ctx.BindJSON(&req)
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/response"
+)
+
+type BlockController struct {
+	blockUC usecase.BlockUC
+	rg      *gin.RouterGroup
+}
+
+func NewBlockController(blockUC usecase.BlockUC, rg *gin.RouterGroup) *BlockController {
+	return &BlockController{blockUC: blockUC, rg: rg}
+}
+
+func (b *BlockController) RouteGroup() {
+	b.rg.PUT("/users/:userId/block", b.Block)
+	b.rg.DELETE("/users/:userId/block", b.Unblock)
+	b.rg.PUT("/users/:userId/mute", b.Mute)
+	b.rg.DELETE("/users/:userId/mute", b.Unmute)
+}
+
+func (b *BlockController) Block(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	block, err := b.blockUC.Block(userId, ctx.Param("userId"))
+	if err != nil {
+		b.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success block user", block)
+}
+
+func (b *BlockController) Unblock(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	block, err := b.blockUC.Unblock(userId, ctx.Param("userId"))
+	if err != nil {
+		b.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success unblock user", block)
+}
+
+func (b *BlockController) Mute(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	mute, err := b.blockUC.Mute(userId, ctx.Param("userId"))
+	if err != nil {
+		b.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success mute user", mute)
+}
+
+func (b *BlockController) Unmute(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	mute, err := b.blockUC.Unmute(userId, ctx.Param("userId"))
+	if err != nil {
+		b.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success unmute user", mute)
+}
+
+func (b *BlockController) error(ctx *gin.Context, err error) {
+	log.Println(err)
+	switch {
+	case errors.Is(err, exception.NotFoundErr):
+		response.ErrorResponse(ctx, http.StatusNotFound, "user not found")
+	case errors.Is(err, exception.InvalidErr):
+		response.ErrorResponse(ctx, http.StatusBadRequest, "you cannot block or mute yourself")
+	default:
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/response"
+)
+
+type CommentController struct {
+	commentUC usecase.CommentUC
+	rg        *gin.RouterGroup
+}
+
+func NewCommentController(commentUC usecase.CommentUC, rg *gin.RouterGroup) *CommentController {
+	return &CommentController{commentUC: commentUC, rg: rg}
+}
+
+func (c *CommentController) RouteGroup() {
+	c.rg.GET("/photos/:photoId/comments", c.GetComments)
+	c.rg.POST("/photos/:photoId/comments", c.CreateComment)
+	c.rg.PUT("/photos/:photoId/comments/:commentId", c.UpdateComment)
+	c.rg.DELETE("/photos/:photoId/comments/:commentId", c.DeleteComment)
+}
+
+// GetComments is reachable without a bearer token for public photos, see middleware.optionalAuthPaths.
+func (c *CommentController) GetComments(ctx *gin.Context) {
+	var viewerId string
+	if value, exists := ctx.Get("claims"); exists {
+		viewerId = value.(*dto.CustomClaims).UserId
+	}
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	comments, err := c.commentUC.GetComments(viewerId, ctx.Param("photoId"), page)
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	response.PagedResponse(ctx, "success get comments", comments.Items, comments.Page)
+}
+
+func (c *CommentController) CreateComment(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	var request dto.CommentRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "request invalid")
+		return
+	}
+
+	comment, err := c.commentUC.CreateComment(userId, ctx.Param("photoId"), request)
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	response.CreatedResponse(ctx, "success create comment", comment)
+}
+
+func (c *CommentController) UpdateComment(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	var request dto.CommentRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "request invalid")
+		return
+	}
+
+	comment, err := c.commentUC.UpdateComment(userId, ctx.Param("photoId"), ctx.Param("commentId"), request)
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success update comment", comment)
+}
+
+func (c *CommentController) DeleteComment(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	err := c.commentUC.DeleteComment(userId, ctx.Param("photoId"), ctx.Param("commentId"))
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success delete comment", nil)
+}
+
+func (c *CommentController) error(ctx *gin.Context, err error) {
+	log.Println(err)
+	switch {
+	case errors.Is(err, exception.NotFoundErr):
+		response.ErrorResponse(ctx, http.StatusNotFound, "photo or comment not found")
+	case errors.Is(err, exception.ForbiddenErr):
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+	case errors.Is(err, exception.InvalidErr):
+		response.ErrorResponse(ctx, http.StatusBadRequest, "comment or page is not valid")
+	default:
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/response"
+)
+
+type ExportController struct {
+	exportUC usecase.ExportUC
+	rg       *gin.RouterGroup
+}
+
+func NewExportController(exportUC usecase.ExportUC, rg *gin.RouterGroup) *ExportController {
+	return &ExportController{exportUC: exportUC, rg: rg}
+}
+
+func (e *ExportController) RouteGroup() {
+	e.rg.GET("/photos/export", e.ExportPhotos)
+	e.rg.GET("/photos/exports/:exportId", e.GetExport)
+	e.rg.GET("/photos/exports/:exportId/download", e.DownloadExport)
+	e.rg.POST("/users/me/data-export", e.ExportPersonalData)
+	e.rg.GET("/users/me/data-exports/:exportId", e.GetPersonalDataExport)
+	e.rg.GET("/users/me/data-exports/:exportId/download", e.DownloadPersonalDataExport)
+}
+
+func (e *ExportController) ExportPhotos(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	export, err := e.exportUC.ExportPhotos(userId)
+	if err != nil {
+		e.error(ctx, err)
+		return
+	}
+
+	if export.Job != nil {
+		ctx.Header("Location", "/photos/exports/"+export.Job.Id)
+		response.AcceptedResponse(ctx, "export is being prepared", export.Job)
+		return
+	}
+	defer export.Archive.Close()
+
+	// the archive is written while it is sent, an error halfway can only cut the response short
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename))
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(http.StatusOK)
+
+	_, err = io.Copy(ctx.Writer, export.Archive)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (e *ExportController) GetExport(ctx *gin.Context) {
+	e.getExport(ctx, entity.ExportKindPhotos)
+}
+
+func (e *ExportController) DownloadExport(ctx *gin.Context) {
+	e.download(ctx, entity.ExportKindPhotos, "photos-%s.zip")
+}
+
+func (e *ExportController) ExportPersonalData(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	export, err := e.exportUC.ExportPersonalData(userId)
+	if err != nil {
+		e.error(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", "/users/me/data-exports/"+export.Id)
+	response.AcceptedResponse(ctx, "personal data export is being prepared", export)
+}
+
+func (e *ExportController) GetPersonalDataExport(ctx *gin.Context) {
+	e.getExport(ctx, entity.ExportKindPersonalData)
+}
+
+func (e *ExportController) DownloadPersonalDataExport(ctx *gin.Context) {
+	e.download(ctx, entity.ExportKindPersonalData, "personal-data-%s.json")
+}
+
+func (e *ExportController) getExport(ctx *gin.Context, kind string) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	export, err := e.exportUC.GetExport(userId, kind, ctx.Param("exportId"))
+	if err != nil {
+		e.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success get export", export)
+}
+
+// download serves the file of a finished export, filename is a format for the export id.
+func (e *ExportController) download(ctx *gin.Context, kind string, filename string) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	exportId := ctx.Param("exportId")
+	content, err := e.exportUC.GetExportContent(userId, kind, exportId)
+	if err != nil {
+		e.error(ctx, err)
+		return
+	}
+	defer content.Content.Close()
+
+	ctx.Header("Content-Type", content.ContentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf(filename, exportId)))
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Cache-Control", "private, no-cache")
+	ctx.Header("ETag", content.ETag)
+
+	http.ServeContent(ctx.Writer, ctx.Request, "", content.LastModified, content.Content)
+}
+
+func (e *ExportController) error(ctx *gin.Context, err error) {
+	log.Println(err)
+	switch {
+	case errors.Is(err, exception.NotFoundErr):
+		response.ErrorResponse(ctx, http.StatusNotFound, "export not found")
+	case errors.Is(err, exception.ConflictErr):
+		response.ErrorResponse(ctx, http.StatusConflict, "export is not ready")
+	case errors.Is(err, exception.ExpiredErr):
+		response.ErrorResponse(ctx, http.StatusGone, "export expired")
+	default:
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+	}
+}
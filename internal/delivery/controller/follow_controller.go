@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/response"
+)
+
+type FollowController struct {
+	followUC usecase.FollowUC
+	rg       *gin.RouterGroup
+}
+
+func NewFollowController(followUC usecase.FollowUC, rg *gin.RouterGroup) *FollowController {
+	return &FollowController{followUC: followUC, rg: rg}
+}
+
+func (f *FollowController) RouteGroup() {
+	f.rg.PUT("/users/:userId/follow", f.Follow)
+	f.rg.DELETE("/users/:userId/follow", f.Unfollow)
+	f.rg.GET("/users/:userId/followers", f.GetFollowers)
+	f.rg.GET("/users/:userId/following", f.GetFollowing)
+}
+
+func (f *FollowController) Follow(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	follow, err := f.followUC.Follow(userId, ctx.Param("userId"))
+	if err != nil {
+		f.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success follow user", follow)
+}
+
+func (f *FollowController) Unfollow(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	follow, err := f.followUC.Unfollow(userId, ctx.Param("userId"))
+	if err != nil {
+		f.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success unfollow user", follow)
+}
+
+func (f *FollowController) GetFollowers(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	followers, err := f.followUC.GetFollowers(userId, ctx.Param("userId"), page)
+	if err != nil {
+		f.error(ctx, err)
+		return
+	}
+
+	response.PagedResponse(ctx, "success get followers", followers.Items, followers.Page)
+}
+
+func (f *FollowController) GetFollowing(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	following, err := f.followUC.GetFollowing(userId, ctx.Param("userId"), page)
+	if err != nil {
+		f.error(ctx, err)
+		return
+	}
+
+	response.PagedResponse(ctx, "success get following", following.Items, following.Page)
+}
+
+func (f *FollowController) error(ctx *gin.Context, err error) {
+	log.Println(err)
+	switch {
+	case errors.Is(err, exception.NotFoundErr):
+		response.ErrorResponse(ctx, http.StatusNotFound, "user not found")
+	case errors.Is(err, exception.InvalidErr):
+		response.ErrorResponse(ctx, http.StatusBadRequest, "request invalid")
+	default:
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+	}
+}
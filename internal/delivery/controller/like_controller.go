@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/response"
+)
+
+type LikeController struct {
+	likeUC usecase.LikeUC
+	rg     *gin.RouterGroup
+}
+
+func NewLikeController(likeUC usecase.LikeUC, rg *gin.RouterGroup) *LikeController {
+	return &LikeController{likeUC: likeUC, rg: rg}
+}
+
+// RouteGroup maps liking to PUT and unliking to DELETE, so retried requests are harmless.
+func (l *LikeController) RouteGroup() {
+	l.rg.PUT("/photos/:photoId/like", l.LikePhoto)
+	l.rg.DELETE("/photos/:photoId/like", l.UnlikePhoto)
+}
+
+func (l *LikeController) LikePhoto(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	like, err := l.likeUC.LikePhoto(userId, ctx.Param("photoId"))
+	if err != nil {
+		l.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success like photo", like)
+}
+
+func (l *LikeController) UnlikePhoto(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	like, err := l.likeUC.UnlikePhoto(userId, ctx.Param("photoId"))
+	if err != nil {
+		l.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success unlike photo", like)
+}
+
+func (l *LikeController) error(ctx *gin.Context, err error) {
+	log.Println(err)
+	if errors.Is(err, exception.NotFoundErr) {
+		response.ErrorResponse(ctx, http.StatusNotFound, "photo not found")
+		return
+	}
+
+	response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+}
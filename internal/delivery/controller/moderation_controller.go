@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/response"
+)
+
+type ModerationController struct {
+	moderationUC usecase.ModerationUC
+	rg           *gin.RouterGroup
+}
+
+func NewModerationController(moderationUC usecase.ModerationUC, rg *gin.RouterGroup) *ModerationController {
+	return &ModerationController{moderationUC: moderationUC, rg: rg}
+}
+
+func (m *ModerationController) RouteGroup() {
+	m.rg.POST("/photos/:photoId/report", m.ReportPhoto)
+	m.rg.GET("/moderation/reports", m.GetReports)
+	m.rg.POST("/moderation/reports/:reportId/claim", m.ClaimReport)
+	m.rg.POST("/moderation/reports/:reportId/resolve", m.ResolveReport)
+	m.rg.GET("/moderation/actions", m.GetActions)
+}
+
+func (m *ModerationController) ReportPhoto(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	var request dto.ReportRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "request invalid")
+		return
+	}
+
+	report, err := m.moderationUC.ReportPhoto(userId, ctx.Param("photoId"), request)
+	if err != nil {
+		m.error(ctx, err)
+		return
+	}
+
+	response.CreatedResponse(ctx, "success report photo", report)
+}
+
+func (m *ModerationController) GetReports(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	reports, err := m.moderationUC.GetReports(userId, ctx.Query("status"), page)
+	if err != nil {
+		m.error(ctx, err)
+		return
+	}
+
+	response.PagedResponse(ctx, "success get reports", reports.Items, reports.Page)
+}
+
+func (m *ModerationController) ClaimReport(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	report, err := m.moderationUC.ClaimReport(userId, ctx.Param("reportId"))
+	if err != nil {
+		m.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success claim report", report)
+}
+
+func (m *ModerationController) ResolveReport(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	var request dto.ResolveReportRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "request invalid")
+		return
+	}
+
+	report, err := m.moderationUC.ResolveReport(userId, ctx.Param("reportId"), request)
+	if err != nil {
+		m.error(ctx, err)
+		return
+	}
+
+	response.SuccessResponse(ctx, "success resolve report", report)
+}
+
+func (m *ModerationController) GetActions(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	actions, err := m.moderationUC.GetActions(userId, page)
+	if err != nil {
+		m.error(ctx, err)
+		return
+	}
+
+	response.PagedResponse(ctx, "success get moderation actions", actions.Items, actions.Page)
+}
+
+func (m *ModerationController) error(ctx *gin.Context, err error) {
+	log.Println(err)
+	switch {
+	case errors.Is(err, exception.NotFoundErr):
+		response.ErrorResponse(ctx, http.StatusNotFound, "photo or report not found")
+	case errors.Is(err, exception.ForbiddenErr):
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+	case errors.Is(err, exception.InvalidErr):
+		response.ErrorResponse(ctx, http.StatusBadRequest, "request invalid")
+	case errors.Is(err, exception.ConflictErr):
+		response.ErrorResponse(ctx, http.StatusConflict, "report is already open or claimed")
+	default:
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+	}
+}
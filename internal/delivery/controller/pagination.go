@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"user-personalize/internal/model/dto"
+)
+
+// parsePage reads the cursor and limit query parameters of keyset paginated lists.
+func parsePage(ctx *gin.Context) (dto.PageRequest, error) {
+	page := dto.PageRequest{Cursor: ctx.Query("cursor")}
+	if ctx.Query("limit") != "" {
+		limit, err := strconv.Atoi(ctx.Query("limit"))
+		if err != nil {
+			return dto.PageRequest{}, err
+		}
+		page.Limit = limit
+	}
+
+	return page, nil
+}
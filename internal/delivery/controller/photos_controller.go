@@ -4,11 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"log"
 	"net/http"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 	"user-personalize/internal/model/dto"
 	"user-personalize/internal/model/entity"
 	"user-personalize/internal/usecase"
@@ -30,6 +30,21 @@ func (p *PhotosController) RouteGroup() {
 	p.rg.PUT("/photos/:photoId", p.UpdatePhotos)
 	p.rg.DELETE("/photos/:photoId", p.DeletePhotos)
 	p.rg.GET("photos", p.GetPhotos)
+	p.rg.GET("/photos/:photoId/content", p.GetPhotoContent)
+	p.rg.GET("/photos/:photoId/content/:variant", p.GetPhotoContent)
+	p.rg.GET("/photos/search", p.SearchPhotos)
+	p.rg.GET("/photos/trash", p.GetTrash)
+	p.rg.DELETE("/photos/trash", p.EmptyTrash)
+	p.rg.DELETE("/photos/trash/:photoId", p.DeleteTrashedPhoto)
+	p.rg.POST("/photos/:photoId/restore", p.RestorePhoto)
+	p.rg.GET("/photos/:photoId/versions", p.GetPhotoVersions)
+	p.rg.POST("/photos/:photoId/versions/:versionId/revert", p.RevertPhotoVersion)
+	p.rg.DELETE("/photos/:photoId/versions/:versionId", p.DeletePhotoVersion)
+	p.rg.GET("/photos/:photoId/similar", p.GetSimilarPhotos)
+	p.rg.POST("/photos/:photoId/share", p.SharePhoto)
+	p.rg.GET("/shared/photos/:photoId", p.GetSharedPhotoContent)
+	p.rg.GET("/users/:userId/photos", p.GetUserPhotos)
+	p.rg.GET("/feed", p.GetFeed)
 }
 
 func (p *PhotosController) UploadPhotos(ctx *gin.Context) {
@@ -49,20 +64,30 @@ func (p *PhotosController) UploadPhotos(ctx *gin.Context) {
 		return
 	}
 
+	content, err := file.Open()
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	defer content.Close()
+
 	title := ctx.Request.FormValue("title")
 	caption := ctx.Request.FormValue("caption")
 
-	id := uuid.NewString()
-	name := strings.Replace(id, "-", "", -1)
-	ext := filepath.Ext(file.Filename)
-	imageName := fmt.Sprintf("%s%s", name, ext)
-	imageUrl := fmt.Sprintf("./images/%s", imageName)
+	crop, err := p.parseCrop(ctx)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "crop is not valid")
+		return
+	}
 
 	request := entity.Photos{
-		Title:    title,
-		Caption:  caption,
-		PhotoUrl: imageUrl,
-		UserId:   userId,
+		Title:      title,
+		Caption:    caption,
+		UserId:     userId,
+		Visibility: ctx.Request.FormValue("visibility"),
+		Tags:       p.parseTags(ctx),
 	}
 
 	_, err = p.photoUC.GetPhotosByUserId(userId)
@@ -72,19 +97,32 @@ func (p *PhotosController) UploadPhotos(ctx *gin.Context) {
 		return
 	}
 
-	photos, err := p.photoUC.SavePhotos(request)
+	photos, err := p.photoUC.SavePhotos(request, dto.PhotoFile{
+		Filename:    file.Filename,
+		ContentType: file.Header.Get("Content-Type"),
+		Size:        file.Size,
+		Content:     content,
+	}, crop)
 	if err != nil {
 		log.Println(err)
-		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
-		return
-	}
+		if errors.Is(err, exception.InvalidErr) {
+			response.ErrorResponse(ctx, http.StatusBadRequest, "crop, visibility or tags are not valid")
+			return
+		}
+
+		if errors.Is(err, exception.UnsupportedErr) {
+			response.ErrorResponse(ctx, http.StatusUnsupportedMediaType, "only jpeg, png, gif and webp photos are accepted")
+			return
+		}
+
+		if p.quotaError(ctx, err) {
+			return
+		}
 
-	err = ctx.SaveUploadedFile(file, imageUrl)
-	if err != nil {
-		log.Println(err)
 		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
 		return
 	}
+
 	response.SuccessResponse(ctx, "success upload photos", photos)
 }
 
@@ -103,28 +141,49 @@ func (p *PhotosController) UpdatePhotos(ctx *gin.Context) {
 	title := ctx.Request.FormValue("title")
 	caption := ctx.Request.FormValue("caption")
 
-	file, err := ctx.FormFile("photo-profile")
+	crop, err := p.parseCrop(ctx)
 	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "crop is not valid")
+		return
+	}
+
+	// the file is optional, without one only the details and the crop are updated
+	var photoFile dto.PhotoFile
+	file, err := ctx.FormFile("photo-profile")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
 		log.Println(err)
 		response.ErrorResponse(ctx, http.StatusBadRequest, "input is not valid")
 		return
 	}
 
-	id := uuid.NewString()
-	name := strings.Replace(id, "-", "", -1)
-	ext := filepath.Ext(file.Filename)
-	imageName := fmt.Sprintf("%s%s", name, ext)
+	if file != nil {
+		content, err := file.Open()
+		if err != nil {
+			log.Println(err)
+			response.ErrorResponse(ctx, http.StatusInternalServerError, "failed update photo")
+			return
+		}
+		defer content.Close()
+
+		photoFile = dto.PhotoFile{
+			Filename:    file.Filename,
+			ContentType: file.Header.Get("Content-Type"),
+			Size:        file.Size,
+			Content:     content,
+		}
+	}
 
-	imageUrl := fmt.Sprintf("./images/%s", imageName)
 	request := entity.Photos{
-		Id:       photoId,
-		Title:    title,
-		Caption:  caption,
-		PhotoUrl: imageUrl,
-		UserId:   userId,
+		Id:         photoId,
+		Title:      title,
+		Caption:    caption,
+		UserId:     userId,
+		Visibility: ctx.Request.FormValue("visibility"),
+		Tags:       p.parseTags(ctx),
 	}
 
-	photos, err := p.photoUC.UpdatePhotos(request)
+	photos, err := p.photoUC.UpdatePhotos(request, photoFile, crop)
 	if err != nil {
 		log.Println(err)
 
@@ -133,13 +192,20 @@ func (p *PhotosController) UpdatePhotos(ctx *gin.Context) {
 			return
 		}
 
-		response.ErrorResponse(ctx, http.StatusInternalServerError, "failed update photo")
-		return
-	}
+		if errors.Is(err, exception.InvalidErr) {
+			response.ErrorResponse(ctx, http.StatusBadRequest, "crop, visibility or tags are not valid")
+			return
+		}
+
+		if errors.Is(err, exception.UnsupportedErr) {
+			response.ErrorResponse(ctx, http.StatusUnsupportedMediaType, "only jpeg, png, gif and webp photos are accepted")
+			return
+		}
+
+		if p.quotaError(ctx, err) {
+			return
+		}
 
-	err = ctx.SaveUploadedFile(file, imageUrl)
-	if err != nil {
-		log.Println(err)
 		response.ErrorResponse(ctx, http.StatusInternalServerError, "failed update photo")
 		return
 	}
@@ -213,3 +279,554 @@ func (p *PhotosController) GetPhotos(ctx *gin.Context) {
 		Data:    photos,
 	})
 }
+
+// GetPhotoContent is reachable without a bearer token for public photos, see middleware.optionalAuthPaths.
+func (p *PhotosController) GetPhotoContent(ctx *gin.Context) {
+	userId := p.viewerId(ctx)
+
+	transform := dto.PhotoTransformRequest{
+		Width:   ctx.Query("w"),
+		Quality: ctx.Query("q"),
+		Format:  ctx.Query("fmt"),
+		Crop:    ctx.Query("crop"),
+		Accept:  ctx.GetHeader("Accept"),
+	}
+
+	content, err := p.photoUC.GetPhotoContent(userId, ctx.Param("photoId"), ctx.Param("variant"), transform)
+	if err != nil {
+		log.Println(err)
+		switch {
+		case errors.Is(err, exception.NotFoundErr):
+			response.ErrorResponse(ctx, http.StatusNotFound, "photo not found")
+		case errors.Is(err, exception.InvalidErr):
+			response.ErrorResponse(ctx, http.StatusBadRequest, "variant or transform is not valid")
+		case errors.Is(err, exception.TooLargeErr):
+			response.ErrorResponse(ctx, http.StatusUnprocessableEntity, "photo is too large to transform")
+		case errors.Is(err, exception.UnavailableErr):
+			ctx.Header("Retry-After", "5")
+			response.ErrorResponse(ctx, http.StatusServiceUnavailable, "too many transforms in progress")
+		default:
+			response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+	defer content.Content.Close()
+
+	// private: the response depends on the bearer token, so shared caches must not keep it,
+	// and on Accept, which picks the format of fmt=auto
+	ctx.Header("Vary", "Accept")
+	p.serveContent(ctx, content, "private, max-age=60, must-revalidate")
+}
+
+func (p *PhotosController) GetSimilarPhotos(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	distance := usecase.SimilarDefaultDistance
+	if ctx.Query("distance") != "" {
+		var err error
+		distance, err = strconv.Atoi(ctx.Query("distance"))
+		if err != nil {
+			log.Println(err)
+			response.ErrorResponse(ctx, http.StatusBadRequest, "distance is not valid")
+			return
+		}
+	}
+
+	similar, err := p.photoUC.GetSimilarPhotos(userId, ctx.Param("photoId"), distance)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.NotFoundErr) {
+			response.ErrorResponse(ctx, http.StatusNotFound, "photo not found")
+			return
+		}
+
+		if errors.Is(err, exception.InvalidErr) {
+			response.ErrorResponse(ctx, http.StatusBadRequest, fmt.Sprintf("distance must be between 0 and %d", usecase.SimilarMaxDistance))
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.SuccessResponse(ctx, "success get similar photos", similar)
+}
+
+// GetUserPhotos is reachable without a bearer token, anonymous callers only get public photos.
+func (p *PhotosController) GetUserPhotos(ctx *gin.Context) {
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	photos, err := p.photoUC.GetUserPhotos(p.viewerId(ctx), ctx.Param("userId"), page)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.InvalidErr) {
+			response.ErrorResponse(ctx, http.StatusBadRequest, "cursor or limit is not valid")
+			return
+		}
+
+		if errors.Is(err, exception.NotFoundErr) {
+			response.ErrorResponse(ctx, http.StatusNotFound, "user not found")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.PagedResponse(ctx, "success get photos", photos.Items, photos.Page)
+}
+
+// SearchPhotos is reachable without a bearer token, anonymous callers only find public photos.
+func (p *PhotosController) SearchPhotos(ctx *gin.Context) {
+	request := dto.PhotoSearchRequest{
+		Query:  ctx.Query("q"),
+		Tags:   ctx.QueryArray("tag"),
+		Cursor: ctx.Query("cursor"),
+	}
+
+	if ctx.Query("limit") != "" {
+		limit, err := strconv.Atoi(ctx.Query("limit"))
+		if err != nil {
+			log.Println(err)
+			response.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+			return
+		}
+		request.Limit = limit
+	}
+
+	result, err := p.photoUC.SearchPhotos(p.viewerId(ctx), request)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.InvalidErr) {
+			response.ErrorResponse(ctx, http.StatusBadRequest, "query, tags, cursor or limit is not valid")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.PagedResponse(ctx, "success search photos", result.Items, result.Page)
+}
+
+// parseTags reads the comma separated tags field. Without the field the tags are kept,
+// an empty field removes them.
+func (p *PhotosController) parseTags(ctx *gin.Context) []string {
+	value, ok := ctx.GetPostForm("tags")
+	if !ok {
+		return nil
+	}
+
+	tags := strings.Split(value, ",")
+	if strings.TrimSpace(value) == "" {
+		tags = []string{}
+	}
+
+	return tags
+}
+
+func (p *PhotosController) GetFeed(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	feed, err := p.photoUC.GetFeed(userId, page)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.InvalidErr) {
+			response.ErrorResponse(ctx, http.StatusBadRequest, "cursor or limit is not valid")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.PagedResponse(ctx, "success get feed", feed.Items, feed.Page)
+}
+
+// viewerId is the caller on routes with optional authentication, empty when anonymous.
+func (p *PhotosController) viewerId(ctx *gin.Context) string {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		return ""
+	}
+
+	return value.(*dto.CustomClaims).UserId
+}
+
+func (p *PhotosController) SharePhoto(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	var request dto.SharePhotoRequest
+	if ctx.Request.ContentLength != 0 {
+		err := ctx.BindJSON(&request)
+		if err != nil {
+			log.Println(err)
+			response.ErrorResponse(ctx, http.StatusBadRequest, "request invalid")
+			return
+		}
+	}
+
+	shared, err := p.photoUC.SharePhoto(userId, ctx.Param("photoId"), request)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.NotFoundErr) {
+			response.ErrorResponse(ctx, http.StatusNotFound, "photo not found")
+			return
+		}
+
+		if errors.Is(err, exception.ForbiddenErr) {
+			response.ErrorResponse(ctx, http.StatusForbidden, "photo cannot be shared while it is hidden")
+			return
+		}
+
+		if errors.Is(err, exception.InvalidErr) {
+			response.ErrorResponse(ctx, http.StatusBadRequest, "request invalid")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.CreatedResponse(ctx, "success share photo", shared)
+}
+
+// GetSharedPhotoContent is reachable without a bearer token, see middleware.publicPaths.
+func (p *PhotosController) GetSharedPhotoContent(ctx *gin.Context) {
+	expires := ctx.Query("expires")
+
+	content, err := p.photoUC.GetSharedPhotoContent(ctx.Param("photoId"), ctx.Query("variant"), expires, ctx.Query("signature"))
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.ForbiddenErr) {
+			response.ErrorResponse(ctx, http.StatusForbidden, "link is invalid or expired")
+			return
+		}
+
+		if errors.Is(err, exception.NotFoundErr) {
+			response.ErrorResponse(ctx, http.StatusNotFound, "photo not found")
+			return
+		}
+
+		if errors.Is(err, exception.InvalidErr) {
+			response.ErrorResponse(ctx, http.StatusBadRequest, "variant is not valid")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	defer content.Content.Close()
+
+	// the link itself is the credential, so caches may keep the response until it expires
+	maxAge := int64(3600)
+	expiresAt, _ := strconv.ParseInt(expires, 10, 64)
+	if remaining := expiresAt - time.Now().Unix(); remaining < maxAge {
+		maxAge = remaining
+	}
+
+	p.serveContent(ctx, content, fmt.Sprintf("public, max-age=%d", maxAge))
+}
+
+// parseCrop reads either the crop_x, crop_y, crop_w and crop_h form fields or focal_x and focal_y.
+func (p *PhotosController) parseCrop(ctx *gin.Context) (dto.PhotoCropRequest, error) {
+	var crop dto.PhotoCropRequest
+	rect := []string{ctx.Request.FormValue("crop_x"), ctx.Request.FormValue("crop_y"), ctx.Request.FormValue("crop_w"), ctx.Request.FormValue("crop_h")}
+	focal := []string{ctx.Request.FormValue("focal_x"), ctx.Request.FormValue("focal_y")}
+
+	if rect[0] != "" || rect[1] != "" || rect[2] != "" || rect[3] != "" {
+		values := make([]int, len(rect))
+		for i, value := range rect {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return dto.PhotoCropRequest{}, fmt.Errorf("crop needs crop_x, crop_y, crop_w and crop_h : %w", err)
+			}
+			values[i] = number
+		}
+
+		crop.Rect = true
+		crop.X, crop.Y, crop.Width, crop.Height = values[0], values[1], values[2], values[3]
+	}
+
+	if focal[0] != "" || focal[1] != "" {
+		if crop.Rect {
+			return dto.PhotoCropRequest{}, fmt.Errorf("crop rectangle and focal point are exclusive")
+		}
+
+		var err error
+		crop.FocalX, err = strconv.ParseFloat(focal[0], 64)
+		if err != nil {
+			return dto.PhotoCropRequest{}, fmt.Errorf("focal point needs focal_x and focal_y : %w", err)
+		}
+
+		crop.FocalY, err = strconv.ParseFloat(focal[1], 64)
+		if err != nil {
+			return dto.PhotoCropRequest{}, fmt.Errorf("focal point needs focal_x and focal_y : %w", err)
+		}
+		crop.Focal = true
+	}
+
+	return crop, nil
+}
+
+// serveContent streams a blob, ServeContent answers If-None-Match, If-Modified-Since and Range requests.
+// Photos are served from the api's own origin, so browsers are told not to second-guess the
+// content type and never to run anything in it. A file that is not a known image is a download.
+func (p *PhotosController) serveContent(ctx *gin.Context, content dto.PhotoContent, cacheControl string) {
+	disposition := "inline"
+	if content.ContentType == "application/octet-stream" {
+		disposition = "attachment"
+	}
+
+	ctx.Header("Cache-Control", cacheControl)
+	ctx.Header("Content-Type", content.ContentType)
+	ctx.Header("Content-Disposition", disposition)
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+	ctx.Header("ETag", content.ETag)
+
+	http.ServeContent(ctx.Writer, ctx.Request, "", content.LastModified, content.Content)
+}
+
+func (p *PhotosController) GetTrash(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	trash, err := p.photoUC.GetTrash(userId, page)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.InvalidErr) {
+			response.ErrorResponse(ctx, http.StatusBadRequest, "cursor or limit is not valid")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.PagedResponse(ctx, "success get trash", trash.Items, trash.Page)
+}
+
+// DeleteTrashedPhoto removes a photo from the trash for good, freeing its storage.
+func (p *PhotosController) DeleteTrashedPhoto(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	err := p.photoUC.DeleteTrashedPhoto(userId, ctx.Param("photoId"))
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.NotFoundErr) {
+			response.ErrorResponse(ctx, http.StatusNotFound, "photo not found in trash")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.SuccessResponse(ctx, "success delete photo permanently", nil)
+}
+
+// EmptyTrash removes every photo in the trash for good, freeing their storage.
+func (p *PhotosController) EmptyTrash(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	removed, err := p.photoUC.EmptyTrash(userId)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.SuccessResponse(ctx, "success empty trash", dto.EmptyTrashResponse{Removed: removed})
+}
+
+func (p *PhotosController) RestorePhoto(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	photo, err := p.photoUC.RestorePhoto(userId, ctx.Param("photoId"))
+	if err != nil {
+		log.Println(err)
+		switch {
+		case errors.Is(err, exception.NotFoundErr):
+			response.ErrorResponse(ctx, http.StatusNotFound, "photo not found in trash")
+		case errors.Is(err, exception.ConflictErr):
+			response.ErrorResponse(ctx, http.StatusConflict, "delete your current photo before restoring this one")
+		default:
+			response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	response.SuccessResponse(ctx, "success restore photo", photo)
+}
+
+// GetPhotoVersions lists what earlier PUT /photos/:photoId requests replaced: a new file,
+// or the title, caption and crop of the same file. Visibility and tags are not versioned.
+func (p *PhotosController) GetPhotoVersions(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	versions, err := p.photoUC.GetPhotoVersions(userId, ctx.Param("photoId"), page)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.InvalidErr) {
+			response.ErrorResponse(ctx, http.StatusBadRequest, "cursor or limit is not valid")
+			return
+		}
+
+		if errors.Is(err, exception.NotFoundErr) {
+			response.ErrorResponse(ctx, http.StatusNotFound, "photo not found")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.PagedResponse(ctx, "success get photo versions", versions.Items, versions.Page)
+}
+
+func (p *PhotosController) RevertPhotoVersion(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	photo, err := p.photoUC.RevertPhotoVersion(userId, ctx.Param("photoId"), ctx.Param("versionId"))
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.NotFoundErr) {
+			response.ErrorResponse(ctx, http.StatusNotFound, "photo or version not found")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.SuccessResponse(ctx, "success revert photo", photo)
+}
+
+// DeletePhotoVersion removes one earlier file of a photo, freeing its storage.
+func (p *PhotosController) DeletePhotoVersion(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	err := p.photoUC.DeletePhotoVersion(userId, ctx.Param("photoId"), ctx.Param("versionId"))
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.NotFoundErr) {
+			response.ErrorResponse(ctx, http.StatusNotFound, "photo or version not found")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.SuccessResponse(ctx, "success delete photo version", nil)
+}
+
+// quotaError answers uploads that do not fit into the storage quota, it reports whether err was one.
+func (p *PhotosController) quotaError(ctx *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, exception.TooLargeErr):
+		response.ErrorResponse(ctx, http.StatusRequestEntityTooLarge, "photo is larger than your storage quota")
+	case errors.Is(err, exception.QuotaErr):
+		response.ErrorResponse(ctx, http.StatusInsufficientStorage, "storage quota exceeded, empty the trash or delete photos or earlier versions to free space")
+	default:
+		return false
+	}
+
+	return true
+}
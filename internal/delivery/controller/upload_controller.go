@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"user-personalize/internal/config"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/response"
+)
+
+const (
+	tusVersion            = "1.0.0"
+	tusExtensions         = "creation,expiration"
+	tusOffsetContentType  = "application/offset+octet-stream"
+	tusResumableHeaderKey = "Tus-Resumable"
+)
+
+// UploadController speaks the tus 1.0 protocol, see https://tus.io/protocols/resumable-upload.
+type UploadController struct {
+	uploadUC usecase.UploadUC
+	cfg      config.UploadConfig
+	rg       *gin.RouterGroup
+}
+
+func NewUploadController(uploadUC usecase.UploadUC, cfg config.UploadConfig, rg *gin.RouterGroup) *UploadController {
+	return &UploadController{uploadUC: uploadUC, cfg: cfg, rg: rg}
+}
+
+func (u *UploadController) RouteGroup() {
+	u.rg.OPTIONS("/uploads", u.Options)
+	u.rg.POST("/uploads", u.CreateUpload)
+	u.rg.HEAD("/uploads/:uploadId", u.GetUploadOffset)
+	u.rg.PATCH("/uploads/:uploadId", u.AppendChunk)
+	u.rg.POST("/photos/upload-url", u.CreateUploadUrl)
+	u.rg.POST("/photos/complete", u.CompleteUpload)
+}
+
+func (u *UploadController) Options(ctx *gin.Context) {
+	ctx.Header(tusResumableHeaderKey, tusVersion)
+	ctx.Header("Tus-Version", tusVersion)
+	ctx.Header("Tus-Extension", tusExtensions)
+	ctx.Header("Tus-Max-Size", strconv.FormatInt(u.cfg.MaxSize, 10))
+	ctx.Status(http.StatusNoContent)
+}
+
+func (u *UploadController) CreateUpload(ctx *gin.Context) {
+	userId, ok := u.prepare(ctx)
+	if !ok {
+		return
+	}
+
+	if ctx.GetHeader("Upload-Defer-Length") != "" {
+		response.ErrorResponse(ctx, http.StatusBadRequest, "deferred upload length is not supported")
+		return
+	}
+
+	length, err := strconv.ParseInt(ctx.GetHeader("Upload-Length"), 10, 64)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "upload length is not valid")
+		return
+	}
+
+	upload, err := u.uploadUC.CreateUpload(userId, length, ctx.GetHeader("Upload-Metadata"))
+	if err != nil {
+		u.handleError(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", "/uploads/"+upload.Id)
+	ctx.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	ctx.Status(http.StatusCreated)
+}
+
+func (u *UploadController) GetUploadOffset(ctx *gin.Context) {
+	userId, ok := u.prepare(ctx)
+	if !ok {
+		return
+	}
+
+	upload, err := u.uploadUC.GetUpload(userId, ctx.Param("uploadId"))
+	if err != nil {
+		u.handleError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	ctx.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	ctx.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if upload.Metadata != "" {
+		ctx.Header("Upload-Metadata", upload.Metadata)
+	}
+	if upload.PhotoId != "" {
+		ctx.Header("Upload-Photo-Id", upload.PhotoId)
+	}
+	ctx.Status(http.StatusOK)
+}
+
+func (u *UploadController) AppendChunk(ctx *gin.Context) {
+	userId, ok := u.prepare(ctx)
+	if !ok {
+		return
+	}
+
+	if ctx.ContentType() != tusOffsetContentType {
+		response.ErrorResponse(ctx, http.StatusUnsupportedMediaType, "content type must be "+tusOffsetContentType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(ctx.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "upload offset is not valid")
+		return
+	}
+
+	upload, err := u.uploadUC.AppendChunk(userId, ctx.Param("uploadId"), offset, ctx.Request.Body)
+	if upload.Id != "" {
+		ctx.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	}
+	if err != nil {
+		u.handleError(ctx, err)
+		return
+	}
+
+	ctx.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if upload.PhotoId != "" {
+		ctx.Header("Upload-Photo-Id", upload.PhotoId)
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (u *UploadController) CreateUploadUrl(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	uploadUrl, err := u.uploadUC.CreateUploadUrl(userId)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.UnsupportedErr) {
+			response.ErrorResponse(ctx, http.StatusNotImplemented, "storage does not support direct uploads")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.CreatedResponse(ctx, "success create upload url", uploadUrl)
+}
+
+func (u *UploadController) CompleteUpload(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	var request dto.CompleteUploadRequest
+	err := ctx.BindJSON(&request)
+	if err != nil {
+		log.Println(err)
+		response.ErrorResponse(ctx, http.StatusBadRequest, "request invalid")
+		return
+	}
+
+	photos, err := u.uploadUC.CompleteUpload(userId, request)
+	if err != nil {
+		u.handleError(ctx, err)
+		return
+	}
+
+	response.CreatedResponse(ctx, "success upload photos", photos)
+}
+
+// prepare checks the claims and the Tus-Resumable header that every request but OPTIONS must send.
+func (u *UploadController) prepare(ctx *gin.Context) (string, bool) {
+	ctx.Header(tusResumableHeaderKey, tusVersion)
+
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return "", false
+	}
+
+	if ctx.GetHeader(tusResumableHeaderKey) != tusVersion {
+		ctx.Header("Tus-Version", tusVersion)
+		response.ErrorResponse(ctx, http.StatusPreconditionFailed, "tus version is not supported")
+		return "", false
+	}
+
+	return value.(*dto.CustomClaims).UserId, true
+}
+
+func (u *UploadController) handleError(ctx *gin.Context, err error) {
+	log.Println(err)
+
+	switch {
+	case errors.Is(err, exception.NotFoundErr):
+		response.ErrorResponse(ctx, http.StatusNotFound, "upload not found")
+	case errors.Is(err, exception.ExpiredErr):
+		response.ErrorResponse(ctx, http.StatusGone, "upload expired")
+	case errors.Is(err, exception.ConflictErr):
+		response.ErrorResponse(ctx, http.StatusConflict, "upload offset or photo conflict")
+	case errors.Is(err, exception.TooLargeErr):
+		response.ErrorResponse(ctx, http.StatusRequestEntityTooLarge, "upload is too large")
+	case errors.Is(err, exception.QuotaErr):
+		response.ErrorResponse(ctx, http.StatusInsufficientStorage, "storage quota exceeded, empty the trash or delete photos to free space")
+	case errors.Is(err, exception.UnsupportedErr):
+		response.ErrorResponse(ctx, http.StatusUnsupportedMediaType, "only jpeg, png, gif and webp photos are accepted")
+	case errors.Is(err, exception.InvalidErr):
+		response.ErrorResponse(ctx, http.StatusBadRequest, "upload is not valid")
+	default:
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/response"
+)
+
+type UsageController struct {
+	usageUC usecase.UsageUC
+	rg      *gin.RouterGroup
+}
+
+func NewUsageController(usageUC usecase.UsageUC, rg *gin.RouterGroup) *UsageController {
+	return &UsageController{usageUC: usageUC, rg: rg}
+}
+
+func (u *UsageController) RouteGroup() {
+	u.rg.GET("/users/me/usage", u.GetUsage)
+}
+
+func (u *UsageController) GetUsage(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	userId := value.(*dto.CustomClaims).UserId
+
+	usage, err := u.usageUC.GetUsage(userId)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.NotFoundErr) {
+			response.ErrorResponse(ctx, http.StatusNotFound, "user not found")
+			return
+		}
+
+		response.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	response.SuccessResponse(ctx, "success get usage", usage)
+}
@@ -1,30 +1,36 @@
 package controller
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
 	"user-personalize/internal/model/dto"
 	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/exception"
 	response2 "user-personalize/pkg/util/response"
 )
 
 type UserController struct {
-	userUC usecase.UserUC
-	rg     *gin.RouterGroup
+	userUC    usecase.UserUC
+	erasureUC usecase.ErasureUC
+	rg        *gin.RouterGroup
 }
 
-func NewUserController(userUC usecase.UserUC, rg *gin.RouterGroup) *UserController {
-	return &UserController{userUC: userUC, rg: rg}
+func NewUserController(userUC usecase.UserUC, erasureUC usecase.ErasureUC, rg *gin.RouterGroup) *UserController {
+	return &UserController{userUC: userUC, erasureUC: erasureUC, rg: rg}
 }
 
 func (u *UserController) RouteGroup() {
 	u.rg.POST("/users", u.CreateUser)
+	u.rg.GET("/users/search", u.SearchUsers)
 	u.rg.GET("/users/:userId", u.GetUserById)
 	u.rg.GET("/users", u.GetListUser)
 	u.rg.PUT("/users/:userId", u.updateUser)
 	u.rg.DELETE("/users/:userId", u.DeleteUser)
 	u.rg.PUT("/users/updatePassword/:userId", u.updatePassword)
+	u.rg.POST("/erasure-receipts/verify", u.VerifyErasureReceipt)
+	u.rg.GET("/erasure-receipts/:receiptId", u.GetErasureReceipt)
 }
 
 func (u *UserController) CreateUser(ctx *gin.Context) {
@@ -47,23 +53,96 @@ func (u *UserController) CreateUser(ctx *gin.Context) {
 	response2.CreatedResponse(ctx, "success create new user", user)
 }
 
+// GetListUser filters with the email_domain, username_prefix, created_after and created_before
+// query parameters and sorts with sort, one of created_at, username, -created_at or -username.
 func (u *UserController) GetListUser(ctx *gin.Context) {
-	user, err := u.userUC.GetAllUser()
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response2.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response2.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	users, err := u.userUC.GetAllUser(value.(*dto.CustomClaims).UserId, dto.UserListRequest{
+		Page:           page,
+		EmailDomain:    ctx.Query("email_domain"),
+		UsernamePrefix: ctx.Query("username_prefix"),
+		CreatedAfter:   ctx.Query("created_after"),
+		CreatedBefore:  ctx.Query("created_before"),
+		Sort:           ctx.Query("sort"),
+	})
+	if errors.Is(err, exception.InvalidErr) {
+		log.Println(err)
+		response2.ErrorResponse(ctx, http.StatusBadRequest, "pagination, filter or sort parameters are not valid")
+		return
+	}
+
 	if err != nil {
 		log.Println(err)
 		response2.ErrorResponse(ctx, http.StatusInternalServerError, "error while getting user")
 		return
 	}
 
-	response2.SuccessResponse(ctx, "success get all user", user)
+	response2.PagedResponse(ctx, "success get all user", users.Items, users.Page)
+}
+
+func (u *UserController) SearchUsers(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response2.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		log.Println(err)
+		response2.ErrorResponse(ctx, http.StatusBadRequest, "limit is not valid")
+		return
+	}
+
+	users, err := u.userUC.SearchUsers(value.(*dto.CustomClaims).UserId, dto.UserSearchRequest{Query: ctx.Query("q"), Page: page})
+	if err != nil {
+		log.Println(err)
+		switch {
+		case errors.Is(err, exception.InvalidErr):
+			response2.ErrorResponse(ctx, http.StatusBadRequest, "query, cursor or limit is not valid")
+		case errors.Is(err, exception.RateLimitedErr):
+			response2.ErrorResponse(ctx, http.StatusTooManyRequests, "too many searches, try again later")
+		default:
+			response2.ErrorResponse(ctx, http.StatusInternalServerError, "error while searching users")
+		}
+		return
+	}
+
+	response2.PagedResponse(ctx, "success search users", users.Items, users.Page)
 }
 
 func (u *UserController) GetUserById(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response2.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
 	id := ctx.Param("userId")
 
-	user, err := u.userUC.GetUserById(id)
+	user, err := u.userUC.GetUserById(value.(*dto.CustomClaims).UserId, id)
 	if err != nil {
 		log.Println(err)
+		if errors.Is(err, exception.NotFoundErr) {
+			response2.ErrorResponse(ctx, http.StatusNotFound, "user not found")
+			return
+		}
+
 		response2.ErrorResponse(ctx, http.StatusInternalServerError, "error while getting user")
 		return
 	}
@@ -114,15 +193,88 @@ func (u *UserController) updatePassword(ctx *gin.Context) {
 	response2.SuccessResponse(ctx, "success update password user", updatedUser)
 }
 
+// DeleteUser deactivates the account, it is erased once the grace period is over.
+// Admins can pass hard=true to erase any account right away, the response is then the
+// erasure receipt.
 func (u *UserController) DeleteUser(ctx *gin.Context) {
+	value, exists := ctx.Get("claims")
+	if !exists {
+		log.Println("claims does not exist")
+		response2.ErrorResponse(ctx, http.StatusForbidden, "you cannot access this resource")
+		return
+	}
+
+	requesterId := value.(*dto.CustomClaims).UserId
 	id := ctx.Param("userId")
 
-	err := u.userUC.DeleteUser(id)
+	if ctx.Query("hard") == "true" {
+		receipt, err := u.erasureUC.EraseUser(requesterId, id)
+		if err != nil {
+			u.deleteError(ctx, err)
+			return
+		}
+
+		response2.SuccessResponse(ctx, "success delete user", receipt)
+		return
+	}
+
+	deletion, err := u.erasureUC.ScheduleDeletion(requesterId, id)
 	if err != nil {
-		log.Println(err)
+		u.deleteError(ctx, err)
+		return
+	}
+
+	response2.AcceptedResponse(ctx, "account is scheduled for deletion", deletion)
+}
+
+func (u *UserController) deleteError(ctx *gin.Context, err error) {
+	log.Println(err)
+	switch {
+	case errors.Is(err, exception.ForbiddenErr):
+		response2.ErrorResponse(ctx, http.StatusForbidden, "you cannot delete this user")
+	case errors.Is(err, exception.NotFoundErr):
+		response2.ErrorResponse(ctx, http.StatusNotFound, "user not found")
+	case errors.Is(err, exception.ConflictErr):
+		response2.ErrorResponse(ctx, http.StatusConflict, "user changed while being deleted, try again")
+	default:
 		response2.ErrorResponse(ctx, http.StatusInternalServerError, "error while deleting user")
+	}
+}
+
+// GetErasureReceipt serves the receipt of an erasure to whoever holds its id, the id is
+// handed out when the account is deleted. The receipt names the user only by a hash.
+func (u *UserController) GetErasureReceipt(ctx *gin.Context) {
+	receipt, err := u.erasureUC.GetReceipt(ctx.Param("receiptId"))
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, exception.NotFoundErr) {
+			response2.ErrorResponse(ctx, http.StatusNotFound, "receipt not found, the account may not be erased yet")
+			return
+		}
+
+		response2.ErrorResponse(ctx, http.StatusInternalServerError, "error while getting receipt")
+		return
+	}
+
+	response2.SuccessResponse(ctx, "success get erasure receipt", receipt)
+}
+
+func (u *UserController) VerifyErasureReceipt(ctx *gin.Context) {
+	receipt := dto.ErasureReceipt{}
+
+	err := ctx.BindJSON(&receipt)
+	if err != nil {
+		log.Println(err)
+		response2.ErrorResponse(ctx, http.StatusBadRequest, "request body is invalid")
+		return
+	}
+
+	verification, err := u.erasureUC.VerifyReceipt(receipt)
+	if err != nil {
+		log.Println(err)
+		response2.ErrorResponse(ctx, http.StatusInternalServerError, "error while verifying receipt")
 		return
 	}
 
-	response2.SuccessResponse(ctx, "success delete user", id)
+	response2.SuccessResponse(ctx, "success verify erasure receipt", verification)
 }
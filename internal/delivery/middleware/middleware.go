@@ -1,9 +1,13 @@
 package middleware
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"log"
 	"net/http"
 	"strings"
+	"user-personalize/internal/usecase"
+	"user-personalize/pkg/util/exception"
 	"user-personalize/pkg/util/response"
 	"user-personalize/pkg/util/service"
 )
@@ -12,14 +16,41 @@ type Middleware interface {
 	ValidateUser(ctx *gin.Context)
 }
 
+// publicPaths are served without a bearer token.
+var publicPaths = map[string]bool{
+	"/users/login":                 true,
+	"/users/register":              true,
+	"/users/reactivate":            true,
+	"/shared/photos/:photoId":      true,
+	"/erasure-receipts/verify":     true,
+	"/erasure-receipts/:receiptId": true,
+}
+
+// optionalAuthPaths accept anonymous GET requests, which only see public photos. A token
+// that is sent must still be valid.
+var optionalAuthPaths = map[string]bool{
+	"/users/:userId/photos":             true,
+	"/photos/search":                    true,
+	"/photos/:photoId/content":          true,
+	"/photos/:photoId/content/:variant": true,
+	"/photos/:photoId/comments":         true,
+}
+
 type middlewareImpl struct {
 	jwtService service.JwtService
+	authUC     usecase.AuthUC
 }
 
 func (m *middlewareImpl) ValidateUser(ctx *gin.Context) {
-	if !(ctx.FullPath() == "/users/login" || ctx.FullPath() == "/users/register") {
+	// OPTIONS carries no credentials, e.g. CORS preflight and tus capability discovery
+	if !publicPaths[ctx.FullPath()] && ctx.Request.Method != http.MethodOptions {
 		fullToken := ctx.GetHeader("Authorization")
 
+		if fullToken == "" && ctx.Request.Method == http.MethodGet && optionalAuthPaths[ctx.FullPath()] {
+			ctx.Next()
+			return
+		}
+
 		if fullToken == "" {
 			response.ErrorResponse(ctx, http.StatusUnauthorized, "token not found")
 			ctx.Abort()
@@ -46,11 +77,26 @@ func (m *middlewareImpl) ValidateUser(ctx *gin.Context) {
 			return
 		}
 
+		err = m.authUC.Authorize(claims.UserId)
+		if err != nil {
+			log.Println(err)
+			switch {
+			case errors.Is(err, exception.ForbiddenErr):
+				response.ErrorResponse(ctx, http.StatusForbidden, "account is suspended")
+			case errors.Is(err, exception.DeactivatedErr):
+				response.ErrorResponse(ctx, http.StatusForbidden, "account is scheduled for deletion")
+			default:
+				response.ErrorResponse(ctx, http.StatusUnauthorized, "unauthorized")
+			}
+			ctx.Abort()
+			return
+		}
+
 		ctx.Set("claims", claims)
 		ctx.Next()
 	}
 }
 
-func NewMiddleware(jwtService service.JwtService) Middleware {
-	return &middlewareImpl{jwtService: jwtService}
+func NewMiddleware(jwtService service.JwtService, authUC usecase.AuthUC) Middleware {
+	return &middlewareImpl{jwtService: jwtService, authUC: authUC}
 }
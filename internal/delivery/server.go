@@ -12,18 +12,40 @@ import (
 	"user-personalize/internal/repository"
 	"user-personalize/internal/usecase"
 	"user-personalize/pkg/util/service"
+	"user-personalize/pkg/util/storage"
 )
 
 type Server struct {
-	UserUC     usecase.UserUC
-	AuthUC     usecase.AuthUC
-	PhotoUC    usecase.PhotosUC
-	Middleware middleware.Middleware
-	Host       string
-	Engine     *gin.Engine
+	UserUC       usecase.UserUC
+	AuthUC       usecase.AuthUC
+	PhotoUC      usecase.PhotosUC
+	Reconciler   usecase.ReconcilerUC
+	UploadUC     usecase.UploadUC
+	LikeUC       usecase.LikeUC
+	CommentUC    usecase.CommentUC
+	FollowUC     usecase.FollowUC
+	BlockUC      usecase.BlockUC
+	ModerationUC usecase.ModerationUC
+	UsageUC      usecase.UsageUC
+	ExportUC     usecase.ExportUC
+	ErasureUC    usecase.ErasureUC
+	Config       *config.Config
+	Middleware   middleware.Middleware
+	Host         string
+	Engine       *gin.Engine
 }
 
 func (s *Server) ServerRun() {
+	go func() {
+		s.PhotoUC.RunLegacyMigration()
+		s.PhotoUC.RunAnalysisBackfill()
+	}()
+	go s.Reconciler.Run()
+	go s.UploadUC.RunGarbageCollector()
+	go s.PhotoUC.RunTrashPurge()
+	go s.ExportUC.RunWorker()
+	go s.ErasureUC.RunDeletionPurge()
+
 	s.Engine.Use(s.Middleware.ValidateUser)
 	s.InitRoute()
 	err := s.Engine.Run(s.Host)
@@ -34,9 +56,17 @@ func (s *Server) ServerRun() {
 
 func (s *Server) InitRoute() {
 	rg := s.Engine.Group("")
-	controller.NewUserController(s.UserUC, rg).RouteGroup()
+	controller.NewUserController(s.UserUC, s.ErasureUC, rg).RouteGroup()
 	controller.NewAuthController(s.AuthUC, rg).RouteGroup()
 	controller.NewPhotosController(s.PhotoUC, rg).RouteGroup()
+	controller.NewLikeController(s.LikeUC, rg).RouteGroup()
+	controller.NewCommentController(s.CommentUC, rg).RouteGroup()
+	controller.NewFollowController(s.FollowUC, rg).RouteGroup()
+	controller.NewBlockController(s.BlockUC, rg).RouteGroup()
+	controller.NewModerationController(s.ModerationUC, rg).RouteGroup()
+	controller.NewUsageController(s.UsageUC, rg).RouteGroup()
+	controller.NewExportController(s.ExportUC, rg).RouteGroup()
+	controller.NewUploadController(s.UploadUC, s.Config.UploadConfig, rg).RouteGroup()
 }
 
 func NewServer() *Server {
@@ -54,29 +84,77 @@ func NewServer() *Server {
 
 	validate := validator.New()
 
+	blobStore, err := storage.NewBlobStore(cfg.StorageConfig)
+	if err != nil {
+		panic(err)
+	}
+
 	// repository
 	userRepository := repository.NewUserRepository(db)
 	photosRepository := repository.NewPhotosRepository(db)
+	blobRepository := repository.NewBlobRepository(db)
+	tagRepository := repository.NewTagRepository(db)
+	photoVersionRepository := repository.NewPhotoVersionRepository(db)
+	likeRepository := repository.NewLikeRepository(db)
+	commentRepository := repository.NewCommentRepository(db)
+	followRepository := repository.NewFollowRepository(db)
+	blockRepository := repository.NewBlockRepository(db)
+	muteRepository := repository.NewMuteRepository(db)
+	reportRepository := repository.NewReportRepository(db)
+	actionRepository := repository.NewModerationActionRepository(db)
+	usageRepository := repository.NewUsageRepository(db)
+	exportRepository := repository.NewExportRepository(db)
+	personalDataRepository := repository.NewPersonalDataRepository(db)
+	erasureReceiptRepository := repository.NewErasureReceiptRepository(db)
+	uploadRepository := repository.NewUploadRepository(db)
+	directUploadRepository := repository.NewDirectUploadRepository(db)
+	transactor := repository.NewTransactor(db)
 
 	// UC
 	jwtService := service.NewJwtService(cfg.JwtConfig)
+	urlSignerService := service.NewUrlSignerService(cfg.ShareConfig, cfg.ApiConfig.BaseUrl)
+	classifier := service.NewNoopContentClassifier()
+	receiptSignerService := service.NewReceiptSignerService(cfg.AccountDeletionConfig)
+
+	userSearchLimiter := service.NewRateLimiterService(cfg.RateLimitConfig.UserSearch)
 
-	userUC := usecase.NewUserUC(userRepository, validate)
-	authUC := usecase.NewAuthUC(userRepository, jwtService, validate)
-	photosUC := usecase.NewPhotosUC(photosRepository)
+	userUC := usecase.NewUserUC(userRepository, blockRepository, transactor, userSearchLimiter, validate)
+	authUC := usecase.NewAuthUC(userRepository, jwtService, validate, cfg.AccountDeletionConfig)
+	photosUC := usecase.NewPhotosUC(photosRepository, blobRepository, tagRepository, photoVersionRepository, followRepository, blockRepository, reportRepository, actionRepository, usageRepository, userRepository, transactor, blobStore, urlSignerService, classifier, cfg.TransformConfig, cfg.TrashConfig, cfg.QuotaConfig)
+	reconcilerUC := usecase.NewReconcilerUC(photosRepository, blobRepository, transactor, blobStore, cfg.ReconcileConfig)
+	likeUC := usecase.NewLikeUC(likeRepository, photosRepository, followRepository, blockRepository)
+	commentUC := usecase.NewCommentUC(commentRepository, photosRepository, followRepository, blockRepository)
+	followUC := usecase.NewFollowUC(followRepository, blockRepository, userRepository)
+	blockUC := usecase.NewBlockUC(blockRepository, muteRepository, followRepository, userRepository, transactor)
+	moderationUC := usecase.NewModerationUC(reportRepository, actionRepository, photosRepository, userRepository, followRepository, blockRepository, transactor, photosUC)
+	usageUC := usecase.NewUsageUC(usageRepository, userRepository, photosRepository, blobStore, cfg.QuotaConfig)
+	exportUC := usecase.NewExportUC(exportRepository, photosRepository, photoVersionRepository, personalDataRepository, blobStore, cfg.ApiConfig.BaseUrl, cfg.ExportConfig)
+	uploadUC := usecase.NewUploadUC(uploadRepository, directUploadRepository, transactor, blobStore, photosUC, validate, cfg.UploadConfig)
+	erasureUC := usecase.NewErasureUC(userRepository, personalDataRepository, erasureReceiptRepository, transactor, photosUC, uploadUC, exportUC, receiptSignerService, cfg.AccountDeletionConfig)
 
-	newMiddleware := middleware.NewMiddleware(jwtService)
+	newMiddleware := middleware.NewMiddleware(jwtService, authUC)
 
 	engine := gin.Default()
 
 	host := fmt.Sprintf(":%s", cfg.ApiConfig.ApiPort)
 
 	return &Server{
-		Host:       host,
-		Engine:     engine,
-		UserUC:     userUC,
-		AuthUC:     authUC,
-		Middleware: newMiddleware,
-		PhotoUC:    photosUC,
+		Host:         host,
+		Engine:       engine,
+		UserUC:       userUC,
+		AuthUC:       authUC,
+		Middleware:   newMiddleware,
+		PhotoUC:      photosUC,
+		Reconciler:   reconcilerUC,
+		UploadUC:     uploadUC,
+		LikeUC:       likeUC,
+		CommentUC:    commentUC,
+		FollowUC:     followUC,
+		BlockUC:      blockUC,
+		ModerationUC: moderationUC,
+		UsageUC:      usageUC,
+		ExportUC:     exportUC,
+		ErasureUC:    erasureUC,
+		Config:       cfg,
 	}
 }
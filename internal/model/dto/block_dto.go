@@ -0,0 +1,11 @@
+package dto
+
+type BlockResponse struct {
+	UserId  string `json:"user_id"`
+	Blocked bool   `json:"blocked"`
+}
+
+type MuteResponse struct {
+	UserId string `json:"user_id"`
+	Muted  bool   `json:"muted"`
+}
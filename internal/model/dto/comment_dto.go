@@ -0,0 +1,26 @@
+package dto
+
+type CommentRequest struct {
+	Body     string `json:"body"`
+	ParentId string `json:"parent_id"`
+}
+
+// CommentResponse nests the replies to a comment, oldest first.
+type CommentResponse struct {
+	Id        string            `json:"id"`
+	PhotoId   string            `json:"photo_id"`
+	UserId    string            `json:"user_id"`
+	ParentId  string            `json:"parent_id,omitempty"`
+	Body      string            `json:"body"`
+	Edited    bool              `json:"edited"`
+	CreatedAt string            `json:"created_at"`
+	UpdatedAt string            `json:"updated_at"`
+	Replies   []CommentResponse `json:"replies"`
+}
+
+// CommentListResponse pages through the threads on a photo, a page holds the top level
+// comments with all their replies.
+type CommentListResponse struct {
+	Items []CommentResponse
+	Page  PageResponse
+}
@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"io"
+	"time"
+)
+
+// PhotoExport is either an Archive streamed while it is read or, for large exports,
+// the Job that builds it in the background.
+type PhotoExport struct {
+	Archive  io.ReadCloser
+	Filename string
+	Job      *ExportResponse
+}
+
+// ExportResponse is an export job, DownloadUrl is set once the archive is ready.
+type ExportResponse struct {
+	Id          string `json:"id"`
+	Status      string `json:"status"`
+	Size        int64  `json:"size"`
+	Error       string `json:"error,omitempty"`
+	DownloadUrl string `json:"download_url,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+}
+
+// ExportMetadata is written to metadata.json next to the files of an export.
+type ExportMetadata struct {
+	UserId     string                `json:"user_id"`
+	ExportedAt time.Time             `json:"exported_at"`
+	Photos     []ExportPhotoMetadata `json:"photos"`
+}
+
+// ExportPhotoMetadata describes one file of an export, VersionId is set for earlier versions of the photo.
+type ExportPhotoMetadata struct {
+	File       string    `json:"file"`
+	PhotoId    string    `json:"photo_id"`
+	VersionId  string    `json:"version_id,omitempty"`
+	Title      string    `json:"title"`
+	Caption    string    `json:"caption"`
+	Tags       []string  `json:"tags,omitempty"`
+	Visibility string    `json:"visibility,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+type FollowResponse struct {
+	UserId        string `json:"user_id"`
+	Following     bool   `json:"following"`
+	FollowerCount int    `json:"follower_count"`
+}
+
+type FollowUserResponse struct {
+	UserId     string `json:"user_id"`
+	Username   string `json:"username"`
+	FollowedAt string `json:"followed_at"`
+}
+
+// FollowListResponse pages through followers or followed users.
+type FollowListResponse struct {
+	Items []FollowUserResponse
+	Page  PageResponse
+}
+
+// FeedResponse pages through the photos of followed users, newest first.
+type FeedResponse struct {
+	Items []PhotosResponse
+	Page  PageResponse
+}
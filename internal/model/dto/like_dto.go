@@ -0,0 +1,7 @@
+package dto
+
+type LikeResponse struct {
+	PhotoId   string `json:"photo_id"`
+	Liked     bool   `json:"liked"`
+	LikeCount int    `json:"like_count"`
+}
@@ -5,6 +5,13 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required,min=6"`
 }
 
+// ReactivateRequest logs in to a deactivated account, Confirm must be true to take it back.
+type ReactivateRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+	Confirm  bool   `json:"confirm" validate:"required"`
+}
+
 type LoginResponse struct {
 	Token string `json:"token"`
 }
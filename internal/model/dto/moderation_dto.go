@@ -0,0 +1,53 @@
+package dto
+
+type ReportRequest struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+// ResolveReportRequest decides a claimed report, Action is one of dismiss, hide_photo,
+// delete_photo and suspend_user.
+type ResolveReportRequest struct {
+	Action string `json:"action"`
+	Note   string `json:"note"`
+}
+
+type ReportResponse struct {
+	Id           string `json:"id"`
+	PhotoId      string `json:"photo_id"`
+	PhotoOwnerId string `json:"photo_owner_id"`
+	ReporterId   string `json:"reporter_id,omitempty"`
+	Reason       string `json:"reason"`
+	Details      string `json:"details"`
+	Status       string `json:"status"`
+	ClaimedBy    string `json:"claimed_by,omitempty"`
+	ClaimedAt    string `json:"claimed_at,omitempty"`
+	ResolvedBy   string `json:"resolved_by,omitempty"`
+	ResolvedAt   string `json:"resolved_at,omitempty"`
+	Resolution   string `json:"resolution,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// ReportListResponse pages through the moderation queue oldest first.
+type ReportListResponse struct {
+	Items []ReportResponse
+	Page  PageResponse
+}
+
+// ModerationActionResponse is an entry of the audit trail, ActorId is empty for the content classifier.
+type ModerationActionResponse struct {
+	Id        string `json:"id"`
+	ActorId   string `json:"actor_id,omitempty"`
+	Action    string `json:"action"`
+	ReportId  string `json:"report_id,omitempty"`
+	PhotoId   string `json:"photo_id,omitempty"`
+	UserId    string `json:"user_id,omitempty"`
+	Note      string `json:"note"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ModerationActionListResponse pages through the audit trail newest first.
+type ModerationActionListResponse struct {
+	Items []ModerationActionResponse
+	Page  PageResponse
+}
@@ -0,0 +1,19 @@
+package dto
+
+// PageRequest holds the raw pagination query parameters, Cursor is the next_cursor
+// of the previous page and empty for the first one.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// PageResponse is the pagination metadata of a keyset paginated list, it is sent in the
+// Pagination field of the envelope. NextCursor is empty on the last page, Sort is only
+// set by lists that can be sorted. The list responses of the usecases carry Items and
+// Page without json tags, response.PagedResponse sends them as Data and Pagination.
+type PageResponse struct {
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Sort       string `json:"sort,omitempty"`
+}
@@ -0,0 +1,59 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PersonalDataExport is the json document of everything stored about a user. Sessions is
+// always empty: sign-in tokens are stateless and never stored, Notes says so in the document.
+type PersonalDataExport struct {
+	UserId          string          `json:"user_id"`
+	ExportedAt      time.Time       `json:"exported_at"`
+	Notes           []string        `json:"notes"`
+	Profile         json.RawMessage `json:"profile"`
+	Sessions        json.RawMessage `json:"sessions"`
+	Usage           json.RawMessage `json:"usage"`
+	Photos          json.RawMessage `json:"photos"`
+	PhotoVersions   json.RawMessage `json:"photo_versions"`
+	Likes           json.RawMessage `json:"likes"`
+	Comments        json.RawMessage `json:"comments"`
+	Following       json.RawMessage `json:"following"`
+	Followers       json.RawMessage `json:"followers"`
+	Blocks          json.RawMessage `json:"blocks"`
+	Mutes           json.RawMessage `json:"mutes"`
+	Uploads         json.RawMessage `json:"uploads"`
+	DirectUploads   json.RawMessage `json:"direct_uploads"`
+	Exports         json.RawMessage `json:"exports"`
+	ReportsFiled    json.RawMessage `json:"reports_filed"`
+	ReportsReceived json.RawMessage `json:"reports_received"`
+	AuditEvents     json.RawMessage `json:"audit_events"`
+}
+
+// AccountDeletionResponse tells when a deactivated account is erased, it can be
+// reactivated until then. The signed receipt of the erasure is served under ReceiptId
+// once the account is gone.
+type AccountDeletionResponse struct {
+	UserId        string `json:"user_id"`
+	DeactivatedAt string `json:"deactivated_at"`
+	DeleteAt      string `json:"delete_at"`
+	ReceiptId     string `json:"receipt_id"`
+}
+
+// ErasureReceipt proves that an account was erased. Subject is the hex sha-256 of the
+// erased user id, so whoever knows the id can tell the receipt is about it. Deleted and
+// Anonymized count the records by kind, Signature covers every other field.
+type ErasureReceipt struct {
+	Id         string           `json:"id"`
+	Subject    string           `json:"subject"`
+	ErasedAt   string           `json:"erased_at"`
+	Deleted    map[string]int64 `json:"deleted"`
+	Anonymized map[string]int64 `json:"anonymized"`
+	Signature  string           `json:"signature,omitempty"`
+}
+
+// ErasureReceiptVerification is valid when the receipt was issued by this service and has not been altered.
+type ErasureReceiptVerification struct {
+	Id    string `json:"id"`
+	Valid bool   `json:"valid"`
+}
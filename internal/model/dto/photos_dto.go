@@ -1,14 +1,171 @@
 package dto
 
+import (
+	"io"
+	"time"
+)
+
 type PhotosRequest struct {
 	Title   string `json:"title"`
 	Caption string `json:"caption"`
 }
 
 type PhotosResponse struct {
-	Id       string `json:"id"`
-	Title    string `json:"title"`
-	Caption  string `json:"caption"`
-	PhotoUrl string `json:"photo_url"`
-	UserId   string `json:"userId"`
+	Id         string `json:"id"`
+	Title      string `json:"title"`
+	Caption    string `json:"caption"`
+	PhotoUrl   string `json:"photo_url"`
+	UserId     string `json:"userId"`
+	Visibility string `json:"visibility"`
+	// ModerationStatus tells the owner whether the photo is flagged or hidden by a moderator.
+	ModerationStatus string             `json:"moderation_status"`
+	Tags             []string           `json:"tags"`
+	Hash             string             `json:"hash"`
+	CreatedAt        string             `json:"created_at"`
+	UpdatedAt        string             `json:"updated_at"`
+	Crop             *PhotoCropResponse `json:"crop,omitempty"`
+	// Width, Height, BlurHash and DominantColor let clients draw a placeholder while the photo loads.
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	BlurHash      string `json:"blurhash"`
+	DominantColor string `json:"dominant_color"`
+	LikeCount     int    `json:"like_count"`
+	CommentCount  int    `json:"comment_count"`
+	// SimilarPhotos warns about near-duplicates on upload, it is left out everywhere else.
+	SimilarPhotos []SimilarPhotoResponse `json:"similar_photos,omitempty"`
+}
+
+// PhotoListResponse pages through the photos of a user, newest first.
+type PhotoListResponse struct {
+	Items []PhotosResponse
+	Page  PageResponse
+}
+
+// PhotoVersionListResponse pages through the versions of a photo, newest first.
+type PhotoVersionListResponse struct {
+	Items []PhotoVersionResponse
+	Page  PageResponse
+}
+
+// TrashListResponse pages through the trash, most recently deleted first.
+type TrashListResponse struct {
+	Items []TrashPhotoResponse
+	Page  PageResponse
+}
+
+// PhotoVersionResponse is an earlier file of a photo with the title and caption it had then.
+type PhotoVersionResponse struct {
+	Id            string             `json:"id"`
+	PhotoId       string             `json:"photo_id"`
+	Title         string             `json:"title"`
+	Caption       string             `json:"caption"`
+	PhotoUrl      string             `json:"photo_url"`
+	Hash          string             `json:"hash"`
+	Crop          *PhotoCropResponse `json:"crop,omitempty"`
+	Width         int                `json:"width"`
+	Height        int                `json:"height"`
+	BlurHash      string             `json:"blurhash"`
+	DominantColor string             `json:"dominant_color"`
+	CreatedAt     string             `json:"created_at"`
+}
+
+// TrashPhotoResponse is a deleted photo, it can be restored until PurgeAt.
+type TrashPhotoResponse struct {
+	PhotosResponse
+	DeletedAt string `json:"deleted_at"`
+	PurgeAt   string `json:"purge_at"`
+}
+
+// EmptyTrashResponse tells how many photos emptying the trash removed for good.
+type EmptyTrashResponse struct {
+	Removed int `json:"removed"`
+}
+
+// PhotoCropRequest selects the avatar area either as a rectangle in pixels or as
+// a focal point given in fractions of the width and height.
+type PhotoCropRequest struct {
+	Rect   bool
+	X      int
+	Y      int
+	Width  int
+	Height int
+	Focal  bool
+	FocalX float64
+	FocalY float64
+}
+
+type PhotoCropResponse struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type SimilarPhotoResponse struct {
+	PhotoId  string `json:"photo_id"`
+	UserId   string `json:"user_id"`
+	Distance int    `json:"distance"`
+}
+
+type PhotoSearchRequest struct {
+	Query  string
+	Tags   []string
+	Cursor string
+	Limit  int
+}
+
+// PhotoSearchResponse pages through results.
+type PhotoSearchResponse struct {
+	Items []PhotoSearchResult
+	Page  PageResponse
+}
+
+// PhotoSearchResult highlights matches with <mark>, the rest of the text is HTML escaped.
+type PhotoSearchResult struct {
+	PhotosResponse
+	Rank             float32 `json:"rank"`
+	TitleHighlight   string  `json:"title_highlight"`
+	CaptionHighlight string  `json:"caption_highlight"`
+}
+
+type PhotoFile struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Content     io.Reader
+}
+
+type PhotoContent struct {
+	Content      io.ReadSeekCloser
+	ContentType  string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// PhotoTransformRequest holds the raw transform query parameters of the content route,
+// Accept is the Accept header that picks the format of fmt=auto.
+type PhotoTransformRequest struct {
+	Width   string
+	Quality string
+	Format  string
+	Crop    string
+	Accept  string
+}
+
+type SharePhotoRequest struct {
+	Variant   string `json:"variant"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+type SharePhotoResponse struct {
+	Url       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ReconcileReport struct {
+	OrphanFiles  []string
+	StagedFiles  []string
+	MissingFiles []string
+	Deleted      bool
 }
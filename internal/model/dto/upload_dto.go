@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+type UploadResponse struct {
+	Id        string    `json:"id"`
+	Length    int64     `json:"length"`
+	Offset    int64     `json:"offset"`
+	Metadata  string    `json:"metadata"`
+	PhotoId   string    `json:"photo_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UploadUrlResponse is a signed upload form: the client sends Fields followed by the file
+// in a "file" part as multipart/form-data to Url with Method.
+type UploadUrlResponse struct {
+	UploadId  string            `json:"upload_id"`
+	Url       string            `json:"url"`
+	Method    string            `json:"method"`
+	Fields    map[string]string `json:"fields"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+type CompleteUploadRequest struct {
+	UploadId   string `json:"upload_id" validate:"required"`
+	Title      string `json:"title"`
+	Caption    string `json:"caption"`
+	Visibility string `json:"visibility"`
+}
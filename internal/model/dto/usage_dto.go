@@ -0,0 +1,12 @@
+package dto
+
+// UsageResponse is the storage a user takes up against the quota of their plan,
+// a zero maximum means unlimited.
+type UsageResponse struct {
+	Plan       string `json:"plan"`
+	Bytes      int64  `json:"bytes"`
+	MaxBytes   int64  `json:"max_bytes"`
+	PhotoCount int    `json:"photo_count"`
+	MaxPhotos  int    `json:"max_photos"`
+	UpdatedAt  string `json:"updated_at,omitempty"`
+}
@@ -1,24 +1,66 @@
 package dto
 
 type UserRequest struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required,min=6"`
-	Email    string `json:"email" validate:"required,email"`
+	Username    string `json:"username" validate:"required"`
+	DisplayName string `json:"display_name" validate:"max=100"`
+	Password    string `json:"password" validate:"required,min=6"`
+	Email       string `json:"email" validate:"required,email"`
 }
 
+// UserUpdateRequest keeps the stored display name when DisplayName is left out, an empty
+// string clears it.
 type UserUpdateRequest struct {
-	Username string `json:"username" valid:"Required"`
-	Email    string `json:"email" valid:"Required,email"`
+	Username    string  `json:"username" valid:"Required"`
+	DisplayName *string `json:"display_name" validate:"omitempty,max=100"`
+	Email       string  `json:"email" valid:"Required,email"`
 }
 
 type UserResponse struct {
-	Id        string `json:"id"`
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	Id          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
 }
 
 type UpdatePasswordRequest struct {
 	Password string `json:"password" validate:"required,min=6"`
 }
+
+// UserListRequest holds the raw query parameters of the user list. CreatedAfter and
+// CreatedBefore are RFC 3339 timestamps or dates, Sort is a sort key, descending when
+// prefixed with "-".
+type UserListRequest struct {
+	Page           PageRequest
+	EmailDomain    string
+	UsernamePrefix string
+	CreatedAfter   string
+	CreatedBefore  string
+	Sort           string
+}
+
+// UserListResponse pages through the users in the order asked for.
+type UserListResponse struct {
+	Items []UserResponse
+	Page  PageResponse
+}
+
+type UserSearchRequest struct {
+	Query string
+	Page  PageRequest
+}
+
+// UserSearchResult is what any user may see of another one, the email is left out.
+type UserSearchResult struct {
+	Id          string  `json:"id"`
+	Username    string  `json:"username"`
+	DisplayName string  `json:"display_name"`
+	Rank        float32 `json:"rank"`
+}
+
+// UserSearchResponse pages through the matches, best first.
+type UserSearchResponse struct {
+	Items []UserSearchResult
+	Page  PageResponse
+}
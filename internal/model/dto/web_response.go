@@ -1,7 +1,8 @@
 package dto
 
 type WebResponse struct {
-	Code    int
-	Message string
-	Data    interface{}
+	Code       int
+	Message    string
+	Data       interface{}
+	Pagination *PageResponse `json:",omitempty"`
 }
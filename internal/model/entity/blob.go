@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// Blob is a stored file addressed by the SHA-256 of its content. RefCount is
+// the number of rows pointing at it, the file is removed when it drops to zero.
+type Blob struct {
+	Hash        string
+	ObjectKey   string
+	Size        int64
+	ContentType string
+	RefCount    int
+	CreatedAt   time.Time
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// Comment is a comment on a photo, ParentId is the comment it replies to and empty
+// for a comment on the photo itself.
+type Comment struct {
+	Id        string
+	PhotoId   string
+	UserId    string
+	ParentId  string
+	Body      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
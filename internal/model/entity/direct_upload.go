@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// DirectUpload is an upload that goes straight from the client to storage through a presigned url.
+type DirectUpload struct {
+	Id        string
+	UserId    string
+	ObjectKey string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
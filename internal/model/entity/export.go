@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Export jobs go from pending to running to done or failed.
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+)
+
+// An export either archives the photos of a user or is the json document of all their personal data.
+const (
+	ExportKindPhotos       = "photos"
+	ExportKindPersonalData = "personal_data"
+)
+
+// Export is a file of the given Kind built in the background. ObjectKey is set once it
+// is done, Error once it failed, ExpiresAt in both cases.
+type Export struct {
+	Id          string
+	UserId      string
+	Kind        string
+	Status      string
+	ObjectKey   string
+	Size        int64
+	Error       string
+	CreatedAt   time.Time
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// FollowUser is a user in a follower or following list, FollowedAt is when the follow started.
+type FollowUser struct {
+	UserId     string
+	Username   string
+	FollowedAt time.Time
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// Keyset selects a page of a list ordered by creation time. Without a cursor the page
+// starts at the first item, otherwise right after (AfterCreatedAt, AfterId) in the order of the list.
+// Lists ordered by a text column page after (AfterKey, AfterId) instead.
+type Keyset struct {
+	Limit          int
+	HasCursor      bool
+	AfterCreatedAt time.Time
+	AfterKey       string
+	AfterId        string
+}
@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PersonalData is everything stored about a user, each section is already encoded as json.
+// Single rows are objects or null, every other section is an array.
+type PersonalData struct {
+	Profile         json.RawMessage
+	Usage           json.RawMessage
+	Photos          json.RawMessage
+	PhotoVersions   json.RawMessage
+	Likes           json.RawMessage
+	Comments        json.RawMessage
+	Following       json.RawMessage
+	Followers       json.RawMessage
+	Blocks          json.RawMessage
+	Mutes           json.RawMessage
+	Uploads         json.RawMessage
+	DirectUploads   json.RawMessage
+	Exports         json.RawMessage
+	ReportsFiled    json.RawMessage
+	ReportsReceived json.RawMessage
+	AuditEvents     json.RawMessage
+}
+
+// PersonalDataCount is how many rows of each kind belong to a user.
+type PersonalDataCount struct {
+	Photos          int64
+	PhotoVersions   int64
+	Likes           int64
+	Comments        int64
+	RepliedComments int64
+	Follows         int64
+	Blocks          int64
+	Mutes           int64
+	Uploads         int64
+	Exports         int64
+	ReportsFiled    int64
+	ReportsReceived int64
+	AuditEvents     int64
+}
+
+// ErasureReceipt is kept after a user is erased. Receipt is the signed document as it
+// was handed out.
+type ErasureReceipt struct {
+	Id        string
+	Subject   string
+	Receipt   string
+	Signature string
+	ErasedAt  time.Time
+}
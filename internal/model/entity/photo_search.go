@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// PhotoSearch filters photos by a full-text query and tags. Results come after the
+// position given by the After fields when HasCursor is set.
+type PhotoSearch struct {
+	ViewerId       string
+	Query          string
+	Tags           []string
+	Limit          int
+	HasCursor      bool
+	AfterRank      float32
+	AfterCreatedAt time.Time
+	AfterId        string
+}
+
+type PhotoSearchResult struct {
+	Photo            Photos
+	Rank             float32
+	TitleHighlight   string
+	CaptionHighlight string
+}
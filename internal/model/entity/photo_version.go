@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PhotoVersion is an earlier file of a photo with the title and caption it had then.
+// CreatedAt is when that state of the photo was saved.
+type PhotoVersion struct {
+	Id             string
+	PhotoId        string
+	BlobHash       string
+	PhotoUrl       string
+	Title          string
+	Caption        string
+	PerceptualHash sql.NullInt64
+	Crop           PhotoCrop
+	Width          int
+	Height         int
+	BlurHash       string
+	DominantColor  string
+	CreatedAt      time.Time
+}
@@ -1,9 +1,59 @@
 package entity
 
+import (
+	"database/sql"
+	"time"
+)
+
+// Visibility decides who besides the owner may see a photo.
+const (
+	VisibilityPublic    = "public"
+	VisibilityFollowers = "followers"
+	VisibilityPrivate   = "private"
+)
+
+// ModerationStatus overrides the visibility: only visible photos are shown to anyone but their owner.
+const (
+	ModerationVisible = "visible"
+	ModerationFlagged = "flagged"
+	ModerationHidden  = "hidden"
+)
+
 type Photos struct {
-	Id       string
-	Title    string
-	Caption  string
-	PhotoUrl string
-	UserId   string
+	Id         string
+	Title      string
+	Caption    string
+	PhotoUrl   string
+	UserId     string
+	Visibility string
+	// ModerationStatus is one of the Moderation constants. OwnerInactive hides the photo as well,
+	// it is set while the owner is suspended or deactivated.
+	ModerationStatus string
+	OwnerInactive    bool
+	BlobHash         string
+	// PerceptualHash is the DHash of the image, null when the file could not be decoded.
+	PerceptualHash sql.NullInt64
+	Crop           PhotoCrop
+	Width          int
+	Height         int
+	BlurHash       string
+	DominantColor  string
+	// Tags are the normalised tag names. On writes a nil slice keeps the stored tags
+	// and an empty one removes them.
+	Tags         []string
+	LikeCount    int
+	CommentCount int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	// DeletedAt is set while the photo waits in the trash.
+	DeletedAt sql.NullTime
+}
+
+// PhotoCrop is the part of the original, in pixels, that avatars are cut from.
+// A zero Width means no crop was chosen and avatars use the centre of the photo.
+type PhotoCrop struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
 }
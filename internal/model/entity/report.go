@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Reason codes of a report.
+const (
+	ReportReasonSpam       = "spam"
+	ReportReasonNudity     = "nudity"
+	ReportReasonViolence   = "violence"
+	ReportReasonHarassment = "harassment"
+	ReportReasonHate       = "hate"
+	ReportReasonCopyright  = "copyright"
+	ReportReasonIllegal    = "illegal"
+	ReportReasonOther      = "other"
+)
+
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusClaimed  = "claimed"
+	ReportStatusResolved = "resolved"
+)
+
+// Resolutions of a report, the audit trail records them under the same names.
+const (
+	ResolutionDismiss     = "dismiss"
+	ResolutionHidePhoto   = "hide_photo"
+	ResolutionDeletePhoto = "delete_photo"
+	ResolutionSuspendUser = "suspend_user"
+)
+
+// Report is a complaint about a photo. An empty ReporterId means the content classifier flagged it.
+type Report struct {
+	Id           string
+	PhotoId      string
+	PhotoOwnerId string
+	ReporterId   string
+	Reason       string
+	Details      string
+	Status       string
+	ClaimedBy    string
+	ClaimedAt    sql.NullTime
+	ResolvedBy   string
+	ResolvedAt   sql.NullTime
+	Resolution   string
+	CreatedAt    time.Time
+}
+
+// Actions recorded in the audit trail besides the resolutions.
+const (
+	ActionAutoFlag = "auto_flag"
+	ActionClaim    = "claim"
+)
+
+// ModerationAction is an entry of the audit trail. An empty ActorId is the content classifier.
+type ModerationAction struct {
+	Id        string
+	ActorId   string
+	Action    string
+	ReportId  string
+	PhotoId   string
+	UserId    string
+	Note      string
+	CreatedAt time.Time
+}
@@ -0,0 +1,7 @@
+package entity
+
+type SimilarPhoto struct {
+	PhotoId  string
+	UserId   string
+	Distance int
+}
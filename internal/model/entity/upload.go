@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+type Upload struct {
+	Id        string
+	UserId    string
+	Length    int64
+	Offset    int64
+	Metadata  string
+	PhotoId   string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// Usage is the storage a user takes up. PhotoCount counts every stored file: the photo,
+// photos in the trash and earlier versions.
+type Usage struct {
+	UserId     string
+	Bytes      int64
+	PhotoCount int
+	UpdatedAt  time.Time
+	// Underflow is set by UsageRepository.Add when a refund took a counter below zero,
+	// the counter was kept at zero.
+	Underflow bool
+}
@@ -1,12 +1,28 @@
 package entity
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
 
 type User struct {
-	Id        string
-	Username  string
-	Email     string
-	Password  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Id          string
+	Username    string
+	DisplayName string
+	Email       string
+	Password    string
+	Role        string
+	// SuspendedAt is set by a moderator, suspended users can neither log in nor use their tokens.
+	SuspendedAt sql.NullTime
+	// DeactivatedAt is set when the user deleted the account, it is erased once the grace period is over.
+	DeactivatedAt sql.NullTime
+	// DeletionReceiptId is the id the erasure receipt gets, known from the deactivation on.
+	DeletionReceiptId string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
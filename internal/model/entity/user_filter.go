@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// Sort keys of the user list.
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortUsername  = "username"
+)
+
+// UserFilter narrows down and orders the user list, zero values leave a filter out.
+// EmailDomain and UsernamePrefix are case insensitive, CreatedAfter and CreatedBefore
+// are exclusive.
+type UserFilter struct {
+	EmailDomain    string
+	UsernamePrefix string
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	SortBy         string
+	Descending     bool
+}
@@ -0,0 +1,19 @@
+package entity
+
+// UserSearch matches users whose username or display name is similar to Query. Results
+// come after (AfterRank, AfterId) when HasCursor is set.
+// UserSearch matches names whose word similarity to Query reaches Threshold.
+type UserSearch struct {
+	ViewerId  string
+	Query     string
+	Threshold float32
+	Limit     int
+	HasCursor bool
+	AfterRank float32
+	AfterId   string
+}
+
+type UserSearchResult struct {
+	User User
+	Rank float32
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"user-personalize/internal/model/entity"
+)
+
+type BlobRepository interface {
+	WithTx(tx *sql.Tx) BlobRepository
+	Lock(hash string) error
+	Acquire(blob entity.Blob) (entity.Blob, error)
+	Release(hash string) (entity.Blob, error)
+	Delete(hash string) error
+	FindByHash(hash string) (entity.Blob, error)
+	FindByObjectKeys(keys []string) ([]entity.Blob, error)
+}
+
+const blobColumns = "hash, object_key, size, content_type, ref_count, created_at"
+
+type blobRepositoryImpl struct {
+	db DBTX
+}
+
+func NewBlobRepository(db *sql.DB) BlobRepository {
+	return &blobRepositoryImpl{db: db}
+}
+
+func (b *blobRepositoryImpl) WithTx(tx *sql.Tx) BlobRepository {
+	return &blobRepositoryImpl{db: tx}
+}
+
+// Lock takes a transaction scoped lock on hash, whether or not a blob row exists for it.
+// It serializes acquiring a hash with removing the file of the same hash.
+func (b *blobRepositoryImpl) Lock(hash string) error {
+	query := "select pg_advisory_xact_lock(hashtext($1))"
+
+	_, err := b.db.Exec(query, hash)
+	if err != nil {
+		return fmt.Errorf("LockBlobRepository : %w", err)
+	}
+
+	return nil
+}
+
+// Acquire inserts the blob with one reference, or adds a reference when the hash is already known.
+func (b *blobRepositoryImpl) Acquire(blob entity.Blob) (entity.Blob, error) {
+	query := "insert into blobs (hash, object_key, size, content_type, ref_count, created_at) values ($1, $2, $3, $4, 1, CURRENT_TIMESTAMP) " +
+		"on conflict (hash) do update set ref_count = blobs.ref_count + 1 returning " + blobColumns
+
+	result, err := b.scan(b.db.QueryRow(query, blob.Hash, blob.ObjectKey, blob.Size, blob.ContentType))
+	if err != nil {
+		return entity.Blob{}, fmt.Errorf("AcquireBlobRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// Release drops one reference and returns the remaining count.
+func (b *blobRepositoryImpl) Release(hash string) (entity.Blob, error) {
+	query := "update blobs set ref_count = ref_count - 1 where hash = $1 returning " + blobColumns
+
+	result, err := b.scan(b.db.QueryRow(query, hash))
+	if err != nil {
+		return entity.Blob{}, fmt.Errorf("ReleaseBlobRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (b *blobRepositoryImpl) Delete(hash string) error {
+	query := "delete from blobs where hash = $1"
+
+	_, err := b.db.Exec(query, hash)
+	if err != nil {
+		return fmt.Errorf("DeleteBlobRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (b *blobRepositoryImpl) FindByHash(hash string) (entity.Blob, error) {
+	query := "select " + blobColumns + " from blobs where hash = $1"
+
+	result, err := b.scan(b.db.QueryRow(query, hash))
+	if err != nil {
+		return entity.Blob{}, fmt.Errorf("FindBlobByHashRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// FindByObjectKeys returns the blobs stored under any of keys.
+func (b *blobRepositoryImpl) FindByObjectKeys(keys []string) ([]entity.Blob, error) {
+	query := "select " + blobColumns + " from blobs where object_key = any($1)"
+
+	rows, err := b.db.Query(query, pq.Array(keys))
+	if err != nil {
+		return nil, fmt.Errorf("FindBlobsByObjectKeysRepository : %w", err)
+	}
+
+	defer rows.Close()
+	blobs := make([]entity.Blob, 0)
+	for rows.Next() {
+		blob, err := b.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("FindBlobsByObjectKeysRepository : %w", err)
+		}
+
+		blobs = append(blobs, blob)
+	}
+
+	return blobs, rows.Err()
+}
+
+func (b *blobRepositoryImpl) scan(row rowScanner) (entity.Blob, error) {
+	var blob entity.Blob
+	err := row.Scan(&blob.Hash, &blob.ObjectKey, &blob.Size, &blob.ContentType, &blob.RefCount, &blob.CreatedAt)
+	return blob, err
+}
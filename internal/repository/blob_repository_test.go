@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"sort"
+	"sync"
+	"testing"
+	"user-personalize/internal/model/entity"
+)
+
+func TestBlobReferencesReachZero(t *testing.T) {
+	tx := testTx(t)
+	blobs := NewBlobRepository(nil).WithTx(tx)
+
+	hash := uuid.NewString()
+	first, err := blobs.Acquire(entity.Blob{Hash: hash, ObjectKey: "blobs/first", Size: 100, ContentType: "image/jpeg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same content stored again only adds a reference, the first object key stays
+	second, err := blobs.Acquire(entity.Blob{Hash: hash, ObjectKey: "blobs/second", Size: 100, ContentType: "image/jpeg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.RefCount != 1 || second.RefCount != 2 || second.ObjectKey != "blobs/first" {
+		t.Fatalf("got %+v then %+v, want 1 then 2 references to blobs/first", first, second)
+	}
+
+	for _, want := range []int{1, 0} {
+		released, err := blobs.Release(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if released.RefCount != want {
+			t.Fatalf("got %d references after release, want %d", released.RefCount, want)
+		}
+	}
+
+	err = blobs.Delete(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = blobs.Release(hash)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("release of a deleted blob : got error %v, want sql.ErrNoRows", err)
+	}
+}
+
+// TestConcurrentReleasesAreCounted releases from transactions of their own, the blob row
+// is committed and removed when the test ends.
+func TestConcurrentReleasesAreCounted(t *testing.T) {
+	db := testDB(t)
+	blobs := NewBlobRepository(db)
+
+	const references = 8
+	hash := uuid.NewString()
+	for i := 0; i < references; i++ {
+		_, err := blobs.Acquire(entity.Blob{Hash: hash, ObjectKey: "blobs/" + hash, Size: 100, ContentType: "image/jpeg"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { blobs.Delete(hash) })
+
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+	remaining := make([]int, 0, references)
+	for i := 0; i < references; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			err := NewTransactor(db).WithinTransaction(func(tx *sql.Tx) error {
+				blob, err := blobs.WithTx(tx).Release(hash)
+				if err != nil {
+					return err
+				}
+
+				mutex.Lock()
+				remaining = append(remaining, blob.RefCount)
+				mutex.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wait.Wait()
+
+	// every release saw the one before it, so exactly one of them reached zero
+	sort.Ints(remaining)
+	for i, count := range remaining {
+		if count != i {
+			t.Fatalf("got remaining references %v, want 0 to %d once each", remaining, references-1)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type BlockRepository interface {
+	WithTx(tx *sql.Tx) BlockRepository
+	Block(blockerId string, blockedId string) error
+	Unblock(blockerId string, blockedId string) error
+	IsBlocked(userId string, otherId string) (bool, error)
+	FindBlockedIds(userId string) ([]string, error)
+}
+
+type blockRepositoryImpl struct {
+	db DBTX
+}
+
+func NewBlockRepository(db *sql.DB) BlockRepository {
+	return &blockRepositoryImpl{db: db}
+}
+
+func (b *blockRepositoryImpl) WithTx(tx *sql.Tx) BlockRepository {
+	return &blockRepositoryImpl{db: tx}
+}
+
+// Block does nothing when blockerId already blocks blockedId.
+func (b *blockRepositoryImpl) Block(blockerId string, blockedId string) error {
+	query := "insert into blocks (blocker_id, blocked_id, created_at) values ($1, $2, CURRENT_TIMESTAMP) on conflict (blocker_id, blocked_id) do nothing"
+
+	_, err := b.db.Exec(query, blockerId, blockedId)
+	if err != nil {
+		return fmt.Errorf("BlockRepository : %w", err)
+	}
+
+	return nil
+}
+
+// Unblock does nothing when blockerId does not block blockedId.
+func (b *blockRepositoryImpl) Unblock(blockerId string, blockedId string) error {
+	query := "delete from blocks where blocker_id = $1 and blocked_id = $2"
+
+	_, err := b.db.Exec(query, blockerId, blockedId)
+	if err != nil {
+		return fmt.Errorf("UnblockRepository : %w", err)
+	}
+
+	return nil
+}
+
+// IsBlocked reports whether either user blocks the other.
+func (b *blockRepositoryImpl) IsBlocked(userId string, otherId string) (bool, error) {
+	query := "select exists (select 1 from blocks where (blocker_id = $1 and blocked_id = $2) or (blocker_id = $2 and blocked_id = $1))"
+
+	var blocked bool
+	err := b.db.QueryRow(query, userId, otherId).Scan(&blocked)
+	if err != nil {
+		return false, fmt.Errorf("IsBlockedRepository : %w", err)
+	}
+
+	return blocked, nil
+}
+
+// FindBlockedIds returns the users userId blocks or is blocked by.
+func (b *blockRepositoryImpl) FindBlockedIds(userId string) ([]string, error) {
+	query := "select blocked_id from blocks where blocker_id = $1 union select blocker_id from blocks where blocked_id = $1"
+
+	rows, err := b.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("FindBlockedIdsRepository : %w", err)
+	}
+
+	defer rows.Close()
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("FindBlockedIdsRepository : %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// notBlocked is the condition for rows whose userColumn is a user that neither blocks
+// nor is blocked by the viewer bound to viewerArg. An anonymous viewer blocks nobody.
+func notBlocked(viewerArg string, userColumn string) string {
+	return "not exists (select 1 from blocks b where (b.blocker_id = " + viewerArg + " and b.blocked_id = " + userColumn + ") " +
+		"or (b.blocker_id = " + userColumn + " and b.blocked_id = " + viewerArg + "))"
+}
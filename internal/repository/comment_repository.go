@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"user-personalize/internal/model/entity"
+)
+
+type CommentRepository interface {
+	Insert(comment entity.Comment) (entity.Comment, error)
+	Update(comment entity.Comment) (entity.Comment, error)
+	Delete(id string) error
+	FindById(id string) (entity.Comment, error)
+	FindThreads(photoId string, keyset entity.Keyset) ([]entity.Comment, error)
+}
+
+// user_id is null on the comments of erased users that were kept for their replies
+const commentColumns = "id, photo_id, coalesce(user_id, ''), coalesce(parent_id, ''), body, created_at, updated_at"
+
+type commentRepositoryImpl struct {
+	db DBTX
+}
+
+func NewCommentRepository(db *sql.DB) CommentRepository {
+	return &commentRepositoryImpl{db: db}
+}
+
+func (c *commentRepositoryImpl) Insert(comment entity.Comment) (entity.Comment, error) {
+	query := "insert into comments (id, photo_id, user_id, parent_id, body, created_at, updated_at) " +
+		"values ($1, $2, $3, nullif($4, ''), $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) returning " + commentColumns
+
+	result, err := c.scan(c.db.QueryRow(query, comment.Id, comment.PhotoId, comment.UserId, comment.ParentId, comment.Body))
+	if err != nil {
+		return entity.Comment{}, fmt.Errorf("InsertCommentRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (c *commentRepositoryImpl) Update(comment entity.Comment) (entity.Comment, error) {
+	query := "update comments set body = $1, updated_at = CURRENT_TIMESTAMP where id = $2 returning " + commentColumns
+
+	result, err := c.scan(c.db.QueryRow(query, comment.Body, comment.Id))
+	if err != nil {
+		return entity.Comment{}, fmt.Errorf("UpdateCommentRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// Delete removes the comment together with the replies to it.
+func (c *commentRepositoryImpl) Delete(id string) error {
+	query := "delete from comments where id = $1"
+
+	_, err := c.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("DeleteCommentRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (c *commentRepositoryImpl) FindById(id string) (entity.Comment, error) {
+	query := "select " + commentColumns + " from comments where id = $1"
+
+	result, err := c.scan(c.db.QueryRow(query, id))
+	if err != nil {
+		return entity.Comment{}, fmt.Errorf("FindCommentByIdRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// FindThreads returns a page of the top level comments on the photo oldest first, together
+// with every reply to them, all in creation order.
+func (c *commentRepositoryImpl) FindThreads(photoId string, keyset entity.Keyset) ([]entity.Comment, error) {
+	args := []any{photoId}
+	roots := "select id from comments where photo_id = $1 and parent_id is null"
+	if keyset.HasCursor {
+		args = append(args, keyset.AfterCreatedAt, keyset.AfterId)
+		roots += " and (created_at, id) > ($2, $3)"
+	}
+
+	args = append(args, keyset.Limit)
+	roots += " order by created_at, id limit $" + strconv.Itoa(len(args))
+
+	query := "with recursive roots as (" + roots + "), " +
+		"thread as (select id from roots union all select c.id from comments c join thread t on c.parent_id = t.id) " +
+		"select " + commentColumns + " from comments where id in (select id from thread) order by created_at, id"
+
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("FindCommentThreadsRepository : %w", err)
+	}
+
+	defer rows.Close()
+	comments := make([]entity.Comment, 0)
+	for rows.Next() {
+		comment, err := c.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("FindCommentThreadsRepository : %w", err)
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func (c *commentRepositoryImpl) scan(row rowScanner) (entity.Comment, error) {
+	var comment entity.Comment
+	err := row.Scan(&comment.Id, &comment.PhotoId, &comment.UserId, &comment.ParentId, &comment.Body, &comment.CreatedAt, &comment.UpdatedAt)
+	return comment, err
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"user-personalize/internal/model/entity"
+)
+
+type DirectUploadRepository interface {
+	Insert(upload entity.DirectUpload) (entity.DirectUpload, error)
+	FindById(id string) (entity.DirectUpload, error)
+	Delete(id string) error
+	FindExpired(now time.Time) ([]entity.DirectUpload, error)
+	FindByUserId(userId string) ([]entity.DirectUpload, error)
+}
+
+const directUploadColumns = "id, user_id, object_key, expires_at, created_at"
+
+type directUploadRepositoryImpl struct {
+	db DBTX
+}
+
+func NewDirectUploadRepository(db *sql.DB) DirectUploadRepository {
+	return &directUploadRepositoryImpl{db: db}
+}
+
+func (d *directUploadRepositoryImpl) Insert(upload entity.DirectUpload) (entity.DirectUpload, error) {
+	query := "insert into direct_uploads (id, user_id, object_key, expires_at, created_at) values ($1, $2, $3, $4, CURRENT_TIMESTAMP) returning " + directUploadColumns
+
+	result, err := d.scan(d.db.QueryRow(query, upload.Id, upload.UserId, upload.ObjectKey, upload.ExpiresAt))
+	if err != nil {
+		return entity.DirectUpload{}, fmt.Errorf("InsertDirectUploadRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (d *directUploadRepositoryImpl) FindById(id string) (entity.DirectUpload, error) {
+	query := "select " + directUploadColumns + " from direct_uploads where id = $1"
+
+	result, err := d.scan(d.db.QueryRow(query, id))
+	if err != nil {
+		return entity.DirectUpload{}, fmt.Errorf("FindDirectUploadByIdRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (d *directUploadRepositoryImpl) Delete(id string) error {
+	query := "delete from direct_uploads where id = $1"
+
+	_, err := d.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("DeleteDirectUploadRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (d *directUploadRepositoryImpl) FindExpired(now time.Time) ([]entity.DirectUpload, error) {
+	query := "select " + directUploadColumns + " from direct_uploads where expires_at < $1"
+
+	uploads, err := d.findAll(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("FindExpiredDirectUploadRepository : %w", err)
+	}
+
+	return uploads, nil
+}
+
+func (d *directUploadRepositoryImpl) FindByUserId(userId string) ([]entity.DirectUpload, error) {
+	query := "select " + directUploadColumns + " from direct_uploads where user_id = $1"
+
+	uploads, err := d.findAll(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("FindDirectUploadsByUserIdRepository : %w", err)
+	}
+
+	return uploads, nil
+}
+
+func (d *directUploadRepositoryImpl) findAll(query string, args ...any) ([]entity.DirectUpload, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	uploads := make([]entity.DirectUpload, 0)
+	for rows.Next() {
+		upload, err := d.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, rows.Err()
+}
+
+func (d *directUploadRepositoryImpl) scan(row rowScanner) (entity.DirectUpload, error) {
+	var upload entity.DirectUpload
+	err := row.Scan(&upload.Id, &upload.UserId, &upload.ObjectKey, &upload.ExpiresAt, &upload.CreatedAt)
+	return upload, err
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"user-personalize/internal/model/entity"
+)
+
+type ErasureReceiptRepository interface {
+	WithTx(tx *sql.Tx) ErasureReceiptRepository
+	Insert(receipt entity.ErasureReceipt) error
+	FindById(id string) (entity.ErasureReceipt, error)
+}
+
+type erasureReceiptRepositoryImpl struct {
+	db DBTX
+}
+
+func NewErasureReceiptRepository(db *sql.DB) ErasureReceiptRepository {
+	return &erasureReceiptRepositoryImpl{db: db}
+}
+
+func (e *erasureReceiptRepositoryImpl) WithTx(tx *sql.Tx) ErasureReceiptRepository {
+	return &erasureReceiptRepositoryImpl{db: tx}
+}
+
+func (e *erasureReceiptRepositoryImpl) Insert(receipt entity.ErasureReceipt) error {
+	query := "insert into erasure_receipts (id, subject, receipt, signature, erased_at) values ($1, $2, $3, $4, $5)"
+
+	_, err := e.db.Exec(query, receipt.Id, receipt.Subject, receipt.Receipt, receipt.Signature, receipt.ErasedAt)
+	if err != nil {
+		return fmt.Errorf("InsertErasureReceiptRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (e *erasureReceiptRepositoryImpl) FindById(id string) (entity.ErasureReceipt, error) {
+	query := "select id, subject, receipt, signature, erased_at from erasure_receipts where id = $1"
+
+	var receipt entity.ErasureReceipt
+	err := e.db.QueryRow(query, id).Scan(&receipt.Id, &receipt.Subject, &receipt.Receipt, &receipt.Signature, &receipt.ErasedAt)
+	if err != nil {
+		return entity.ErasureReceipt{}, fmt.Errorf("FindErasureReceiptRepository : %w", err)
+	}
+
+	return receipt, nil
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"user-personalize/internal/model/entity"
+)
+
+type ExportRepository interface {
+	Insert(export entity.Export) (entity.Export, error)
+	FindById(id string) (entity.Export, error)
+	FindActiveByUserId(userId string, kind string) (entity.Export, error)
+	FindByUserId(userId string) ([]entity.Export, error)
+	ClaimNext(staleBefore time.Time) (entity.Export, error)
+	Complete(id string, objectKey string, size int64, expiresAt time.Time) error
+	Fail(id string, message string, expiresAt time.Time) error
+	FindExpired(now time.Time) ([]entity.Export, error)
+	Delete(id string) error
+}
+
+const exportColumns = "id, user_id, kind, status, coalesce(object_key, ''), size, coalesce(error, ''), created_at, completed_at, expires_at"
+
+type exportRepositoryImpl struct {
+	db DBTX
+}
+
+func NewExportRepository(db *sql.DB) ExportRepository {
+	return &exportRepositoryImpl{db: db}
+}
+
+func (e *exportRepositoryImpl) Insert(export entity.Export) (entity.Export, error) {
+	query := "insert into exports (id, user_id, kind, status, created_at) values ($1, $2, $3, $4, CURRENT_TIMESTAMP) returning " + exportColumns
+
+	result, err := e.scan(e.db.QueryRow(query, export.Id, export.UserId, export.Kind, entity.ExportPending))
+	if err != nil {
+		return entity.Export{}, fmt.Errorf("InsertExportRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (e *exportRepositoryImpl) FindById(id string) (entity.Export, error) {
+	query := "select " + exportColumns + " from exports where id = $1"
+
+	result, err := e.scan(e.db.QueryRow(query, id))
+	if err != nil {
+		return entity.Export{}, fmt.Errorf("FindExportByIdRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// FindActiveByUserId returns the pending or running export of the kind of a user, if there is one.
+func (e *exportRepositoryImpl) FindActiveByUserId(userId string, kind string) (entity.Export, error) {
+	query := "select " + exportColumns + " from exports where user_id = $1 and kind = $2 and status in ('pending', 'running') order by created_at desc limit 1"
+
+	result, err := e.scan(e.db.QueryRow(query, userId, kind))
+	if err != nil {
+		return entity.Export{}, fmt.Errorf("FindActiveExportRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// ClaimNext marks the oldest pending export as running and returns it. Running exports
+// started before staleBefore belong to a worker that died and are claimed again.
+// Concurrent workers skip each other's rows, sql.ErrNoRows means there is nothing to do.
+func (e *exportRepositoryImpl) ClaimNext(staleBefore time.Time) (entity.Export, error) {
+	query := "update exports set status = 'running', started_at = CURRENT_TIMESTAMP where id = (" +
+		"select id from exports where status = 'pending' or (status = 'running' and started_at < $1) " +
+		"order by created_at limit 1 for update skip locked) returning " + exportColumns
+
+	result, err := e.scan(e.db.QueryRow(query, staleBefore))
+	if err != nil {
+		return entity.Export{}, fmt.Errorf("ClaimNextExportRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (e *exportRepositoryImpl) Complete(id string, objectKey string, size int64, expiresAt time.Time) error {
+	query := "update exports set status = 'done', object_key = $1, size = $2, completed_at = CURRENT_TIMESTAMP, expires_at = $3 where id = $4"
+
+	_, err := e.db.Exec(query, objectKey, size, expiresAt, id)
+	if err != nil {
+		return fmt.Errorf("CompleteExportRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (e *exportRepositoryImpl) Fail(id string, message string, expiresAt time.Time) error {
+	query := "update exports set status = 'failed', error = $1, completed_at = CURRENT_TIMESTAMP, expires_at = $2 where id = $3"
+
+	_, err := e.db.Exec(query, message, expiresAt, id)
+	if err != nil {
+		return fmt.Errorf("FailExportRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (e *exportRepositoryImpl) FindByUserId(userId string) ([]entity.Export, error) {
+	query := "select " + exportColumns + " from exports where user_id = $1 order by created_at desc"
+
+	exports, err := e.findAll(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("FindExportsByUserIdRepository : %w", err)
+	}
+
+	return exports, nil
+}
+
+// FindExpired returns the finished exports that are past their expiry.
+func (e *exportRepositoryImpl) FindExpired(now time.Time) ([]entity.Export, error) {
+	query := "select " + exportColumns + " from exports where expires_at < $1"
+
+	exports, err := e.findAll(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("FindExpiredExportsRepository : %w", err)
+	}
+
+	return exports, nil
+}
+
+func (e *exportRepositoryImpl) Delete(id string) error {
+	query := "delete from exports where id = $1"
+
+	_, err := e.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("DeleteExportRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (e *exportRepositoryImpl) findAll(query string, args ...any) ([]entity.Export, error) {
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	exports := make([]entity.Export, 0)
+	for rows.Next() {
+		export, err := e.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+func (e *exportRepositoryImpl) scan(row rowScanner) (entity.Export, error) {
+	var export entity.Export
+	err := row.Scan(&export.Id, &export.UserId, &export.Kind, &export.Status, &export.ObjectKey, &export.Size, &export.Error,
+		&export.CreatedAt, &export.CompletedAt, &export.ExpiresAt)
+	return export, err
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"user-personalize/internal/model/entity"
+)
+
+type FollowRepository interface {
+	WithTx(tx *sql.Tx) FollowRepository
+	Follow(followerId string, followeeId string) error
+	Unfollow(followerId string, followeeId string) error
+	IsFollowing(followerId string, followeeId string) (bool, error)
+	CountFollowers(userId string) (int, error)
+	FindFollowers(userId string, viewerId string, keyset entity.Keyset) ([]entity.FollowUser, error)
+	FindFollowing(userId string, viewerId string, keyset entity.Keyset) ([]entity.FollowUser, error)
+}
+
+type followRepositoryImpl struct {
+	db DBTX
+}
+
+func NewFollowRepository(db *sql.DB) FollowRepository {
+	return &followRepositoryImpl{db: db}
+}
+
+func (f *followRepositoryImpl) WithTx(tx *sql.Tx) FollowRepository {
+	return &followRepositoryImpl{db: tx}
+}
+
+// Follow does nothing when followerId already follows followeeId.
+func (f *followRepositoryImpl) Follow(followerId string, followeeId string) error {
+	query := "insert into follows (follower_id, followee_id, created_at) values ($1, $2, CURRENT_TIMESTAMP) on conflict (follower_id, followee_id) do nothing"
+
+	_, err := f.db.Exec(query, followerId, followeeId)
+	if err != nil {
+		return fmt.Errorf("FollowRepository : %w", err)
+	}
+
+	return nil
+}
+
+// Unfollow does nothing when followerId does not follow followeeId.
+func (f *followRepositoryImpl) Unfollow(followerId string, followeeId string) error {
+	query := "delete from follows where follower_id = $1 and followee_id = $2"
+
+	_, err := f.db.Exec(query, followerId, followeeId)
+	if err != nil {
+		return fmt.Errorf("UnfollowRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (f *followRepositoryImpl) IsFollowing(followerId string, followeeId string) (bool, error) {
+	query := "select exists (select 1 from follows where follower_id = $1 and followee_id = $2)"
+
+	var following bool
+	err := f.db.QueryRow(query, followerId, followeeId).Scan(&following)
+	if err != nil {
+		return false, fmt.Errorf("IsFollowingRepository : %w", err)
+	}
+
+	return following, nil
+}
+
+func (f *followRepositoryImpl) CountFollowers(userId string) (int, error) {
+	query := "select count(*) from follows where followee_id = $1"
+
+	var count int
+	err := f.db.QueryRow(query, userId).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountFollowersRepository : %w", err)
+	}
+
+	return count, nil
+}
+
+// FindFollowers returns the users following userId that viewerId may see, latest follow first.
+func (f *followRepositoryImpl) FindFollowers(userId string, viewerId string, keyset entity.Keyset) ([]entity.FollowUser, error) {
+	users, err := f.find("followee_id", "follower_id", userId, viewerId, keyset)
+	if err != nil {
+		return nil, fmt.Errorf("FindFollowersRepository : %w", err)
+	}
+
+	return users, nil
+}
+
+// FindFollowing returns the users userId follows that viewerId may see, latest follow first.
+func (f *followRepositoryImpl) FindFollowing(userId string, viewerId string, keyset entity.Keyset) ([]entity.FollowUser, error) {
+	users, err := f.find("follower_id", "followee_id", userId, viewerId, keyset)
+	if err != nil {
+		return nil, fmt.Errorf("FindFollowingRepository : %w", err)
+	}
+
+	return users, nil
+}
+
+// find lists the users in column other of the follows whose column by is userId.
+// Both directions have an index on (by, created_at, other), see DDL.sql.
+func (f *followRepositoryImpl) find(by string, other string, userId string, viewerId string, keyset entity.Keyset) ([]entity.FollowUser, error) {
+	args := []any{userId, viewerId}
+	query := "select u.id, u.username, f.created_at from follows f join users u on u.id = f." + other + " where f." + by + " = $1 and u.deactivated_at is null and " + notBlocked("$2", "u.id")
+	if keyset.HasCursor {
+		args = append(args, keyset.AfterCreatedAt, keyset.AfterId)
+		query += " and (f.created_at, f." + other + ") < ($3, $4)"
+	}
+
+	args = append(args, keyset.Limit)
+	query += " order by f.created_at desc, f." + other + " desc limit $" + strconv.Itoa(len(args))
+
+	rows, err := f.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	users := make([]entity.FollowUser, 0)
+	for rows.Next() {
+		var user entity.FollowUser
+		err := rows.Scan(&user.UserId, &user.Username, &user.FollowedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type LikeRepository interface {
+	Like(photoId string, userId string) error
+	Unlike(photoId string, userId string) error
+	CountByPhotoId(photoId string) (int, error)
+}
+
+type likeRepositoryImpl struct {
+	db DBTX
+}
+
+func NewLikeRepository(db *sql.DB) LikeRepository {
+	return &likeRepositoryImpl{db: db}
+}
+
+// Like does nothing when userId already likes the photo.
+func (l *likeRepositoryImpl) Like(photoId string, userId string) error {
+	query := "insert into photo_likes (photo_id, user_id, created_at) values ($1, $2, CURRENT_TIMESTAMP) on conflict (photo_id, user_id) do nothing"
+
+	_, err := l.db.Exec(query, photoId, userId)
+	if err != nil {
+		return fmt.Errorf("LikeRepository : %w", err)
+	}
+
+	return nil
+}
+
+// Unlike does nothing when userId does not like the photo.
+func (l *likeRepositoryImpl) Unlike(photoId string, userId string) error {
+	query := "delete from photo_likes where photo_id = $1 and user_id = $2"
+
+	_, err := l.db.Exec(query, photoId, userId)
+	if err != nil {
+		return fmt.Errorf("UnlikeRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (l *likeRepositoryImpl) CountByPhotoId(photoId string) (int, error) {
+	query := "select count(*) from photo_likes where photo_id = $1"
+
+	var count int
+	err := l.db.QueryRow(query, photoId).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountLikesRepository : %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"user-personalize/internal/model/entity"
+)
+
+// ModerationActionRepository is the audit trail of moderation, entries are never changed or removed.
+type ModerationActionRepository interface {
+	WithTx(tx *sql.Tx) ModerationActionRepository
+	Insert(action entity.ModerationAction) error
+	FindAll(keyset entity.Keyset) ([]entity.ModerationAction, error)
+}
+
+type moderationActionRepositoryImpl struct {
+	db DBTX
+}
+
+func NewModerationActionRepository(db *sql.DB) ModerationActionRepository {
+	return &moderationActionRepositoryImpl{db: db}
+}
+
+func (m *moderationActionRepositoryImpl) WithTx(tx *sql.Tx) ModerationActionRepository {
+	return &moderationActionRepositoryImpl{db: tx}
+}
+
+func (m *moderationActionRepositoryImpl) Insert(action entity.ModerationAction) error {
+	query := "insert into moderation_actions (id, actor_id, action, report_id, photo_id, user_id, note, created_at) " +
+		"values ($1, nullif($2, ''), $3, nullif($4, ''), nullif($5, ''), nullif($6, ''), $7, CURRENT_TIMESTAMP)"
+
+	_, err := m.db.Exec(query, action.Id, action.ActorId, action.Action, action.ReportId, action.PhotoId, action.UserId, action.Note)
+	if err != nil {
+		return fmt.Errorf("InsertModerationActionRepository : %w", err)
+	}
+
+	return nil
+}
+
+// FindAll returns the audit trail newest first.
+func (m *moderationActionRepositoryImpl) FindAll(keyset entity.Keyset) ([]entity.ModerationAction, error) {
+	args := make([]any, 0)
+	query := "select id, coalesce(actor_id, ''), action, coalesce(report_id, ''), coalesce(photo_id, ''), coalesce(user_id, ''), note, created_at from moderation_actions"
+	if keyset.HasCursor {
+		args = append(args, keyset.AfterCreatedAt, keyset.AfterId)
+		query += " where (created_at, id) < ($1, $2)"
+	}
+
+	args = append(args, keyset.Limit)
+	query += " order by created_at desc, id desc limit $" + strconv.Itoa(len(args))
+
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("FindModerationActionsRepository : %w", err)
+	}
+
+	defer rows.Close()
+	actions := make([]entity.ModerationAction, 0)
+	for rows.Next() {
+		var action entity.ModerationAction
+		err := rows.Scan(&action.Id, &action.ActorId, &action.Action, &action.ReportId, &action.PhotoId, &action.UserId, &action.Note, &action.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("FindModerationActionsRepository : %w", err)
+		}
+
+		actions = append(actions, action)
+	}
+
+	return actions, rows.Err()
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type MuteRepository interface {
+	Mute(muterId string, mutedId string) error
+	Unmute(muterId string, mutedId string) error
+}
+
+type muteRepositoryImpl struct {
+	db DBTX
+}
+
+func NewMuteRepository(db *sql.DB) MuteRepository {
+	return &muteRepositoryImpl{db: db}
+}
+
+// Mute does nothing when muterId already mutes mutedId.
+func (m *muteRepositoryImpl) Mute(muterId string, mutedId string) error {
+	query := "insert into mutes (muter_id, muted_id, created_at) values ($1, $2, CURRENT_TIMESTAMP) on conflict (muter_id, muted_id) do nothing"
+
+	_, err := m.db.Exec(query, muterId, mutedId)
+	if err != nil {
+		return fmt.Errorf("MuteRepository : %w", err)
+	}
+
+	return nil
+}
+
+// Unmute does nothing when muterId does not mute mutedId.
+func (m *muteRepositoryImpl) Unmute(muterId string, mutedId string) error {
+	query := "delete from mutes where muter_id = $1 and muted_id = $2"
+
+	_, err := m.db.Exec(query, muterId, mutedId)
+	if err != nil {
+		return fmt.Errorf("UnmuteRepository : %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"user-personalize/internal/model/entity"
+)
+
+// PersonalDataRepository reads and anonymizes the data of a user across all tables, it
+// backs the personal data export and the erasure of an account.
+type PersonalDataRepository interface {
+	WithTx(tx *sql.Tx) PersonalDataRepository
+	Find(userId string) (entity.PersonalData, error)
+	Count(userId string) (entity.PersonalDataCount, error)
+	Anonymize(userId string, pseudonym string) error
+}
+
+// repliedComments selects the ids of the comments of user $1 that have a reply by someone
+// else anywhere below them. Erasure keeps them, anonymized, so the replies stay in place.
+const repliedComments = "with recursive subtree (root, id) as (" +
+	"select c.id, c.id from comments c where c.user_id = $1 " +
+	"union all select s.root, r.id from comments r join subtree s on r.parent_id = s.id) " +
+	"select distinct s.root from subtree s join comments r on r.id = s.id where r.user_id is distinct from $1"
+
+type personalDataRepositoryImpl struct {
+	db DBTX
+}
+
+func NewPersonalDataRepository(db *sql.DB) PersonalDataRepository {
+	return &personalDataRepositoryImpl{db: db}
+}
+
+func (p *personalDataRepositoryImpl) WithTx(tx *sql.Tx) PersonalDataRepository {
+	return &personalDataRepositoryImpl{db: tx}
+}
+
+// Find leaves out what belongs to other users: who reported the user's photos, which
+// moderator acted and who blocked the user. Passwords are never exported.
+func (p *personalDataRepositoryImpl) Find(userId string) (entity.PersonalData, error) {
+	var data entity.PersonalData
+	sections := []struct {
+		target *json.RawMessage
+		query  string
+	}{
+		{&data.Profile, jsonObject("select id, username, display_name, email, role, suspended_at, deactivated_at, created_at, updated_at from users where id = $1")},
+		{&data.Usage, jsonObject("select bytes, photo_count, updated_at from user_usage where user_id = $1")},
+		{&data.Photos, jsonArray("select p.id, p.title, p.caption, p.visibility, p.moderation_status, p.width, p.height, " +
+			"coalesce(array(select t.name from photo_tags pt join tags t on t.id = pt.tag_id where pt.photo_id = p.id order by t.name), '{}') as tags, " +
+			"p.created_at, p.updated_at, p.deleted_at from photos p where p.user_id = $1 order by p.created_at")},
+		{&data.PhotoVersions, jsonArray("select v.id, v.photo_id, v.title, v.caption, v.width, v.height, v.created_at " +
+			"from photo_versions v join photos p on p.id = v.photo_id where p.user_id = $1 order by v.created_at")},
+		{&data.Likes, jsonArray("select photo_id, created_at from photo_likes where user_id = $1 order by created_at")},
+		{&data.Comments, jsonArray("select id, photo_id, parent_id, body, created_at, updated_at from comments where user_id = $1 order by created_at")},
+		{&data.Following, jsonArray("select followee_id as user_id, created_at from follows where follower_id = $1 order by created_at")},
+		{&data.Followers, jsonArray("select follower_id as user_id, created_at from follows where followee_id = $1 order by created_at")},
+		{&data.Blocks, jsonArray("select blocked_id as user_id, created_at from blocks where blocker_id = $1 order by created_at")},
+		{&data.Mutes, jsonArray("select muted_id as user_id, created_at from mutes where muter_id = $1 order by created_at")},
+		{&data.Uploads, jsonArray("select id, upload_length, upload_offset, metadata, photo_id, expires_at, created_at from uploads where user_id = $1 order by created_at")},
+		{&data.DirectUploads, jsonArray("select id, expires_at, created_at from direct_uploads where user_id = $1 order by created_at")},
+		{&data.Exports, jsonArray("select id, kind, status, size, created_at, completed_at, expires_at from exports where user_id = $1 order by created_at")},
+		{&data.ReportsFiled, jsonArray("select id, photo_id, reason, details, status, resolution, created_at, resolved_at from reports where reporter_id = $1 order by created_at")},
+		{&data.ReportsReceived, jsonArray("select id, photo_id, reason, status, resolution, created_at, resolved_at from reports where photo_owner_id = $1 order by created_at")},
+		{&data.AuditEvents, jsonArray("select id, action, " +
+			"case when actor_id = $1 then 'you' when actor_id is null then 'classifier' else 'moderator' end as actor, " +
+			"report_id, photo_id, case when user_id = $1 then 'you' else user_id end as user_id, note, created_at " +
+			"from moderation_actions where user_id = $1 or actor_id = $1 order by created_at")},
+	}
+
+	for _, section := range sections {
+		err := p.db.QueryRow(section.query, userId).Scan(section.target)
+		if err != nil {
+			return entity.PersonalData{}, fmt.Errorf("FindPersonalDataRepository : %w", err)
+		}
+	}
+
+	return data, nil
+}
+
+func (p *personalDataRepositoryImpl) Count(userId string) (entity.PersonalDataCount, error) {
+	query := "select " +
+		"(select count(*) from photos where user_id = $1), " +
+		"(select count(*) from photo_versions v join photos p on p.id = v.photo_id where p.user_id = $1), " +
+		"(select count(*) from photo_likes where user_id = $1), " +
+		"(select count(*) from comments where user_id = $1) - (select count(*) from (" + repliedComments + ") rc), " +
+		"(select count(*) from (" + repliedComments + ") rc), " +
+		"(select count(*) from follows where follower_id = $1 or followee_id = $1), " +
+		"(select count(*) from blocks where blocker_id = $1 or blocked_id = $1), " +
+		"(select count(*) from mutes where muter_id = $1 or muted_id = $1), " +
+		"(select count(*) from uploads where user_id = $1) + (select count(*) from direct_uploads where user_id = $1), " +
+		"(select count(*) from exports where user_id = $1), " +
+		"(select count(*) from reports where reporter_id = $1), " +
+		"(select count(*) from reports where photo_owner_id = $1), " +
+		"(select count(*) from moderation_actions where user_id = $1 or actor_id = $1)"
+
+	var count entity.PersonalDataCount
+	err := p.db.QueryRow(query, userId).Scan(&count.Photos, &count.PhotoVersions, &count.Likes, &count.Comments, &count.RepliedComments, &count.Follows,
+		&count.Blocks, &count.Mutes, &count.Uploads, &count.Exports, &count.ReportsFiled, &count.ReportsReceived, &count.AuditEvents)
+	if err != nil {
+		return entity.PersonalDataCount{}, fmt.Errorf("CountPersonalDataRepository : %w", err)
+	}
+
+	return count, nil
+}
+
+// Anonymize replaces the user id in reports and the audit trail, which outlive the user,
+// with pseudonym and clears the free text the user wrote or that was written about them.
+// Comments with replies by others lose their author and body, the other comments of the
+// user go with the user row.
+func (p *personalDataRepositoryImpl) Anonymize(userId string, pseudonym string) error {
+	_, err := p.db.Exec("update comments set user_id = null, body = '' where id in ("+repliedComments+")", userId)
+	if err != nil {
+		return fmt.Errorf("AnonymizePersonalDataRepository : %w", err)
+	}
+
+	queries := []string{
+		"update reports set reporter_id = $2, details = '' where reporter_id = $1",
+		"update reports set photo_owner_id = $2 where photo_owner_id = $1",
+		"update reports set claimed_by = $2 where claimed_by = $1",
+		"update reports set resolved_by = $2 where resolved_by = $1",
+		"update moderation_actions set user_id = $2, note = '' where user_id = $1",
+		"update moderation_actions set actor_id = $2 where actor_id = $1",
+	}
+
+	for _, query := range queries {
+		_, err = p.db.Exec(query, userId, pseudonym)
+		if err != nil {
+			return fmt.Errorf("AnonymizePersonalDataRepository : %w", err)
+		}
+	}
+
+	return nil
+}
+
+// jsonObject selects the row of query as a json object, or null when there is none.
+func jsonObject(query string) string {
+	return "select coalesce((select row_to_json(t) from (" + query + ") t), 'null'::json)"
+}
+
+// jsonArray selects the rows of query as a json array.
+func jsonArray(query string) string {
+	return "select coalesce(json_agg(t), '[]'::json) from (" + query + ") t"
+}
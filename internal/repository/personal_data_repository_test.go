@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"user-personalize/internal/model/entity"
+)
+
+func TestAnonymizeKeepsRepliedComments(t *testing.T) {
+	tx := testTx(t)
+	personalData := NewPersonalDataRepository(nil).WithTx(tx)
+	comments := &commentRepositoryImpl{db: tx}
+
+	suffix := uuid.NewString()[:8]
+	erased := testUser(t, tx, "erased_"+suffix)
+	other := testUser(t, tx, "other_"+suffix)
+
+	photo, err := NewPhotosRepository(nil).WithTx(tx).Insert(entity.Photos{Id: uuid.NewString(), UserId: other.Id, Visibility: entity.VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment := func(userId string, parentId string) string {
+		inserted, err := comments.Insert(entity.Comment{Id: uuid.NewString(), PhotoId: photo.Id, UserId: userId, ParentId: parentId, Body: "nice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inserted.Id
+	}
+
+	// a thread of erased that other answered, with a reply of erased in it, and a thread nobody answered
+	replied := comment(erased.Id, "")
+	reply := comment(other.Id, replied)
+	comment(erased.Id, reply)
+	comment(erased.Id, "")
+
+	count, err := personalData.Count(erased.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count.Comments != 2 || count.RepliedComments != 1 {
+		t.Fatalf("got %d comments deleted and %d anonymized, want 2 and 1", count.Comments, count.RepliedComments)
+	}
+
+	err = personalData.Anonymize(erased.Id, "erased-"+suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewUserRepository(nil).WithTx(tx).Delete(erased.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var userId sql.NullString
+	var body string
+	err = tx.QueryRow("select user_id, body from comments where id = $1", replied).Scan(&userId, &body)
+	if err != nil {
+		t.Fatalf("the answered comment is gone : %v", err)
+	}
+
+	if userId.Valid || body != "" {
+		t.Fatalf("the answered comment still has author %q and body %q", userId.String, body)
+	}
+
+	var left int
+	err = tx.QueryRow("select count(*) from comments where photo_id = $1", photo.Id).Scan(&left)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the anonymized comment and the answer of other, the rest went with the user
+	if left != 2 {
+		t.Fatalf("got %d comments left, want 2", left)
+	}
+}
+
+func TestCountSeesFilesStoredAfterThePurge(t *testing.T) {
+	tx := testTx(t)
+	personalData := NewPersonalDataRepository(nil).WithTx(tx)
+
+	user := testUser(t, tx, "erased_"+uuid.NewString()[:8])
+
+	count, err := personalData.Count(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count.Photos != 0 || count.Uploads != 0 {
+		t.Fatalf("got %d photos and %d uploads before any was stored", count.Photos, count.Uploads)
+	}
+
+	// what a request authorized before the suspension may store while the files are purged
+	_, err = NewPhotosRepository(nil).WithTx(tx).Insert(entity.Photos{Id: uuid.NewString(), UserId: user.Id, Visibility: entity.VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewUploadRepository(nil).WithTx(tx).Insert(entity.Upload{Id: uuid.NewString(), UserId: user.Id, Length: 10, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err = personalData.Count(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count.Photos != 1 || count.Uploads != 1 {
+		t.Fatalf("got %d photos and %d uploads, want the erasure to see 1 of each", count.Photos, count.Uploads)
+	}
+}
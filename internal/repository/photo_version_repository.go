@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"user-personalize/internal/model/entity"
+)
+
+type PhotoVersionRepository interface {
+	WithTx(tx *sql.Tx) PhotoVersionRepository
+	Insert(version entity.PhotoVersion) error
+	FindById(id string) (entity.PhotoVersion, error)
+	FindByPhotoId(photoId string) ([]entity.PhotoVersion, error)
+	FindPageByPhotoId(photoId string, keyset entity.Keyset) ([]entity.PhotoVersion, error)
+	FindSurplus(photoId string, keep int) ([]entity.PhotoVersion, error)
+	Delete(id string) error
+}
+
+const photoVersionColumns = "id, photo_id, blob_hash, photo_url, coalesce(title, ''), coalesce(caption, ''), phash, crop_x, crop_y, crop_width, crop_height, " +
+	"width, height, coalesce(blurhash, ''), coalesce(dominant_color, ''), created_at"
+
+type photoVersionRepositoryImpl struct {
+	db DBTX
+}
+
+func NewPhotoVersionRepository(db *sql.DB) PhotoVersionRepository {
+	return &photoVersionRepositoryImpl{db: db}
+}
+
+func (p *photoVersionRepositoryImpl) WithTx(tx *sql.Tx) PhotoVersionRepository {
+	return &photoVersionRepositoryImpl{db: tx}
+}
+
+func (p *photoVersionRepositoryImpl) Insert(version entity.PhotoVersion) error {
+	query := "insert into photo_versions (id, photo_id, blob_hash, photo_url, title, caption, phash, crop_x, crop_y, crop_width, crop_height, width, height, blurhash, dominant_color, created_at) " +
+		"values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, nullif($14, ''), nullif($15, ''), $16)"
+
+	_, err := p.db.Exec(query, version.Id, version.PhotoId, version.BlobHash, version.PhotoUrl, version.Title, version.Caption, version.PerceptualHash,
+		version.Crop.X, version.Crop.Y, version.Crop.Width, version.Crop.Height, version.Width, version.Height, version.BlurHash, version.DominantColor, version.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("InsertPhotoVersionRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (p *photoVersionRepositoryImpl) FindById(id string) (entity.PhotoVersion, error) {
+	query := "select " + photoVersionColumns + " from photo_versions where id = $1"
+
+	result, err := p.scan(p.db.QueryRow(query, id))
+	if err != nil {
+		return entity.PhotoVersion{}, fmt.Errorf("FindPhotoVersionByIdRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// FindByPhotoId returns the versions of a photo, newest first.
+func (p *photoVersionRepositoryImpl) FindByPhotoId(photoId string) ([]entity.PhotoVersion, error) {
+	query := "select " + photoVersionColumns + " from photo_versions where photo_id = $1 order by created_at desc, id desc"
+
+	rows, err := p.db.Query(query, photoId)
+	if err != nil {
+		return nil, fmt.Errorf("FindPhotoVersionsByPhotoIdRepository : %w", err)
+	}
+
+	defer rows.Close()
+	versions := make([]entity.PhotoVersion, 0)
+	for rows.Next() {
+		version, err := p.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("FindPhotoVersionsByPhotoIdRepository : %w", err)
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// FindPageByPhotoId returns a page of the versions of a photo, newest first.
+func (p *photoVersionRepositoryImpl) FindPageByPhotoId(photoId string, keyset entity.Keyset) ([]entity.PhotoVersion, error) {
+	args := []any{photoId}
+	query := "select " + photoVersionColumns + " from photo_versions where photo_id = $1"
+	if keyset.HasCursor {
+		args = append(args, keyset.AfterCreatedAt, keyset.AfterId)
+		query += " and (created_at, id) < ($2, $3)"
+	}
+
+	args = append(args, keyset.Limit)
+	query += " order by created_at desc, id desc limit $" + strconv.Itoa(len(args))
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("FindPhotoVersionsPageRepository : %w", err)
+	}
+
+	defer rows.Close()
+	versions := make([]entity.PhotoVersion, 0)
+	for rows.Next() {
+		version, err := p.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("FindPhotoVersionsPageRepository : %w", err)
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// FindSurplus returns the versions of a photo beyond the keep newest ones.
+func (p *photoVersionRepositoryImpl) FindSurplus(photoId string, keep int) ([]entity.PhotoVersion, error) {
+	query := "select " + photoVersionColumns + " from photo_versions where photo_id = $1 order by created_at desc, id desc offset $2"
+
+	rows, err := p.db.Query(query, photoId, keep)
+	if err != nil {
+		return nil, fmt.Errorf("FindSurplusPhotoVersionsRepository : %w", err)
+	}
+
+	defer rows.Close()
+	versions := make([]entity.PhotoVersion, 0)
+	for rows.Next() {
+		version, err := p.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("FindSurplusPhotoVersionsRepository : %w", err)
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+// Delete returns sql.ErrNoRows when the version was removed already, only the call that
+// removed the row may release its blob.
+func (p *photoVersionRepositoryImpl) Delete(id string) error {
+	query := "delete from photo_versions where id = $1 returning id"
+
+	err := p.db.QueryRow(query, id).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("DeletePhotoVersionRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (p *photoVersionRepositoryImpl) scan(row rowScanner) (entity.PhotoVersion, error) {
+	var version entity.PhotoVersion
+	err := row.Scan(&version.Id, &version.PhotoId, &version.BlobHash, &version.PhotoUrl, &version.Title, &version.Caption, &version.PerceptualHash,
+		&version.Crop.X, &version.Crop.Y, &version.Crop.Width, &version.Crop.Height, &version.Width, &version.Height, &version.BlurHash, &version.DominantColor, &version.CreatedAt)
+	return version, err
+}
@@ -2,27 +2,76 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/lib/pq"
+	"strconv"
+	"strings"
+	"time"
 	"user-personalize/internal/model/entity"
+	"user-personalize/pkg/util/exception"
 )
 
 type PhotosRepository interface {
+	WithTx(tx *sql.Tx) PhotosRepository
 	Insert(photos entity.Photos) (entity.Photos, error)
 	Update(photos entity.Photos) (entity.Photos, error)
 	Delete(userId string) error
+	Trash(id string) error
+	Restore(id string) error
 	FindByUserId(userId string) (entity.Photos, error)
+	FindCountedByUserId(userId string) (entity.Photos, error)
+	FindPageByUserId(userId string, viewerId string, keyset entity.Keyset) ([]entity.Photos, error)
 	FindById(id string) (entity.Photos, error)
+	FindAll() ([]entity.Photos, error)
+	FindAllAfter(afterId string, limit int) ([]entity.Photos, error)
+	FindTrashById(id string) (entity.Photos, error)
+	FindTrashByUserId(userId string) ([]entity.Photos, error)
+	FindTrashPage(userId string, keyset entity.Keyset) ([]entity.Photos, error)
+	FindTrashedBefore(cutoff time.Time) ([]entity.Photos, error)
+	UpdateAnalysis(photos entity.Photos) error
+	FindUnanalyzed(afterId string, limit int) ([]entity.Photos, error)
+	MarkAnalysisFailed(id string) error
+	FindSimilar(photo entity.Photos, viewerId string, maxDistance int, limit int) ([]entity.SimilarPhoto, error)
+	Search(search entity.PhotoSearch) ([]entity.PhotoSearchResult, error)
+	FindFeed(userId string, keyset entity.Keyset) ([]entity.Photos, error)
+	UpdateModerationStatus(id string, status string) error
+	FindLegacy(afterId string, limit int) ([]entity.Photos, error)
+	AdoptBlob(id string, legacyUrl string, blob entity.Blob) error
+	RevertBlob(id string, hash string, legacyUrl string) error
 }
 
+// phashBands is the number of 8 bit slices of photos.phash that carry their own index,
+// see DDL.sql. Two hashes within phashBands-1 bits of each other share at least one slice.
+const phashBands = 8
+
+// inactiveOwner is the condition for photos whose owner is suspended or deactivated.
+const inactiveOwner = "exists (select 1 from users su where su.id = photos.user_id and (su.suspended_at is not null or su.deactivated_at is not null))"
+
+const photosColumns = "id, title, caption, photo_url, user_id, visibility, moderation_status, " +
+	inactiveOwner + ", coalesce(blob_hash, ''), phash, crop_x, crop_y, crop_width, crop_height, " +
+	"width, height, coalesce(blurhash, ''), coalesce(dominant_color, ''), created_at, updated_at, " +
+	"coalesce((select array_agg(t.name order by t.name) from photo_tags pt join tags t on t.id = pt.tag_id where pt.photo_id = photos.id), '{}'), deleted_at"
+
+// highlightStart and highlightStop mark matches in search highlights. They are control
+// characters so that they cannot clash with user text, the usecase turns them into markup.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
 type photosRepositoryImpl struct {
-	db *sql.DB
+	db DBTX
+}
+
+func (p *photosRepositoryImpl) WithTx(tx *sql.Tx) PhotosRepository {
+	return &photosRepositoryImpl{db: tx}
 }
 
 func (p *photosRepositoryImpl) FindById(id string) (entity.Photos, error) {
-	query := "select id, title, caption, photo_url, user_id from photos where id = $1"
+	query := "select " + photosColumns + " from photos where id = $1 and " + notDeleted
 
-	var result entity.Photos
-	err := p.db.QueryRow(query, id).Scan(&result.Id, &result.Title, &result.Caption, &result.PhotoUrl, &result.UserId)
+	result, err := p.scan(p.db.QueryRow(query, id))
 	if err != nil {
 		return entity.Photos{}, fmt.Errorf("FindByIdRepository : %w", err)
 	}
@@ -31,24 +80,71 @@ func (p *photosRepositoryImpl) FindById(id string) (entity.Photos, error) {
 }
 
 func (p *photosRepositoryImpl) FindByUserId(userId string) (entity.Photos, error) {
-	query := "select id, title, caption, photo_url, user_id from photos where user_id = $1"
+	query := "select " + photosColumns + " from photos where user_id = $1 and " + notDeleted
 
-	var photosEntity entity.Photos
-	err := p.db.QueryRow(query, userId).Scan(&photosEntity.Id, &photosEntity.Title, &photosEntity.Caption, &photosEntity.PhotoUrl, &photosEntity.UserId)
+	photosEntity, err := p.scan(p.db.QueryRow(query, userId))
+	if err != nil {
+		return entity.Photos{}, fmt.Errorf("PhotosFindByUserIdRepository : %w", err)
+	}
+
+	return photosEntity, nil
+}
+
+// FindCountedByUserId returns the photo of a user with its counts as the user sees them.
+func (p *photosRepositoryImpl) FindCountedByUserId(userId string) (entity.Photos, error) {
+	query := "select " + photosColumns + photoCounts("$1") + " from photos where user_id = $1 and " + notDeleted
 
+	photosEntity, err := p.scanCounted(p.db.QueryRow(query, userId))
 	if err != nil {
-		return entity.Photos{}, fmt.Errorf("PhotosFindByUserIdRepository : %v", err)
+		return entity.Photos{}, fmt.Errorf("PhotosFindCountedByUserIdRepository : %w", err)
 	}
 
 	return photosEntity, nil
 }
 
-func (p *photosRepositoryImpl) Insert(photos entity.Photos) (entity.Photos, error) {
-	query := "insert into photos (id, title, caption, photo_url, user_id) values($1, $2, $3, $4, $5) returning id, title, caption, photo_url, user_id"
+// FindPageByUserId returns the photos of a user, newest first, with their counts as viewerId sees them.
+func (p *photosRepositoryImpl) FindPageByUserId(userId string, viewerId string, keyset entity.Keyset) ([]entity.Photos, error) {
+	args := []any{userId, viewerId}
+	query := "select " + photosColumns + photoCounts("$2") + " from photos where user_id = $1 and " + notDeleted
+	if keyset.HasCursor {
+		args = append(args, keyset.AfterCreatedAt, keyset.AfterId)
+		query += " and (created_at, id) < ($3, $4)"
+	}
 
-	var photosEntity entity.Photos
-	err := p.db.QueryRow(query, photos.Id, photos.Title, photos.Caption, photos.PhotoUrl, photos.UserId).Scan(&photosEntity.Id, &photosEntity.Title, &photosEntity.Caption, &photosEntity.PhotoUrl, &photosEntity.UserId)
+	args = append(args, keyset.Limit)
+	query += " order by created_at desc, id desc limit $" + strconv.Itoa(len(args))
+
+	return p.findCountedPhotos("FindPhotosPageByUserIdRepository", query, args...)
+}
+
+func (p *photosRepositoryImpl) FindAll() ([]entity.Photos, error) {
+	query := "select " + photosColumns + " from photos where " + notDeleted
+
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("PhotosFindAllRepository : %w", err)
+	}
+
+	defer rows.Close()
+	photos := make([]entity.Photos, 0)
+	for rows.Next() {
+		photosEntity, err := p.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("PhotosFindAllRepository : %w", err)
+		}
 
+		photos = append(photos, photosEntity)
+	}
+
+	return photos, rows.Err()
+}
+
+func (p *photosRepositoryImpl) Insert(photos entity.Photos) (entity.Photos, error) {
+	query := "insert into photos (id, title, caption, photo_url, user_id, blob_hash, phash, crop_x, crop_y, crop_width, crop_height, width, height, blurhash, dominant_color, visibility, created_at, updated_at) " +
+		"values($1, $2, $3, $4, $5, nullif($6, ''), $7, $8, $9, $10, $11, $12, $13, nullif($14, ''), nullif($15, ''), $16, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) returning " + photosColumns
+
+	photosEntity, err := p.scan(p.db.QueryRow(query, photos.Id, photos.Title, photos.Caption, photos.PhotoUrl, photos.UserId, photos.BlobHash, photos.PerceptualHash,
+		photos.Crop.X, photos.Crop.Y, photos.Crop.Width, photos.Crop.Height, photos.Width, photos.Height, photos.BlurHash, photos.DominantColor, photos.Visibility))
 	if err != nil {
 		return entity.Photos{}, fmt.Errorf("insertPhotosRepository : %v", err)
 	}
@@ -57,11 +153,12 @@ func (p *photosRepositoryImpl) Insert(photos entity.Photos) (entity.Photos, erro
 }
 
 func (p *photosRepositoryImpl) Update(photos entity.Photos) (entity.Photos, error) {
-	query := "update photos set title = $1, caption = $2, photo_url = $3 where user_id = $4 returning id, title, caption, photo_url, user_id"
-
-	var photosEntity entity.Photos
-	err := p.db.QueryRow(query, photos.Title, photos.Caption, photos.PhotoUrl, photos.UserId).Scan(&photosEntity.Id, &photosEntity.Title, &photosEntity.Caption, &photosEntity.PhotoUrl, &photosEntity.UserId)
+	query := "update photos set title = $1, caption = $2, photo_url = $3, blob_hash = nullif($4, ''), phash = $5, " +
+		"crop_x = $6, crop_y = $7, crop_width = $8, crop_height = $9, width = $10, height = $11, blurhash = nullif($12, ''), dominant_color = nullif($13, ''), " +
+		"analysis_failed_at = null, visibility = $14, updated_at = CURRENT_TIMESTAMP where user_id = $15 and " + notDeleted + " returning " + photosColumns + photoCounts("$15")
 
+	photosEntity, err := p.scanCounted(p.db.QueryRow(query, photos.Title, photos.Caption, photos.PhotoUrl, photos.BlobHash, photos.PerceptualHash,
+		photos.Crop.X, photos.Crop.Y, photos.Crop.Width, photos.Crop.Height, photos.Width, photos.Height, photos.BlurHash, photos.DominantColor, photos.Visibility, photos.UserId))
 	if err != nil {
 		return entity.Photos{}, fmt.Errorf("updatePhotosRepository : %v", err)
 	}
@@ -69,17 +166,408 @@ func (p *photosRepositoryImpl) Update(photos entity.Photos) (entity.Photos, erro
 	return photosEntity, nil
 }
 
+// Delete removes the row for good, see Trash for the deletes users make.
+// Delete returns sql.ErrNoRows when the photo was removed already, only the call that
+// removed the row may release its blob.
 func (p *photosRepositoryImpl) Delete(userId string) error {
 	query := "delete from photos where id = $1"
 
-	_, err := p.db.Exec(query, userId)
+	result, err := p.db.Exec(query, userId)
+	if err != nil {
+		return fmt.Errorf("deletePhotosRepository : %v", err)
+	}
+
+	deleted, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("deletePhotosRepository : %v", err)
 	}
 
+	if deleted == 0 {
+		return fmt.Errorf("deletePhotosRepository : %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// Trash moves the photo to the trash, where every other query but the Trash ones no longer finds it.
+func (p *photosRepositoryImpl) Trash(id string) error {
+	query := "update photos set deleted_at = CURRENT_TIMESTAMP where id = $1 and " + notDeleted
+
+	_, err := p.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("TrashPhotosRepository : %w", err)
+	}
+
 	return nil
 }
 
+// Restore fails with ConflictErr when the user has another photo, photos_user_id_idx
+// decides between restores and uploads that race.
+func (p *photosRepositoryImpl) Restore(id string) error {
+	query := "update photos set deleted_at = null where id = $1 and deleted_at is not null"
+
+	_, err := p.db.Exec(query, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == "photos_user_id_idx" {
+		return fmt.Errorf("RestorePhotosRepository : %w", exception.ConflictErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("RestorePhotosRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (p *photosRepositoryImpl) FindTrashById(id string) (entity.Photos, error) {
+	query := "select " + photosColumns + " from photos where id = $1 and deleted_at is not null"
+
+	result, err := p.scan(p.db.QueryRow(query, id))
+	if err != nil {
+		return entity.Photos{}, fmt.Errorf("FindTrashByIdRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// FindTrashByUserId returns the trash of a user, most recently deleted first.
+func (p *photosRepositoryImpl) FindTrashByUserId(userId string) ([]entity.Photos, error) {
+	query := "select " + photosColumns + " from photos where user_id = $1 and deleted_at is not null order by deleted_at desc, id desc"
+
+	return p.findPhotos("FindTrashByUserIdRepository", query, userId)
+}
+
+// FindTrashPage returns the trash of a user, most recently deleted first. The keyset
+// carries the deleted_at of the last photo in AfterCreatedAt.
+func (p *photosRepositoryImpl) FindTrashPage(userId string, keyset entity.Keyset) ([]entity.Photos, error) {
+	args := []any{userId}
+	query := "select " + photosColumns + photoCounts("$1") + " from photos where user_id = $1 and deleted_at is not null"
+	if keyset.HasCursor {
+		args = append(args, keyset.AfterCreatedAt, keyset.AfterId)
+		query += " and (deleted_at, id) < ($2, $3)"
+	}
+
+	args = append(args, keyset.Limit)
+	query += " order by deleted_at desc, id desc limit $" + strconv.Itoa(len(args))
+
+	return p.findCountedPhotos("FindTrashPageRepository", query, args...)
+}
+
+// FindTrashedBefore returns the photos that were moved to the trash before cutoff, oldest first.
+func (p *photosRepositoryImpl) FindTrashedBefore(cutoff time.Time) ([]entity.Photos, error) {
+	query := "select " + photosColumns + " from photos where deleted_at < $1 order by deleted_at, id"
+
+	return p.findPhotos("FindTrashedBeforeRepository", query, cutoff)
+}
+
+func (p *photosRepositoryImpl) findPhotos(name string, query string, args ...any) ([]entity.Photos, error) {
+	return p.queryPhotos(name, p.scan, query, args...)
+}
+
+// findCountedPhotos is findPhotos for queries that select photoCounts after the photosColumns.
+func (p *photosRepositoryImpl) findCountedPhotos(name string, query string, args ...any) ([]entity.Photos, error) {
+	return p.queryPhotos(name, p.scanCounted, query, args...)
+}
+
+func (p *photosRepositoryImpl) queryPhotos(name string, scan func(row rowScanner, extra ...any) (entity.Photos, error), query string, args ...any) ([]entity.Photos, error) {
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", name, err)
+	}
+
+	defer rows.Close()
+	photos := make([]entity.Photos, 0)
+	for rows.Next() {
+		photosEntity, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", name, err)
+		}
+
+		photos = append(photos, photosEntity)
+	}
+
+	return photos, rows.Err()
+}
+
+// FindAllAfter returns the photos in and out of the trash ordered by id after afterId.
+func (p *photosRepositoryImpl) FindAllAfter(afterId string, limit int) ([]entity.Photos, error) {
+	query := "select " + photosColumns + " from photos where id > $1 order by id limit $2"
+
+	return p.findPhotos("FindAllPhotosAfterRepository", query, afterId, limit)
+}
+
+// FindLegacy returns the photos stored before content addressing, in and out of the trash,
+// ordered by id after afterId.
+func (p *photosRepositoryImpl) FindLegacy(afterId string, limit int) ([]entity.Photos, error) {
+	query := "select " + photosColumns + " from photos where blob_hash is null and id > $1 order by id limit $2"
+
+	rows, err := p.db.Query(query, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("FindLegacyPhotosRepository : %w", err)
+	}
+
+	defer rows.Close()
+	photos := make([]entity.Photos, 0)
+	for rows.Next() {
+		photosEntity, err := p.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("FindLegacyPhotosRepository : %w", err)
+		}
+
+		photos = append(photos, photosEntity)
+	}
+
+	return photos, rows.Err()
+}
+
+// AdoptBlob points a legacy photo at the blob its file was copied to. It returns sql.ErrNoRows
+// when the photo was changed or removed since legacyUrl was read, it leaves updated_at alone.
+func (p *photosRepositoryImpl) AdoptBlob(id string, legacyUrl string, blob entity.Blob) error {
+	query := "update photos set photo_url = $1, blob_hash = $2 where id = $3 and photo_url = $4 and blob_hash is null"
+
+	result, err := p.db.Exec(query, blob.ObjectKey, blob.Hash, id, legacyUrl)
+	if err != nil {
+		return fmt.Errorf("AdoptBlobRepository : %w", err)
+	}
+
+	adopted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("AdoptBlobRepository : %w", err)
+	}
+
+	if adopted == 0 {
+		return fmt.Errorf("AdoptBlobRepository : %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// RevertBlob undoes AdoptBlob, it returns sql.ErrNoRows when the photo no longer points at hash.
+func (p *photosRepositoryImpl) RevertBlob(id string, hash string, legacyUrl string) error {
+	query := "update photos set photo_url = $1, blob_hash = null where id = $2 and blob_hash = $3"
+
+	result, err := p.db.Exec(query, legacyUrl, id, hash)
+	if err != nil {
+		return fmt.Errorf("RevertBlobRepository : %w", err)
+	}
+
+	reverted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("RevertBlobRepository : %w", err)
+	}
+
+	if reverted == 0 {
+		return fmt.Errorf("RevertBlobRepository : %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// FindUnanalyzed returns the photos out of the trash that have no perceptual hash yet and
+// whose analysis did not fail before, ordered by id after afterId.
+func (p *photosRepositoryImpl) FindUnanalyzed(afterId string, limit int) ([]entity.Photos, error) {
+	query := "select " + photosColumns + " from photos where phash is null and analysis_failed_at is null and " + notDeleted + " and id > $1 order by id limit $2"
+
+	return p.findPhotos("FindUnanalyzedPhotosRepository", query, afterId, limit)
+}
+
+// MarkAnalysisFailed keeps the backfill from analyzing a photo again, it leaves updated_at alone.
+func (p *photosRepositoryImpl) MarkAnalysisFailed(id string) error {
+	query := "update photos set analysis_failed_at = CURRENT_TIMESTAMP where id = $1"
+
+	_, err := p.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("MarkAnalysisFailedRepository : %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAnalysis stores the values derived from the pixels of a photo, it leaves updated_at alone.
+func (p *photosRepositoryImpl) UpdateAnalysis(photos entity.Photos) error {
+	query := "update photos set phash = $1, width = $2, height = $3, blurhash = nullif($4, ''), dominant_color = nullif($5, '') where id = $6 and " + notDeleted
+
+	_, err := p.db.Exec(query, photos.PerceptualHash, photos.Width, photos.Height, photos.BlurHash, photos.DominantColor, photos.Id)
+	if err != nil {
+		return fmt.Errorf("UpdateAnalysisRepository : %w", err)
+	}
+
+	return nil
+}
+
+// UpdateModerationStatus also applies to photos in the trash, so that restoring a photo
+// cannot undo a moderator's decision.
+func (p *photosRepositoryImpl) UpdateModerationStatus(id string, status string) error {
+	query := "update photos set moderation_status = $1 where id = $2"
+
+	_, err := p.db.Exec(query, status, id)
+	if err != nil {
+		return fmt.Errorf("UpdateModerationStatusRepository : %w", err)
+	}
+
+	return nil
+}
+
+// FindSimilar returns the photos whose perceptual hash is at most maxDistance bits away
+// from the one of photo and that viewerId may see, closest first. Candidates are looked up
+// by the band indexes, so maxDistance must be below phashBands for the result to be complete.
+func (p *photosRepositoryImpl) FindSimilar(photo entity.Photos, viewerId string, maxDistance int, limit int) ([]entity.SimilarPhoto, error) {
+	bands := make([]string, 0, phashBands)
+	args := []any{photo.Id, photo.PerceptualHash.Int64, maxDistance, limit, viewerId}
+	for band := 0; band < phashBands; band++ {
+		shift := 56 - band*8
+		args = append(args, (uint64(photo.PerceptualHash.Int64)>>shift)&0xff)
+		bands = append(bands, fmt.Sprintf("((phash >> %d) & 255) = $%d", shift, len(args)))
+	}
+
+	query := "select id, user_id, distance from (" +
+		"select id, user_id, bit_count((phash # $2)::bit(64)) as distance from photos " +
+		"where id <> $1 and " + visibleTo("$5") + " and (" + strings.Join(bands, " or ") + ")" +
+		") candidates where distance <= $3 order by distance, id limit $4"
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("FindSimilarRepository : %w", err)
+	}
+
+	defer rows.Close()
+	similar := make([]entity.SimilarPhoto, 0)
+	for rows.Next() {
+		var photo entity.SimilarPhoto
+		err := rows.Scan(&photo.PhotoId, &photo.UserId, &photo.Distance)
+		if err != nil {
+			return nil, fmt.Errorf("FindSimilarRepository : %w", err)
+		}
+
+		similar = append(similar, photo)
+	}
+
+	return similar, rows.Err()
+}
+
+// Search runs the full-text query and the tag filter. With a query results are ordered
+// by rank, without one by creation time, both newest id first on ties.
+func (p *photosRepositoryImpl) Search(search entity.PhotoSearch) ([]entity.PhotoSearchResult, error) {
+	// every parameter must appear in the query, so they are numbered as they are used
+	args := make([]any, 0)
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	viewer := arg(search.ViewerId)
+	conditions := []string{visibleTo(viewer)}
+	for _, tag := range search.Tags {
+		conditions = append(conditions, "exists (select 1 from photo_tags pt join tags t on t.id = pt.tag_id where pt.photo_id = photos.id and t.name = "+arg(tag)+")")
+	}
+
+	rank := "0::real"
+	order := "created_at desc, id desc"
+	highlights := "coalesce(title, ''), coalesce(caption, '')"
+	if search.Query != "" {
+		tsQuery := "websearch_to_tsquery('simple', " + arg(search.Query) + ")"
+		conditions = append(conditions, "search_vector @@ "+tsQuery)
+		rank = "ts_rank(search_vector, " + tsQuery + ")"
+		order = "rank desc, id desc"
+
+		options := arg("StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", HighlightAll=true")
+		highlights = fmt.Sprintf("ts_headline('simple', coalesce(title, ''), %s, %s), ts_headline('simple', coalesce(caption, ''), %s, %s)", tsQuery, options, tsQuery, options)
+	}
+
+	if search.HasCursor && search.Query != "" {
+		conditions = append(conditions, "("+rank+", id) < ("+arg(search.AfterRank)+"::real, "+arg(search.AfterId)+")")
+	} else if search.HasCursor {
+		conditions = append(conditions, "(created_at, id) < ("+arg(search.AfterCreatedAt)+", "+arg(search.AfterId)+")")
+	}
+
+	query := "select " + photosColumns + photoCounts(viewer) + ", " + rank + " as rank, " + highlights +
+		" from photos where " + strings.Join(conditions, " and ") + " order by " + order + " limit " + arg(search.Limit)
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SearchPhotosRepository : %w", err)
+	}
+
+	defer rows.Close()
+	results := make([]entity.PhotoSearchResult, 0)
+	for rows.Next() {
+		var result entity.PhotoSearchResult
+		result.Photo, err = p.scanCounted(rows, &result.Rank, &result.TitleHighlight, &result.CaptionHighlight)
+		if err != nil {
+			return nil, fmt.Errorf("SearchPhotosRepository : %w", err)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// FindFeed returns the photos of the users userId follows that are shared with followers,
+// newest first, leaving out muted and blocked users. The planner picks the side to start
+// from: walking photos_created_at_idx and probing follows_pkey per photo reads past many
+// photos when userId follows few users, while starting from follows_follower_created_at_idx
+// costs one probe of photos_user_id_idx per followed user, as each has one live photo.
+func (p *photosRepositoryImpl) FindFeed(userId string, keyset entity.Keyset) ([]entity.Photos, error) {
+	args := []any{userId}
+	query := "select " + photosColumns + photoCounts("$1") + " from photos where " + notDeleted + " and visibility in ('public', 'followers') and " + moderated +
+		" and exists (select 1 from follows f where f.follower_id = $1 and f.followee_id = photos.user_id) " +
+		"and not exists (select 1 from mutes m where m.muter_id = $1 and m.muted_id = photos.user_id) and " + notBlocked("$1", "photos.user_id")
+	if keyset.HasCursor {
+		args = append(args, keyset.AfterCreatedAt, keyset.AfterId)
+		query += " and (created_at, id) < ($2, $3)"
+	}
+
+	args = append(args, keyset.Limit)
+	query += " order by created_at desc, id desc limit $" + strconv.Itoa(len(args))
+
+	return p.findCountedPhotos("FindFeedRepository", query, args...)
+}
+
+// notDeleted is the condition for photos that are not in the trash.
+const notDeleted = "deleted_at is null"
+
+// moderated is the condition for photos no moderator took down, of users that are neither suspended nor deactivated.
+const moderated = "moderation_status = 'visible' and not " + inactiveOwner
+
+// visibleTo is the condition for the photos the viewer bound to viewerArg may see,
+// an empty viewer is an anonymous caller. It mirrors usecase canView.
+func visibleTo(viewerArg string) string {
+	return "(" + notDeleted + " and (user_id = " + viewerArg + " or (" + moderated + " and (visibility = 'public' or (visibility = 'followers' and " +
+		"exists (select 1 from follows f where f.follower_id = " + viewerArg + " and f.followee_id = photos.user_id))) and " +
+		notBlocked(viewerArg, "photos.user_id") + ")))"
+}
+
+// photoCounts selects the like and comment counts of a photo, to be appended to the photosColumns of
+// queries whose photos are rendered. The comment count follows GetComments: comments of users blocked
+// with the viewer bound to viewerArg are left out together with the replies to them.
+func photoCounts(viewerArg string) string {
+	return ", (select count(*) from photo_likes pl where pl.photo_id = photos.id), " +
+		"(with recursive shown as (select c.id from comments c where c.photo_id = photos.id and c.parent_id is null and " + notBlocked(viewerArg, "c.user_id") +
+		" union all select c.id from comments c join shown s on c.parent_id = s.id where " + notBlocked(viewerArg, "c.user_id") + ") select count(*) from shown)"
+}
+
+// scan reads the photosColumns of a row followed by the extra columns of a query.
+func (p *photosRepositoryImpl) scan(row rowScanner, extra ...any) (entity.Photos, error) {
+	var photosEntity entity.Photos
+	dest := []any{&photosEntity.Id, &photosEntity.Title, &photosEntity.Caption, &photosEntity.PhotoUrl, &photosEntity.UserId, &photosEntity.Visibility,
+		&photosEntity.ModerationStatus, &photosEntity.OwnerInactive, &photosEntity.BlobHash, &photosEntity.PerceptualHash,
+		&photosEntity.Crop.X, &photosEntity.Crop.Y, &photosEntity.Crop.Width, &photosEntity.Crop.Height,
+		&photosEntity.Width, &photosEntity.Height, &photosEntity.BlurHash, &photosEntity.DominantColor, &photosEntity.CreatedAt, &photosEntity.UpdatedAt, pq.Array(&photosEntity.Tags),
+		&photosEntity.DeletedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return photosEntity, err
+}
+
+// scanCounted reads a row that selects photoCounts between the photosColumns and the extra columns.
+func (p *photosRepositoryImpl) scanCounted(row rowScanner, extra ...any) (entity.Photos, error) {
+	var likes, comments int
+	photosEntity, err := p.scan(row, append([]any{&likes, &comments}, extra...)...)
+	photosEntity.LikeCount = likes
+	photosEntity.CommentCount = comments
+	return photosEntity, err
+}
+
 func NewPhotosRepository(db *sql.DB) PhotosRepository {
 	return &photosRepositoryImpl{db: db}
 }
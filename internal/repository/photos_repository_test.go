@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"user-personalize/internal/model/entity"
+)
+
+func TestPhotosFindTrashPagePagesWithoutGapsOrRepeats(t *testing.T) {
+	tx := testTx(t)
+	photos := NewPhotosRepository(nil).WithTx(tx)
+	owner := testUser(t, tx, "trash_"+uuid.NewString()[:8])
+
+	// photos trashed in one transaction share deleted_at, the id decides between them
+	for range 5 {
+		photo, err := photos.Insert(entity.Photos{Id: uuid.NewString(), UserId: owner.Id, Visibility: entity.VisibilityPrivate})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = photos.Trash(photo.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := photos.FindTrashByUserId(owner.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, limit := range []int{1, 2, 5} {
+		paged := pageAll(t, limit, func(keyset entity.Keyset) ([]entity.Photos, entity.Keyset) {
+			page, err := photos.FindTrashPage(owner.Id, keyset)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(page) > 0 {
+				last := page[len(page)-1]
+				keyset = entity.Keyset{Limit: keyset.Limit, HasCursor: true, AfterCreatedAt: last.DeletedAt.Time, AfterId: last.Id}
+			}
+			return page, keyset
+		})
+
+		if len(all) != 5 || len(paged) != len(all) {
+			t.Fatalf("limit %d : got %d photos paged and %d in one query, want 5", limit, len(paged), len(all))
+		}
+
+		for i, photo := range all {
+			if paged[i].Id != photo.Id {
+				t.Fatalf("limit %d : photo %d is %s paged and %s in one query", limit, i, paged[i].Id, photo.Id)
+			}
+		}
+	}
+}
+
+func TestPhotoCountsLeaveOutBlockedCommenters(t *testing.T) {
+	tx := testTx(t)
+	photos := NewPhotosRepository(nil).WithTx(tx)
+	comments := &commentRepositoryImpl{db: tx}
+
+	suffix := uuid.NewString()[:8]
+	owner := testUser(t, tx, "owner_"+suffix)
+	viewer := testUser(t, tx, "viewer_"+suffix)
+	blocked := testUser(t, tx, "blocked_"+suffix)
+	other := testUser(t, tx, "other_"+suffix)
+
+	err := NewBlockRepository(nil).WithTx(tx).Block(viewer.Id, blocked.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	photo, err := photos.Insert(entity.Photos{Id: uuid.NewString(), UserId: owner.Id, Visibility: entity.VisibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a thread of other, replied to by blocked whose reply other answered, and a thread of blocked
+	comment := func(userId string, parentId string) string {
+		inserted, err := comments.Insert(entity.Comment{Id: uuid.NewString(), PhotoId: photo.Id, UserId: userId, ParentId: parentId, Body: "nice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inserted.Id
+	}
+	root := comment(other.Id, "")
+	comment(other.Id, comment(blocked.Id, root))
+	comment(blocked.Id, "")
+
+	tests := []struct {
+		name     string
+		viewerId string
+		want     int
+	}{
+		{name: "owner", viewerId: owner.Id, want: 4},
+		{name: "anonymous", viewerId: "", want: 4},
+		{name: "viewer who blocked a commenter", viewerId: viewer.Id, want: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := photos.FindPageByUserId(owner.Id, test.viewerId, entity.Keyset{Limit: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(page) != 1 || page[0].CommentCount != test.want {
+				t.Fatalf("got %v, want one photo with %d comments", page, test.want)
+			}
+		})
+	}
+}
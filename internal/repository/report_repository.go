@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"user-personalize/internal/model/entity"
+)
+
+type ReportRepository interface {
+	WithTx(tx *sql.Tx) ReportRepository
+	Insert(report entity.Report) (entity.Report, error)
+	FindById(id string) (entity.Report, error)
+	FindByIdForUpdate(id string) (entity.Report, error)
+	FindByStatus(status string, keyset entity.Keyset) ([]entity.Report, error)
+	Claim(id string, moderatorId string) (entity.Report, error)
+	ResolveByPhotoId(photoId string, moderatorId string, resolution string) error
+}
+
+const reportColumns = "id, photo_id, photo_owner_id, coalesce(reporter_id, ''), reason, details, status, coalesce(claimed_by, ''), claimed_at, " +
+	"coalesce(resolved_by, ''), resolved_at, coalesce(resolution, ''), created_at"
+
+type reportRepositoryImpl struct {
+	db DBTX
+}
+
+func NewReportRepository(db *sql.DB) ReportRepository {
+	return &reportRepositoryImpl{db: db}
+}
+
+func (r *reportRepositoryImpl) WithTx(tx *sql.Tx) ReportRepository {
+	return &reportRepositoryImpl{db: tx}
+}
+
+// Insert returns sql.ErrNoRows when the reporter has an unresolved report on the photo already.
+func (r *reportRepositoryImpl) Insert(report entity.Report) (entity.Report, error) {
+	query := "insert into reports (id, photo_id, photo_owner_id, reporter_id, reason, details, status, created_at) " +
+		"values ($1, $2, $3, nullif($4, ''), $5, $6, 'open', CURRENT_TIMESTAMP) " +
+		"on conflict (photo_id, reporter_id) where status <> 'resolved' do nothing returning " + reportColumns
+
+	result, err := r.scan(r.db.QueryRow(query, report.Id, report.PhotoId, report.PhotoOwnerId, report.ReporterId, report.Reason, report.Details))
+	if err != nil {
+		return entity.Report{}, fmt.Errorf("InsertReportRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *reportRepositoryImpl) FindById(id string) (entity.Report, error) {
+	query := "select " + reportColumns + " from reports where id = $1"
+
+	result, err := r.scan(r.db.QueryRow(query, id))
+	if err != nil {
+		return entity.Report{}, fmt.Errorf("FindReportByIdRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// FindByIdForUpdate locks the report until the surrounding transaction ends, so a claim
+// cannot change hands while the decision on it is written.
+func (r *reportRepositoryImpl) FindByIdForUpdate(id string) (entity.Report, error) {
+	query := "select " + reportColumns + " from reports where id = $1 for update"
+
+	result, err := r.scan(r.db.QueryRow(query, id))
+	if err != nil {
+		return entity.Report{}, fmt.Errorf("FindReportByIdForUpdateRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// FindByStatus returns the reports with status oldest first, the order the queue is worked in.
+func (r *reportRepositoryImpl) FindByStatus(status string, keyset entity.Keyset) ([]entity.Report, error) {
+	args := []any{status}
+	query := "select " + reportColumns + " from reports where status = $1"
+	if keyset.HasCursor {
+		args = append(args, keyset.AfterCreatedAt, keyset.AfterId)
+		query += " and (created_at, id) > ($2, $3)"
+	}
+
+	args = append(args, keyset.Limit)
+	query += " order by created_at, id limit $" + strconv.Itoa(len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("FindReportsByStatusRepository : %w", err)
+	}
+
+	defer rows.Close()
+	reports := make([]entity.Report, 0)
+	for rows.Next() {
+		report, err := r.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("FindReportsByStatusRepository : %w", err)
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
+
+// Claim assigns an open report to moderatorId. It returns sql.ErrNoRows when the report
+// does not exist or is not open anymore, so two moderators never work the same report.
+func (r *reportRepositoryImpl) Claim(id string, moderatorId string) (entity.Report, error) {
+	query := "update reports set status = 'claimed', claimed_by = $1, claimed_at = CURRENT_TIMESTAMP where id = $2 and status = 'open' returning " + reportColumns
+
+	result, err := r.scan(r.db.QueryRow(query, moderatorId, id))
+	if err != nil {
+		return entity.Report{}, fmt.Errorf("ClaimReportRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// ResolveByPhotoId resolves every unresolved report on the photo, a decision about
+// the photo answers all of them.
+func (r *reportRepositoryImpl) ResolveByPhotoId(photoId string, moderatorId string, resolution string) error {
+	query := "update reports set status = 'resolved', resolved_by = $1, resolved_at = CURRENT_TIMESTAMP, resolution = $2 where photo_id = $3 and status <> 'resolved'"
+
+	_, err := r.db.Exec(query, moderatorId, resolution, photoId)
+	if err != nil {
+		return fmt.Errorf("ResolveReportsRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (r *reportRepositoryImpl) scan(row rowScanner) (entity.Report, error) {
+	var report entity.Report
+	err := row.Scan(&report.Id, &report.PhotoId, &report.PhotoOwnerId, &report.ReporterId, &report.Reason, &report.Details, &report.Status,
+		&report.ClaimedBy, &report.ClaimedAt, &report.ResolvedBy, &report.ResolvedAt, &report.Resolution, &report.CreatedAt)
+	return report, err
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"os"
+	"testing"
+	"user-personalize/internal/model/entity"
+)
+
+// testDB opens the database named by REPOSITORY_TEST_DSN, which must hold the schema of
+// database/DDL.sql. Tests without the variable are skipped.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("REPOSITORY_TEST_DSN")
+	if dsn == "" {
+		t.Skip("REPOSITORY_TEST_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// testTx opens a transaction on the test database, it is rolled back when the test ends.
+func testTx(t *testing.T) *sql.Tx {
+	t.Helper()
+
+	tx, err := testDB(t).Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+
+	return tx
+}
+
+// testUser creates a user named username in tx.
+func testUser(t *testing.T, tx *sql.Tx, username string) entity.User {
+	t.Helper()
+
+	id := uuid.NewString()
+	user, err := NewUserRepository(nil).WithTx(tx).Create(entity.User{Id: id, Username: username, Email: id + "@example.com", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+// pageAll reads every page of a keyset query of limit rows, find returns a page and the
+// keyset after its last row.
+func pageAll[T any](t *testing.T, limit int, find func(keyset entity.Keyset) ([]T, entity.Keyset)) []T {
+	t.Helper()
+
+	all := make([]T, 0)
+	keyset := entity.Keyset{Limit: limit}
+	for {
+		page, next := find(keyset)
+		all = append(all, page...)
+		if len(page) < limit {
+			return all
+		}
+		keyset = next
+	}
+}
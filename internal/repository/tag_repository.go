@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type TagRepository interface {
+	WithTx(tx *sql.Tx) TagRepository
+	ReplacePhotoTags(photoId string, names []string) error
+}
+
+type tagRepositoryImpl struct {
+	db DBTX
+}
+
+func NewTagRepository(db *sql.DB) TagRepository {
+	return &tagRepositoryImpl{db: db}
+}
+
+func (t *tagRepositoryImpl) WithTx(tx *sql.Tx) TagRepository {
+	return &tagRepositoryImpl{db: tx}
+}
+
+// ReplacePhotoTags makes names the tags of the photo, tags that do not exist yet are created.
+func (t *tagRepositoryImpl) ReplacePhotoTags(photoId string, names []string) error {
+	_, err := t.db.Exec("delete from photo_tags where photo_id = $1", photoId)
+	if err != nil {
+		return fmt.Errorf("ReplacePhotoTagsRepository : %w", err)
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	for _, name := range names {
+		_, err = t.db.Exec("insert into tags (id, name) values ($1, $2) on conflict (name) do nothing", uuid.NewString(), name)
+		if err != nil {
+			return fmt.Errorf("ReplacePhotoTagsRepository : %w", err)
+		}
+	}
+
+	query := "insert into photo_tags (photo_id, tag_id) select $1, id from tags where name = any($2)"
+	_, err = t.db.Exec(query, photoId, pq.Array(names))
+	if err != nil {
+		return fmt.Errorf("ReplacePhotoTagsRepository : %w", err)
+	}
+
+	return nil
+}
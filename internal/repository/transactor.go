@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx, so repositories can run inside a transaction.
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+type Transactor interface {
+	WithinTransaction(fn func(tx *sql.Tx) error) error
+}
+
+type transactorImpl struct {
+	db *sql.DB
+}
+
+func NewTransactor(db *sql.DB) Transactor {
+	return &transactorImpl{db: db}
+}
+
+// WithinTransaction commits when fn returns nil and rolls back otherwise.
+func (t *transactorImpl) WithinTransaction(fn func(tx *sql.Tx) error) error {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return fmt.Errorf("BeginTransaction : %w", err)
+	}
+
+	err = fn(tx)
+	if err != nil {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil {
+			return fmt.Errorf("RollbackTransaction : %v : %w", rollbackErr, err)
+		}
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("CommitTransaction : %w", err)
+	}
+
+	return nil
+}
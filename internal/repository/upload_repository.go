@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"user-personalize/internal/model/entity"
+)
+
+type UploadRepository interface {
+	WithTx(tx *sql.Tx) UploadRepository
+	Insert(upload entity.Upload) (entity.Upload, error)
+	FindById(id string) (entity.Upload, error)
+	FindByIdForUpdate(id string) (entity.Upload, error)
+	UpdateOffset(id string, offset int64, expiresAt time.Time) (entity.Upload, error)
+	UpdatePhotoId(id string, photoId string) error
+	Delete(id string) error
+	FindExpired(now time.Time) ([]entity.Upload, error)
+	FindByUserId(userId string) ([]entity.Upload, error)
+}
+
+const uploadColumns = "id, user_id, upload_length, upload_offset, metadata, coalesce(photo_id, ''), expires_at, created_at"
+
+type uploadRepositoryImpl struct {
+	db DBTX
+}
+
+func NewUploadRepository(db *sql.DB) UploadRepository {
+	return &uploadRepositoryImpl{db: db}
+}
+
+func (u *uploadRepositoryImpl) WithTx(tx *sql.Tx) UploadRepository {
+	return &uploadRepositoryImpl{db: tx}
+}
+
+func (u *uploadRepositoryImpl) Insert(upload entity.Upload) (entity.Upload, error) {
+	query := "insert into uploads (id, user_id, upload_length, upload_offset, metadata, expires_at, created_at) values ($1, $2, $3, 0, $4, $5, CURRENT_TIMESTAMP) returning " + uploadColumns
+
+	result, err := u.scan(u.db.QueryRow(query, upload.Id, upload.UserId, upload.Length, upload.Metadata, upload.ExpiresAt))
+	if err != nil {
+		return entity.Upload{}, fmt.Errorf("InsertUploadRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (u *uploadRepositoryImpl) FindById(id string) (entity.Upload, error) {
+	query := "select " + uploadColumns + " from uploads where id = $1"
+
+	result, err := u.scan(u.db.QueryRow(query, id))
+	if err != nil {
+		return entity.Upload{}, fmt.Errorf("FindUploadByIdRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// FindByIdForUpdate locks the row until the surrounding transaction ends, so
+// concurrent PATCH requests for one upload are applied one after the other.
+func (u *uploadRepositoryImpl) FindByIdForUpdate(id string) (entity.Upload, error) {
+	query := "select " + uploadColumns + " from uploads where id = $1 for update"
+
+	result, err := u.scan(u.db.QueryRow(query, id))
+	if err != nil {
+		return entity.Upload{}, fmt.Errorf("FindUploadByIdForUpdateRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (u *uploadRepositoryImpl) UpdateOffset(id string, offset int64, expiresAt time.Time) (entity.Upload, error) {
+	query := "update uploads set upload_offset = $1, expires_at = $2 where id = $3 returning " + uploadColumns
+
+	result, err := u.scan(u.db.QueryRow(query, offset, expiresAt, id))
+	if err != nil {
+		return entity.Upload{}, fmt.Errorf("UpdateUploadOffsetRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (u *uploadRepositoryImpl) UpdatePhotoId(id string, photoId string) error {
+	query := "update uploads set photo_id = $1 where id = $2"
+
+	_, err := u.db.Exec(query, photoId, id)
+	if err != nil {
+		return fmt.Errorf("UpdateUploadPhotoIdRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (u *uploadRepositoryImpl) Delete(id string) error {
+	query := "delete from uploads where id = $1"
+
+	_, err := u.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("DeleteUploadRepository : %w", err)
+	}
+
+	return nil
+}
+
+func (u *uploadRepositoryImpl) FindExpired(now time.Time) ([]entity.Upload, error) {
+	query := "select " + uploadColumns + " from uploads where expires_at < $1"
+
+	uploads, err := u.findAll(query, now)
+	if err != nil {
+		return nil, fmt.Errorf("FindExpiredUploadRepository : %w", err)
+	}
+
+	return uploads, nil
+}
+
+func (u *uploadRepositoryImpl) FindByUserId(userId string) ([]entity.Upload, error) {
+	query := "select " + uploadColumns + " from uploads where user_id = $1"
+
+	uploads, err := u.findAll(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("FindUploadsByUserIdRepository : %w", err)
+	}
+
+	return uploads, nil
+}
+
+func (u *uploadRepositoryImpl) findAll(query string, args ...any) ([]entity.Upload, error) {
+	rows, err := u.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	uploads := make([]entity.Upload, 0)
+	for rows.Next() {
+		upload, err := u.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, rows.Err()
+}
+
+func (u *uploadRepositoryImpl) scan(row rowScanner) (entity.Upload, error) {
+	var upload entity.Upload
+	err := row.Scan(&upload.Id, &upload.UserId, &upload.Length, &upload.Offset, &upload.Metadata, &upload.PhotoId, &upload.ExpiresAt, &upload.CreatedAt)
+	return upload, err
+}
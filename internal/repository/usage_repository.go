@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"user-personalize/internal/model/entity"
+)
+
+type UsageRepository interface {
+	WithTx(tx *sql.Tx) UsageRepository
+	Add(userId string, bytes int64, photos int) (entity.Usage, error)
+	FindByUserId(userId string) (entity.Usage, error)
+	Recalculate(legacyBytes map[string]int64) (int64, error)
+}
+
+const usageColumns = "user_id, bytes, photo_count, updated_at"
+
+type usageRepositoryImpl struct {
+	db DBTX
+}
+
+func NewUsageRepository(db *sql.DB) UsageRepository {
+	return &usageRepositoryImpl{db: db}
+}
+
+func (u *usageRepositoryImpl) WithTx(tx *sql.Tx) UsageRepository {
+	return &usageRepositoryImpl{db: tx}
+}
+
+// Add changes the counters of a user by the given amounts and returns the new totals.
+// The row stays locked until the transaction ends, so concurrent uploads are checked one after the other.
+// Counters never go below zero, Underflow reports a refund that would have taken them there.
+func (u *usageRepositoryImpl) Add(userId string, bytes int64, photos int) (entity.Usage, error) {
+	query := "with previous as (select bytes, photo_count from user_usage where user_id = $1 for update) " +
+		"insert into user_usage (user_id, bytes, photo_count, updated_at) values ($1, greatest($2::bigint, 0), greatest($3::int, 0), CURRENT_TIMESTAMP) " +
+		"on conflict (user_id) do update set bytes = greatest(user_usage.bytes + $2, 0), photo_count = greatest(user_usage.photo_count + $3, 0), " +
+		"updated_at = CURRENT_TIMESTAMP returning " + usageColumns + ", " +
+		"coalesce((select bytes from previous), 0) + $2 < 0 or coalesce((select photo_count from previous), 0) + $3 < 0"
+
+	var result entity.Usage
+	err := u.db.QueryRow(query, userId, bytes, photos).Scan(&result.UserId, &result.Bytes, &result.PhotoCount, &result.UpdatedAt, &result.Underflow)
+	if err != nil {
+		return entity.Usage{}, fmt.Errorf("AddUsageRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+func (u *usageRepositoryImpl) FindByUserId(userId string) (entity.Usage, error) {
+	query := "select " + usageColumns + " from user_usage where user_id = $1"
+
+	result, err := u.scan(u.db.QueryRow(query, userId))
+	if err != nil {
+		return entity.Usage{}, fmt.Errorf("FindUsageByUserIdRepository : %w", err)
+	}
+
+	return result, nil
+}
+
+// Recalculate rebuilds the counters of every user from the photos, versions and blobs
+// tables in one statement and returns the number of users. Files without a blob row
+// count zero bytes, legacyBytes adds the size of those only storage knows, by user id.
+func (u *usageRepositoryImpl) Recalculate(legacyBytes map[string]int64) (int64, error) {
+	userIds := make([]string, 0, len(legacyBytes))
+	sizes := make([]int64, 0, len(legacyBytes))
+	for userId, size := range legacyBytes {
+		userIds = append(userIds, userId)
+		sizes = append(sizes, size)
+	}
+
+	query := "insert into user_usage (user_id, bytes, photo_count, updated_at) " +
+		"select u.id, coalesce(f.bytes, 0) + coalesce(l.bytes, 0), coalesce(f.files, 0), CURRENT_TIMESTAMP from users u " +
+		"left join (select f.user_id, sum(b.size) as bytes, count(*) as files from (" +
+		"select user_id, blob_hash from photos union all " +
+		"select p.user_id, v.blob_hash from photo_versions v join photos p on p.id = v.photo_id" +
+		") f left join blobs b on b.hash = f.blob_hash group by f.user_id) f on f.user_id = u.id " +
+		"left join unnest($1::varchar[], $2::bigint[]) as l(user_id, bytes) on l.user_id = u.id " +
+		"on conflict (user_id) do update set bytes = excluded.bytes, photo_count = excluded.photo_count, updated_at = excluded.updated_at"
+
+	result, err := u.db.Exec(query, pq.Array(userIds), pq.Array(sizes))
+	if err != nil {
+		return 0, fmt.Errorf("RecalculateUsageRepository : %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (u *usageRepositoryImpl) scan(row rowScanner) (entity.Usage, error) {
+	var usage entity.Usage
+	err := row.Scan(&usage.UserId, &usage.Bytes, &usage.PhotoCount, &usage.UpdatedAt)
+	return usage, err
+}
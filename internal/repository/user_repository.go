@@ -3,32 +3,49 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 	"user-personalize/internal/model/entity"
 )
 
 type UserRepository interface {
+	WithTx(tx *sql.Tx) UserRepository
 	Create(user entity.User) (entity.User, error)
 	Update(user entity.User) (entity.User, error)
 	Delete(id string) error
-	GetAll() ([]entity.User, error)
+	GetAll(viewerId string, filter entity.UserFilter, keyset entity.Keyset) ([]entity.User, error)
 	GetById(id string) (entity.User, error)
+	Search(search entity.UserSearch) ([]entity.UserSearchResult, error)
 	UpdatePassword(id string, newPassword string) (entity.User, error)
 	GetByEmail(email string) (entity.User, error)
+	Suspend(id string) error
+	Lock(id string) error
+	Deactivate(id string, receiptId string) (entity.User, error)
+	Reactivate(id string, cutoff time.Time) error
+	FindDeactivatedBefore(cutoff time.Time) ([]entity.User, error)
 }
 
+// likeEscaper escapes the wildcards of a LIKE pattern, backslash is the default escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type userRepositoryImpl struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepositoryImpl{db: db}
 }
 
+func (u *userRepositoryImpl) WithTx(tx *sql.Tx) UserRepository {
+	return &userRepositoryImpl{db: tx}
+}
+
 func (u *userRepositoryImpl) GetByEmail(email string) (entity.User, error) {
-	query := "select id, username, email, password, created_at, updated_at from users where email = $1"
+	query := "select id, username, display_name, email, password, role, suspended_at, deactivated_at, created_at, updated_at from users where email = $1"
 
 	var user entity.User
-	err := u.db.QueryRow(query, email).Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt)
+	err := u.db.QueryRow(query, email).Scan(&user.Id, &user.Username, &user.DisplayName, &user.Email, &user.Password, &user.Role, &user.SuspendedAt, &user.DeactivatedAt, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		return entity.User{}, fmt.Errorf("GetUserByEmailRepository: %w", err)
 	}
@@ -37,10 +54,10 @@ func (u *userRepositoryImpl) GetByEmail(email string) (entity.User, error) {
 }
 
 func (u *userRepositoryImpl) Create(user entity.User) (entity.User, error) {
-	query := "insert into users (id, username, email, password, created_at, updated_at) values ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) returning id, username, email, created_at, updated_at"
+	query := "insert into users (id, username, display_name, email, password, created_at, updated_at) values ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) returning id, username, display_name, email, created_at, updated_at"
 
 	var result entity.User
-	err := u.db.QueryRow(query, user.Id, user.Username, user.Email, user.Password).Scan(&result.Id, &result.Username, &result.Email, &result.CreatedAt, &result.UpdatedAt)
+	err := u.db.QueryRow(query, user.Id, user.Username, user.DisplayName, user.Email, user.Password).Scan(&result.Id, &result.Username, &result.DisplayName, &result.Email, &result.CreatedAt, &result.UpdatedAt)
 
 	if err != nil {
 		return result, fmt.Errorf("CreateRepository: %w", err)
@@ -50,10 +67,10 @@ func (u *userRepositoryImpl) Create(user entity.User) (entity.User, error) {
 }
 
 func (u *userRepositoryImpl) Update(user entity.User) (entity.User, error) {
-	query := "update users set username = $1, email = $2, updated_at = CURRENT_TIMESTAMP where id = $3 returning id, username, email, created_at, updated_at"
+	query := "update users set username = $1, display_name = $2, email = $3, updated_at = CURRENT_TIMESTAMP where id = $4 returning id, username, display_name, email, created_at, updated_at"
 
 	var result entity.User
-	err := u.db.QueryRow(query, user.Username, user.Email, user.Id).Scan(&result.Id, &result.Username, &result.Email, &result.CreatedAt, &result.UpdatedAt)
+	err := u.db.QueryRow(query, user.Username, user.DisplayName, user.Email, user.Id).Scan(&result.Id, &result.Username, &result.DisplayName, &result.Email, &result.CreatedAt, &result.UpdatedAt)
 
 	if err != nil {
 		return entity.User{}, fmt.Errorf("UpdateRepository: %w", err)
@@ -71,9 +88,51 @@ func (u *userRepositoryImpl) Delete(id string) error {
 	return nil
 }
 
-func (u *userRepositoryImpl) GetAll() ([]entity.User, error) {
-	query := "select id, username, email, created_at, updated_at from users"
-	rows, err := u.db.Query(query)
+// GetAll leaves out suspended and deactivated users and the users that block or are blocked
+// by viewerId.
+// Users are ordered by filter.SortBy then id, both in the same direction.
+func (u *userRepositoryImpl) GetAll(viewerId string, filter entity.UserFilter, keyset entity.Keyset) ([]entity.User, error) {
+	// every parameter must appear in the query, so they are numbered as they are used
+	args := make([]any, 0)
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"suspended_at is null", "deactivated_at is null", notBlocked(arg(viewerId), "users.id")}
+	if filter.EmailDomain != "" {
+		conditions = append(conditions, "lower(split_part(email, '@', 2)) = lower("+arg(filter.EmailDomain)+")")
+	}
+
+	if filter.UsernamePrefix != "" {
+		conditions = append(conditions, "lower(username) like lower("+arg(likeEscaper.Replace(filter.UsernamePrefix)+"%")+")")
+	}
+
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at > "+arg(filter.CreatedAfter))
+	}
+
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(filter.CreatedBefore))
+	}
+
+	column, after := "created_at", any(keyset.AfterCreatedAt)
+	if filter.SortBy == entity.UserSortUsername {
+		column, after = "username", keyset.AfterKey
+	}
+
+	direction, comparison := "asc", ">"
+	if filter.Descending {
+		direction, comparison = "desc", "<"
+	}
+
+	if keyset.HasCursor {
+		conditions = append(conditions, "("+column+", id) "+comparison+" ("+arg(after)+", "+arg(keyset.AfterId)+")")
+	}
+
+	query := "select id, username, display_name, email, created_at, updated_at from users where " + strings.Join(conditions, " and ") +
+		" order by " + column + " " + direction + ", id " + direction + " limit " + arg(keyset.Limit)
+	rows, err := u.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("GetAllRepository: %w", err)
 	}
@@ -83,7 +142,7 @@ func (u *userRepositoryImpl) GetAll() ([]entity.User, error) {
 	for rows.Next() {
 		var user entity.User
 
-		err := rows.Scan(&user.Id, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+		err := rows.Scan(&user.Id, &user.Username, &user.DisplayName, &user.Email, &user.CreatedAt, &user.UpdatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("GetAllRepository: %w", err)
 		}
@@ -91,14 +150,67 @@ func (u *userRepositoryImpl) GetAll() ([]entity.User, error) {
 		users = append(users, user)
 	}
 
-	return users, nil
+	return users, rows.Err()
+}
+
+// Search ranks users by the trigram word similarity of the query to their username or
+// display name, whichever is closer, best match first and newest id first on ties. Only
+// users reaching search.Threshold match: it becomes pg_trgm.word_similarity_threshold for
+// the running transaction, so Search has to run inside one, and the <% operator compares
+// against it while using the trigram indexes. Suspended and deactivated users are left out along with the users
+// that block or are blocked by the viewer.
+func (u *userRepositoryImpl) Search(search entity.UserSearch) ([]entity.UserSearchResult, error) {
+	// <% compares against this setting, which is only changed for the running transaction
+	_, err := u.db.Exec("select set_config('pg_trgm.word_similarity_threshold', $1, true)", strconv.FormatFloat(float64(search.Threshold), 'f', -1, 32))
+	if err != nil {
+		return nil, fmt.Errorf("SearchUsersRepository : %w", err)
+	}
+
+	args := make([]any, 0)
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	query := arg(search.Query)
+	rank := "greatest(word_similarity(" + query + ", username), word_similarity(" + query + ", display_name))"
+	conditions := []string{
+		"(" + query + " <% username or " + query + " <% display_name)",
+		"suspended_at is null and deactivated_at is null",
+		notBlocked(arg(search.ViewerId), "users.id"),
+	}
+
+	if search.HasCursor {
+		conditions = append(conditions, "("+rank+", id) < ("+arg(search.AfterRank)+"::real, "+arg(search.AfterId)+")")
+	}
+
+	statement := "select id, username, display_name, " + rank + " as rank from users where " + strings.Join(conditions, " and ") +
+		" order by rank desc, id desc limit " + arg(search.Limit)
+	rows, err := u.db.Query(statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SearchUsersRepository : %w", err)
+	}
+
+	defer rows.Close()
+	results := make([]entity.UserSearchResult, 0)
+	for rows.Next() {
+		var result entity.UserSearchResult
+		err := rows.Scan(&result.User.Id, &result.User.Username, &result.User.DisplayName, &result.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("SearchUsersRepository : %w", err)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
 }
 
 func (u *userRepositoryImpl) GetById(id string) (entity.User, error) {
-	query := "select id, username, email, created_at, updated_at from users where id = $1"
+	query := "select id, username, display_name, email, role, suspended_at, deactivated_at, created_at, updated_at from users where id = $1"
 
 	var user entity.User
-	err := u.db.QueryRow(query, id).Scan(&user.Id, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err := u.db.QueryRow(query, id).Scan(&user.Id, &user.Username, &user.DisplayName, &user.Email, &user.Role, &user.SuspendedAt, &user.DeactivatedAt, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return entity.User{}, fmt.Errorf("GetByIdRepository: %w", err)
@@ -108,11 +220,11 @@ func (u *userRepositoryImpl) GetById(id string) (entity.User, error) {
 }
 
 func (u *userRepositoryImpl) UpdatePassword(id string, newPassword string) (entity.User, error) {
-	query := "update users set password = $1 where id = $2 returning id, username, email, created_at, updated_at"
+	query := "update users set password = $1 where id = $2 returning id, username, display_name, email, created_at, updated_at"
 
 	var result entity.User
 
-	err := u.db.QueryRow(query, newPassword, id).Scan(&result.Id, &result.Username, &result.Email, &result.CreatedAt, &result.UpdatedAt)
+	err := u.db.QueryRow(query, newPassword, id).Scan(&result.Id, &result.Username, &result.DisplayName, &result.Email, &result.CreatedAt, &result.UpdatedAt)
 
 	if err != nil {
 		return entity.User{}, fmt.Errorf("UpdatePasswordRepository: %w", err)
@@ -120,3 +232,91 @@ func (u *userRepositoryImpl) UpdatePassword(id string, newPassword string) (enti
 
 	return result, nil
 }
+
+// Suspend keeps the time of the first suspension when the user is suspended again.
+func (u *userRepositoryImpl) Suspend(id string) error {
+	query := "update users set suspended_at = coalesce(suspended_at, CURRENT_TIMESTAMP) where id = $1"
+
+	_, err := u.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("SuspendRepository: %w", err)
+	}
+
+	return nil
+}
+
+// Lock holds the user row until the surrounding transaction ends. Rows referencing the
+// user cannot be inserted meanwhile, it returns sql.ErrNoRows when the user does not exist.
+func (u *userRepositoryImpl) Lock(id string) error {
+	query := "select id from users where id = $1 for update"
+
+	err := u.db.QueryRow(query, id).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("LockUserRepository: %w", err)
+	}
+
+	return nil
+}
+
+// Deactivate keeps the time of the first deactivation and its receipt id when the user
+// deletes the account again.
+func (u *userRepositoryImpl) Deactivate(id string, receiptId string) (entity.User, error) {
+	query := "update users set deactivated_at = coalesce(deactivated_at, CURRENT_TIMESTAMP), deletion_receipt_id = coalesce(deletion_receipt_id, $2) " +
+		"where id = $1 returning id, username, email, deactivated_at, deletion_receipt_id, created_at, updated_at"
+
+	var result entity.User
+	err := u.db.QueryRow(query, id, receiptId).Scan(&result.Id, &result.Username, &result.Email, &result.DeactivatedAt, &result.DeletionReceiptId, &result.CreatedAt, &result.UpdatedAt)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("DeactivateRepository: %w", err)
+	}
+
+	return result, nil
+}
+
+// Reactivate clears the deactivation of a user deactivated after cutoff and not suspended,
+// it returns sql.ErrNoRows otherwise. The check and the update are one statement, so it
+// cannot take back an account the purge has started erasing.
+func (u *userRepositoryImpl) Reactivate(id string, cutoff time.Time) error {
+	query := "update users set deactivated_at = null, deletion_receipt_id = null where id = $1 and deactivated_at > $2 and suspended_at is null"
+
+	result, err := u.db.Exec(query, id, cutoff)
+	if err != nil {
+		return fmt.Errorf("ReactivateRepository: %w", err)
+	}
+
+	reactivated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ReactivateRepository: %w", err)
+	}
+
+	if reactivated == 0 {
+		return fmt.Errorf("ReactivateRepository: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// FindDeactivatedBefore returns the users deactivated before cutoff, oldest first.
+func (u *userRepositoryImpl) FindDeactivatedBefore(cutoff time.Time) ([]entity.User, error) {
+	query := "select id, username, email, deactivated_at, coalesce(deletion_receipt_id, ''), created_at, updated_at from users where deactivated_at < $1 order by deactivated_at"
+
+	rows, err := u.db.Query(query, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("FindDeactivatedBeforeRepository: %w", err)
+	}
+
+	defer rows.Close()
+	users := make([]entity.User, 0)
+	for rows.Next() {
+		var user entity.User
+
+		err := rows.Scan(&user.Id, &user.Username, &user.Email, &user.DeactivatedAt, &user.DeletionReceiptId, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("FindDeactivatedBeforeRepository: %w", err)
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
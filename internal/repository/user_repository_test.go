@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+	"user-personalize/internal/model/entity"
+)
+
+func TestUserSearchMatchesMisspellings(t *testing.T) {
+	tx := testTx(t)
+	users := NewUserRepository(nil).WithTx(tx)
+
+	for _, user := range []entity.User{
+		{Username: "jonathan_doe", DisplayName: "Jonathan Doe"},
+		{Username: "margaret88", DisplayName: "Margaret Hamilton"},
+		{Username: "zed", DisplayName: ""},
+	} {
+		user.Id = uuid.NewString()
+		user.Email = user.Id + "@example.com"
+		user.Password = "secret"
+		_, err := users.Create(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "jonahtan", want: "jonathan_doe"},
+		{query: "margret", want: "margaret88"},
+		{query: "hamliton", want: "margaret88"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			results, err := users.Search(entity.UserSearch{Query: test.query, Threshold: 0.3, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(results) == 0 || results[0].User.Username != test.want {
+				t.Fatalf("search %q : got %v, want %s first", test.query, results, test.want)
+			}
+		})
+	}
+}
+
+func TestLikeEscaper(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "alice", want: "alice"},
+		{value: "al_ce", want: `al\_ce`},
+		{value: "100%", want: `100\%`},
+		{value: `back\slash`, want: `back\\slash`},
+		{value: `\_%`, want: `\\\_\%`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got := likeEscaper.Replace(test.value)
+			if got != test.want {
+				t.Fatalf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUserGetAllPagesWithoutGapsOrRepeats(t *testing.T) {
+	tx := testTx(t)
+	users := NewUserRepository(nil).WithTx(tx)
+
+	// users created in one transaction share created_at, the id decides between them
+	prefix := "page_" + uuid.NewString()[:8] + "_"
+	want := make(map[string]bool)
+	for _, name := range []string{"d", "a", "c", "e", "b"} {
+		want[testUser(t, tx, prefix+name).Id] = true
+	}
+	// a match for the prefix if its underscores were wildcards
+	testUser(t, tx, strings.ReplaceAll(prefix, "_", "x")+"f")
+
+	err := users.Suspend(testUser(t, tx, prefix+"g").Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter entity.UserFilter
+	}{
+		{name: "created at ascending", filter: entity.UserFilter{UsernamePrefix: prefix}},
+		{name: "created at descending", filter: entity.UserFilter{UsernamePrefix: prefix, Descending: true}},
+		{name: "username ascending", filter: entity.UserFilter{UsernamePrefix: prefix, SortBy: entity.UserSortUsername}},
+		{name: "username descending", filter: entity.UserFilter{UsernamePrefix: prefix, SortBy: entity.UserSortUsername, Descending: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			all, err := users.GetAll("", test.filter, entity.Keyset{Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			paged := pageAll(t, 2, func(keyset entity.Keyset) ([]entity.User, entity.Keyset) {
+				page, err := users.GetAll("", test.filter, keyset)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(page) > 0 {
+					last := page[len(page)-1]
+					keyset = entity.Keyset{Limit: keyset.Limit, HasCursor: true, AfterCreatedAt: last.CreatedAt, AfterKey: last.Username, AfterId: last.Id}
+				}
+				return page, keyset
+			})
+
+			if len(all) != len(want) || len(paged) != len(all) {
+				t.Fatalf("got %d users in one query and %d paged, want %d", len(all), len(paged), len(want))
+			}
+
+			for i, user := range all {
+				if !want[user.Id] || paged[i].Id != user.Id {
+					t.Fatalf("user %d : got %s paged and %s in one query", i, paged[i].Username, user.Username)
+				}
+			}
+		})
+	}
+}
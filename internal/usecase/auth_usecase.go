@@ -1,11 +1,16 @@
 package usecase
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"time"
+	"user-personalize/internal/config"
 	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
 	"user-personalize/internal/repository"
 	"user-personalize/pkg/util/exception"
 	"user-personalize/pkg/util/mapping"
@@ -15,37 +20,81 @@ import (
 type AuthUC interface {
 	Login(payload dto.LoginRequest) (dto.LoginResponse, error)
 	Register(payload dto.UserRequest) (dto.UserResponse, error)
+	Reactivate(payload dto.ReactivateRequest) (dto.LoginResponse, error)
+	Authorize(userId string) error
 }
 
 type authUCImpl struct {
 	userRepository repository.UserRepository
 	jwtService     service.JwtService
 	validate       *validator.Validate
+	deletionConfig config.AccountDeletionConfig
 }
 
-func NewAuthUC(userRepository repository.UserRepository, jwtService service.JwtService, validate *validator.Validate) AuthUC {
-	return &authUCImpl{userRepository: userRepository, jwtService: jwtService, validate: validate}
+func NewAuthUC(userRepository repository.UserRepository, jwtService service.JwtService, validate *validator.Validate, deletionConfig config.AccountDeletionConfig) AuthUC {
+	return &authUCImpl{userRepository: userRepository, jwtService: jwtService, validate: validate, deletionConfig: deletionConfig}
 }
 
+// Login refuses deactivated accounts with exception.DeactivatedErr, they have to be reactivated first.
 func (a *authUCImpl) Login(payload dto.LoginRequest) (dto.LoginResponse, error) {
 	err := a.validate.Struct(payload)
 	if err != nil {
 		return dto.LoginResponse{}, fmt.Errorf("validate payload failed: %w", err)
 	}
 
-	user, err := a.userRepository.GetByEmail(payload.Email)
+	user, err := a.authenticate(payload.Email, payload.Password)
 	if err != nil {
-		return dto.LoginResponse{}, exception.NotFoundErr
+		return dto.LoginResponse{}, fmt.Errorf("LoginUC : %w", err)
+	}
+
+	if user.DeactivatedAt.Valid {
+		return dto.LoginResponse{}, fmt.Errorf("LoginUC : %w", exception.DeactivatedErr)
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password))
+	token, err := a.jwtService.GenerateToken(user.Id)
 	if err != nil {
 		return dto.LoginResponse{}, fmt.Errorf("LoginUC : %w", err)
 	}
 
+	return dto.LoginResponse{Token: *token}, nil
+}
+
+// Reactivate cancels the deletion of an account and logs in to it. It fails with
+// exception.ConflictErr when the account is not deactivated and with exception.ExpiredErr
+// once the grace period is over, such accounts are about to be erased.
+func (a *authUCImpl) Reactivate(payload dto.ReactivateRequest) (dto.LoginResponse, error) {
+	err := a.validate.Struct(payload)
+	if err != nil {
+		return dto.LoginResponse{}, fmt.Errorf("validate payload failed: %w", err)
+	}
+
+	user, err := a.authenticate(payload.Email, payload.Password)
+	if err != nil {
+		return dto.LoginResponse{}, fmt.Errorf("ReactivateUC : %w", err)
+	}
+
+	if !user.DeactivatedAt.Valid {
+		return dto.LoginResponse{}, fmt.Errorf("ReactivateUC : account is active : %w", exception.ConflictErr)
+	}
+
+	// the grace period is checked again by the update, the purge may have started since the read
+	cutoff := time.Now().Add(-a.deletionConfig.GracePeriod)
+	if !user.DeactivatedAt.Time.After(cutoff) {
+		return dto.LoginResponse{}, fmt.Errorf("ReactivateUC : %w", exception.ExpiredErr)
+	}
+
+	err = a.userRepository.Reactivate(user.Id, cutoff)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.LoginResponse{}, fmt.Errorf("ReactivateUC : %w", exception.ExpiredErr)
+	}
+
+	if err != nil {
+		return dto.LoginResponse{}, fmt.Errorf("ReactivateUC : %w", err)
+	}
+
 	token, err := a.jwtService.GenerateToken(user.Id)
 	if err != nil {
-		return dto.LoginResponse{}, fmt.Errorf("LoginUC : %w", err)
+		return dto.LoginResponse{}, fmt.Errorf("ReactivateUC : %w", err)
 	}
 
 	return dto.LoginResponse{Token: *token}, nil
@@ -79,3 +128,45 @@ func (a *authUCImpl) Register(payload dto.UserRequest) (dto.UserResponse, error)
 
 	return mapping.MapUserToResponse(user), nil
 }
+
+// Authorize checks on every request that the owner of a valid token may still use it,
+// tokens of deleted, deactivated and suspended users are refused before they expire.
+func (a *authUCImpl) Authorize(userId string) error {
+	user, err := a.userRepository.GetById(userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("AuthorizeUC : %w", exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("AuthorizeUC : %w", err)
+	}
+
+	if user.SuspendedAt.Valid {
+		return fmt.Errorf("AuthorizeUC : account is suspended : %w", exception.ForbiddenErr)
+	}
+
+	if user.DeactivatedAt.Valid {
+		return fmt.Errorf("AuthorizeUC : %w", exception.DeactivatedErr)
+	}
+
+	return nil
+}
+
+// authenticate checks the credentials and that the account is not suspended.
+func (a *authUCImpl) authenticate(email string, password string) (entity.User, error) {
+	user, err := a.userRepository.GetByEmail(email)
+	if err != nil {
+		return entity.User{}, exception.NotFoundErr
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if user.SuspendedAt.Valid {
+		return entity.User{}, fmt.Errorf("account is suspended : %w", exception.ForbiddenErr)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,362 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/storage"
+)
+
+// stagingPrefix holds uploads that are not referenced by a committed row yet,
+// the reconciler removes what is left there. Objects under directUploadPrefix
+// were put there by clients with a presigned url and are cleaned up by UploadUC.
+// Committed files live under blobPrefix, addressed by their SHA-256.
+const (
+	stagingPrefix      = "staging/"
+	directUploadPrefix = "direct/"
+	blobPrefix         = "blobs/"
+	variantsPrefix     = "variants/"
+)
+
+// legacyLocalPrefix starts the paths the first version wrote photos to, relative to the
+// working directory and outside of any BlobStore, e.g. "./images/<name>.jpg".
+const legacyLocalPrefix = "./"
+
+// allowedPhotoTypes maps a sniffed image content type to the extension it is stored with.
+var allowedPhotoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type stagedBlob struct {
+	Key         string
+	Hash        string
+	Size        int64
+	ContentType string
+	Ext         string
+}
+
+// blobManager keeps files in storage in step with the reference counts in the
+// blobs table. Callers acquire and release inside their own transaction and
+// promote or discard files once it has committed.
+type blobManager struct {
+	blobRepository repository.BlobRepository
+	transactor     repository.Transactor
+	blobStore      storage.BlobStore
+}
+
+func newBlobManager(blobRepository repository.BlobRepository, transactor repository.Transactor, blobStore storage.BlobStore) *blobManager {
+	return &blobManager{blobRepository: blobRepository, transactor: transactor, blobStore: blobStore}
+}
+
+// stage writes the upload under stagingPrefix, hashing and sniffing it on the way.
+func (b *blobManager) stage(file dto.PhotoFile) (stagedBlob, error) {
+	hasher := sha256.New()
+	head := &headWriter{limit: 512}
+	reader := io.TeeReader(file.Content, io.MultiWriter(hasher, head))
+
+	key := stagingPrefix + uuid.NewString()
+	info, err := b.blobStore.Put(key, reader, file.Size, "application/octet-stream")
+	if err != nil {
+		return stagedBlob{}, err
+	}
+
+	contentType, ext, err := b.sniff(head.buf)
+	if err != nil {
+		b.discard(key)
+		return stagedBlob{}, err
+	}
+
+	return stagedBlob{
+		Key:         key,
+		Hash:        hex.EncodeToString(hasher.Sum(nil)),
+		Size:        info.Size,
+		ContentType: contentType,
+		Ext:         ext,
+	}, nil
+}
+
+// stageExisting hashes an object that is already in storage, e.g. a direct upload.
+func (b *blobManager) stageExisting(key string) (stagedBlob, error) {
+	content, info, err := b.blobStore.Get(key)
+	if err != nil {
+		return stagedBlob{}, err
+	}
+	defer content.Close()
+
+	hasher := sha256.New()
+	head := &headWriter{limit: 512}
+	_, err = io.Copy(io.MultiWriter(hasher, head), content)
+	if err != nil {
+		return stagedBlob{}, err
+	}
+
+	contentType, ext, err := b.sniff(head.buf)
+	if err != nil {
+		return stagedBlob{}, err
+	}
+
+	return stagedBlob{
+		Key:         key,
+		Hash:        hex.EncodeToString(hasher.Sum(nil)),
+		Size:        info.Size,
+		ContentType: contentType,
+		Ext:         ext,
+	}, nil
+}
+
+// openLegacy opens the file of a photo stored before content addressing, either under
+// a BlobStore key or at a path of the first version, see legacyLocalPrefix.
+func (b *blobManager) openLegacy(key string) (io.ReadCloser, int64, error) {
+	if !strings.HasPrefix(key, legacyLocalPrefix) {
+		content, info, err := b.blobStore.Get(key)
+		return content, info.Size, err
+	}
+
+	file, err := os.Open(filepath.FromSlash(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, exception.NotFoundErr
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	return file, stat.Size(), nil
+}
+
+// acquire adds a reference to the staged content. created reports whether the
+// blob is new, in which case the staged file has to be promoted after commit.
+func (b *blobManager) acquire(tx *sql.Tx, staged stagedBlob) (entity.Blob, bool, error) {
+	blobRepository := b.blobRepository.WithTx(tx)
+	err := blobRepository.Lock(staged.Hash)
+	if err != nil {
+		return entity.Blob{}, false, err
+	}
+
+	blob, err := blobRepository.Acquire(entity.Blob{
+		Hash:        staged.Hash,
+		ObjectKey:   fmt.Sprintf("%s%s/%s%s", blobPrefix, staged.Hash[:2], staged.Hash, staged.Ext),
+		Size:        staged.Size,
+		ContentType: staged.ContentType,
+	})
+	if err != nil {
+		return entity.Blob{}, false, err
+	}
+
+	return blob, blob.RefCount == 1, nil
+}
+
+// retain adds a reference to a blob that is stored already, e.g. for a version that
+// keeps the file the photo goes on using.
+func (b *blobManager) retain(tx *sql.Tx, hash string) (entity.Blob, error) {
+	blobRepository := b.blobRepository.WithTx(tx)
+	err := blobRepository.Lock(hash)
+	if err != nil {
+		return entity.Blob{}, err
+	}
+
+	blob, err := blobRepository.FindByHash(hash)
+	if err != nil {
+		return entity.Blob{}, err
+	}
+
+	return blobRepository.Acquire(blob)
+}
+
+// release drops a reference and deletes the blob row with the last one.
+// The file itself is only removed by the caller once the transaction has committed, see discardReleased.
+func (b *blobManager) release(tx *sql.Tx, hash string) (entity.Blob, bool, error) {
+	if hash == "" {
+		return entity.Blob{}, false, nil
+	}
+
+	blobRepository := b.blobRepository.WithTx(tx)
+	blob, err := blobRepository.Release(hash)
+	if err != nil {
+		return entity.Blob{}, false, err
+	}
+
+	if blob.RefCount > 0 {
+		return blob, false, nil
+	}
+
+	err = blobRepository.Delete(hash)
+	if err != nil {
+		return entity.Blob{}, false, err
+	}
+
+	return blob, true, nil
+}
+
+// settle runs after commit: it promotes the staged file of a new blob and drops
+// the staged copy of content that was already stored.
+func (b *blobManager) settle(staged stagedBlob, blob entity.Blob, created bool) error {
+	if !created {
+		b.discard(staged.Key)
+		return nil
+	}
+
+	return b.blobStore.Move(staged.Key, blob.ObjectKey)
+}
+
+// discard removes a file that is no longer referenced. Failures are only logged,
+// the reconciler removes whatever is left behind.
+func (b *blobManager) discard(key string) {
+	err := b.blobStore.Delete(key)
+	if err != nil && !errors.Is(err, exception.NotFoundErr) {
+		log.Println("discard blob", key, ":", err)
+	}
+}
+
+// discardReleased removes the file of a blob whose last reference was released by a
+// transaction that has committed. The same content may have been uploaded again since,
+// so the hash is locked against acquire and the file only removed while no row refers to it.
+func (b *blobManager) discardReleased(hash string, key string) {
+	err := b.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		blobRepository := b.blobRepository.WithTx(tx)
+		err := blobRepository.Lock(hash)
+		if err != nil {
+			return err
+		}
+
+		_, err = blobRepository.FindByHash(hash)
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		b.discardWithVariants(key)
+		return nil
+	})
+	if err != nil {
+		log.Println("discard blob", key, ":", err)
+	}
+}
+
+// discardWithVariants removes a file together with every rendition made from it.
+func (b *blobManager) discardWithVariants(key string) {
+	if strings.HasPrefix(key, legacyLocalPrefix) {
+		err := os.Remove(filepath.FromSlash(key))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Println("discard blob", key, ":", err)
+		}
+	} else {
+		b.discard(key)
+	}
+
+	variants, err := b.blobStore.List(b.variantPrefix(key))
+	if err != nil {
+		log.Println("discard variants", key, ":", err)
+		return
+	}
+
+	for _, variant := range variants {
+		b.discard(variant.Key)
+	}
+}
+
+// variantPrefix maps "blobs/ab/<hash>.jpg" to "variants/ab/<hash>.jpg/".
+// Keys written before content addressing ("photos/<userId>/<name>.jpg") map the same way.
+func (b *blobManager) variantPrefix(key string) string {
+	return variantsPrefix + key[strings.Index(key, "/")+1:] + "/"
+}
+
+// variantSource maps a variant key back to the key of its blob, the reverse of variantPrefix.
+// It reports false for variants of a legacy file, "variants/<userId>/<name>.jpg/..." could
+// not be told from a blob otherwise.
+func (b *blobManager) variantSource(key string) (string, bool) {
+	rest := strings.TrimPrefix(key, variantsPrefix)
+	end := strings.LastIndex(rest, "/")
+	if end < 0 {
+		return "", false
+	}
+
+	// a blob key is "<first two digits of the hash>/<hash><ext>"
+	dir, name, ok := strings.Cut(rest[:end], "/")
+	if !ok || len(dir) != 2 || len(name) < sha256.Size*2 || !strings.HasPrefix(name, dir) {
+		return "", false
+	}
+
+	_, err := hex.DecodeString(name[:sha256.Size*2])
+	if err != nil {
+		return "", false
+	}
+
+	return blobPrefix + rest[:end], true
+}
+
+// sniff judges the content by its magic bytes alone and only accepts the raster formats
+// of allowedPhotoTypes. What the client claims, its content type or the extension of the
+// file name, is ignored, so a document a browser would render (html, svg, ...) is never
+// stored as a photo.
+func (b *blobManager) sniff(head []byte) (string, string, error) {
+	contentType := http.DetectContentType(head)
+	ext, ok := allowedPhotoTypes[contentType]
+	if !ok {
+		return "", "", fmt.Errorf("content type %q : %w", contentType, exception.UnsupportedErr)
+	}
+
+	return contentType, ext, nil
+}
+
+// servedType is the content type a photo file is served with: the sniffed type stored
+// with its blob, or for files without one the type sniffed from head. Anything outside
+// allowedPhotoTypes is served as an opaque download.
+func (b *blobManager) servedType(hash string, head []byte) string {
+	contentType := http.DetectContentType(head)
+	if hash != "" {
+		blob, err := b.blobRepository.FindByHash(hash)
+		if err == nil {
+			contentType = blob.ContentType
+		}
+	}
+
+	if _, ok := allowedPhotoTypes[contentType]; !ok {
+		return "application/octet-stream"
+	}
+
+	return contentType
+}
+
+// headWriter keeps the first limit bytes written to it, enough for content sniffing.
+type headWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	if remaining := h.limit - len(h.buf); remaining > 0 {
+		if len(p) < remaining {
+			remaining = len(p)
+		}
+		h.buf = append(h.buf, p[:remaining]...)
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariantSource(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	blobs := &blobManager{}
+
+	tests := []struct {
+		name   string
+		source string
+		ok     bool
+	}{
+		{name: "blob", source: "blobs/ab/" + hash + ".jpg", ok: true},
+		{name: "blob without an extension", source: "blobs/ab/" + hash, ok: true},
+		{name: "legacy file", source: "photos/0b6c1a5e-4a3d-4f7e-9a51-1f0e3c2d4b6a/cat.jpg"},
+		{name: "legacy local file", source: "./images/cat.jpg"},
+		{name: "legacy file in a two letter directory", source: "photos/ab/cat.jpg"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			variant := blobs.variantPrefix(test.source) + "thumbnail.jpg"
+
+			got, ok := blobs.variantSource(variant)
+			if ok != test.ok {
+				t.Fatalf("%s : got ok %v, want %v", variant, ok, test.ok)
+			}
+
+			if ok && got != test.source {
+				t.Fatalf("%s : got source %s, want %s", variant, got, test.source)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+)
+
+// BlockUC manages blocks and mutes, all calls are idempotent. A block hides both users
+// from each other everywhere and ends the follows between them, a mute only keeps the
+// muted user out of the muter's feed.
+type BlockUC interface {
+	Block(userId string, targetId string) (dto.BlockResponse, error)
+	Unblock(userId string, targetId string) (dto.BlockResponse, error)
+	Mute(userId string, targetId string) (dto.MuteResponse, error)
+	Unmute(userId string, targetId string) (dto.MuteResponse, error)
+}
+
+type blockUCImpl struct {
+	blockRepository  repository.BlockRepository
+	muteRepository   repository.MuteRepository
+	followRepository repository.FollowRepository
+	userRepository   repository.UserRepository
+	transactor       repository.Transactor
+}
+
+func NewBlockUC(blockRepository repository.BlockRepository, muteRepository repository.MuteRepository, followRepository repository.FollowRepository, userRepository repository.UserRepository, transactor repository.Transactor) BlockUC {
+	return &blockUCImpl{
+		blockRepository:  blockRepository,
+		muteRepository:   muteRepository,
+		followRepository: followRepository,
+		userRepository:   userRepository,
+		transactor:       transactor,
+	}
+}
+
+func (b *blockUCImpl) Block(userId string, targetId string) (dto.BlockResponse, error) {
+	err := b.target(userId, targetId)
+	if err != nil {
+		return dto.BlockResponse{}, fmt.Errorf("BlockUC : %w", err)
+	}
+
+	// unfollowing in the same transaction leaves no window in which a blocked user still follows
+	err = b.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		err := b.blockRepository.WithTx(tx).Block(userId, targetId)
+		if err != nil {
+			return err
+		}
+
+		err = b.followRepository.WithTx(tx).Unfollow(userId, targetId)
+		if err != nil {
+			return err
+		}
+
+		return b.followRepository.WithTx(tx).Unfollow(targetId, userId)
+	})
+	if err != nil {
+		return dto.BlockResponse{}, fmt.Errorf("BlockUC : %w", err)
+	}
+
+	return dto.BlockResponse{UserId: targetId, Blocked: true}, nil
+}
+
+// Unblock lifts the block of userId only, the follows it ended are not restored.
+func (b *blockUCImpl) Unblock(userId string, targetId string) (dto.BlockResponse, error) {
+	err := b.target(userId, targetId)
+	if err != nil {
+		return dto.BlockResponse{}, fmt.Errorf("UnblockUC : %w", err)
+	}
+
+	err = b.blockRepository.Unblock(userId, targetId)
+	if err != nil {
+		return dto.BlockResponse{}, fmt.Errorf("UnblockUC : %w", err)
+	}
+
+	return dto.BlockResponse{UserId: targetId, Blocked: false}, nil
+}
+
+func (b *blockUCImpl) Mute(userId string, targetId string) (dto.MuteResponse, error) {
+	err := b.target(userId, targetId)
+	if err != nil {
+		return dto.MuteResponse{}, fmt.Errorf("MuteUC : %w", err)
+	}
+
+	err = b.muteRepository.Mute(userId, targetId)
+	if err != nil {
+		return dto.MuteResponse{}, fmt.Errorf("MuteUC : %w", err)
+	}
+
+	return dto.MuteResponse{UserId: targetId, Muted: true}, nil
+}
+
+func (b *blockUCImpl) Unmute(userId string, targetId string) (dto.MuteResponse, error) {
+	err := b.target(userId, targetId)
+	if err != nil {
+		return dto.MuteResponse{}, fmt.Errorf("UnmuteUC : %w", err)
+	}
+
+	err = b.muteRepository.Unmute(userId, targetId)
+	if err != nil {
+		return dto.MuteResponse{}, fmt.Errorf("UnmuteUC : %w", err)
+	}
+
+	return dto.MuteResponse{UserId: targetId, Muted: false}, nil
+}
+
+// target checks that targetId is another user that exists.
+func (b *blockUCImpl) target(userId string, targetId string) error {
+	if userId == targetId {
+		return fmt.Errorf("cannot block or mute yourself : %w", exception.InvalidErr)
+	}
+
+	_, err := b.userRepository.GetById(targetId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user %q : %w", targetId, exception.NotFoundErr)
+	}
+
+	return err
+}
@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"unicode/utf8"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/mapping"
+)
+
+const maxCommentLength = 2000
+
+// CommentUC manages threaded comments on photos. Authors edit and delete their own
+// comments, the owner of the photo may delete any comment on it. Users that block
+// each other can neither reply to nor see each other's comments.
+type CommentUC interface {
+	CreateComment(userId string, photoId string, request dto.CommentRequest) (dto.CommentResponse, error)
+	UpdateComment(userId string, photoId string, commentId string, request dto.CommentRequest) (dto.CommentResponse, error)
+	DeleteComment(userId string, photoId string, commentId string) error
+	GetComments(viewerId string, photoId string, page dto.PageRequest) (dto.CommentListResponse, error)
+}
+
+type commentUCImpl struct {
+	commentRepository repository.CommentRepository
+	blockRepository   repository.BlockRepository
+	access            *photoAccess
+}
+
+func NewCommentUC(commentRepository repository.CommentRepository, photosRepository repository.PhotosRepository, followRepository repository.FollowRepository, blockRepository repository.BlockRepository) CommentUC {
+	return &commentUCImpl{
+		commentRepository: commentRepository,
+		blockRepository:   blockRepository,
+		access:            newPhotoAccess(photosRepository, followRepository, blockRepository),
+	}
+}
+
+func (c *commentUCImpl) CreateComment(userId string, photoId string, request dto.CommentRequest) (dto.CommentResponse, error) {
+	_, err := c.access.find(userId, photoId)
+	if err != nil {
+		return dto.CommentResponse{}, fmt.Errorf("CreateCommentUC : %w", err)
+	}
+
+	body, err := commentBody(request.Body)
+	if err != nil {
+		return dto.CommentResponse{}, fmt.Errorf("CreateCommentUC : %w", err)
+	}
+
+	// replies must stay within the thread of the same photo
+	if request.ParentId != "" {
+		parent, err := c.commentRepository.FindById(request.ParentId)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return dto.CommentResponse{}, fmt.Errorf("CreateCommentUC : %w", err)
+		}
+
+		if err != nil || parent.PhotoId != photoId {
+			return dto.CommentResponse{}, fmt.Errorf("CreateCommentUC : parent %q : %w", request.ParentId, exception.InvalidErr)
+		}
+
+		blocked, err := c.blockRepository.IsBlocked(userId, parent.UserId)
+		if err != nil {
+			return dto.CommentResponse{}, fmt.Errorf("CreateCommentUC : %w", err)
+		}
+
+		if blocked {
+			return dto.CommentResponse{}, fmt.Errorf("CreateCommentUC : parent %q : %w", request.ParentId, exception.InvalidErr)
+		}
+	}
+
+	comment, err := c.commentRepository.Insert(entity.Comment{
+		Id:       uuid.NewString(),
+		PhotoId:  photoId,
+		UserId:   userId,
+		ParentId: request.ParentId,
+		Body:     body,
+	})
+	if err != nil {
+		return dto.CommentResponse{}, fmt.Errorf("CreateCommentUC : %w", err)
+	}
+
+	return mapping.MapCommentToResponse(comment), nil
+}
+
+func (c *commentUCImpl) UpdateComment(userId string, photoId string, commentId string, request dto.CommentRequest) (dto.CommentResponse, error) {
+	_, comment, err := c.find(userId, photoId, commentId)
+	if err != nil {
+		return dto.CommentResponse{}, fmt.Errorf("UpdateCommentUC : %w", err)
+	}
+
+	if comment.UserId != userId {
+		return dto.CommentResponse{}, fmt.Errorf("UpdateCommentUC : %w", exception.ForbiddenErr)
+	}
+
+	comment.Body, err = commentBody(request.Body)
+	if err != nil {
+		return dto.CommentResponse{}, fmt.Errorf("UpdateCommentUC : %w", err)
+	}
+
+	comment, err = c.commentRepository.Update(comment)
+	if err != nil {
+		return dto.CommentResponse{}, fmt.Errorf("UpdateCommentUC : %w", err)
+	}
+
+	return mapping.MapCommentToResponse(comment), nil
+}
+
+// DeleteComment removes the comment and the replies to it.
+func (c *commentUCImpl) DeleteComment(userId string, photoId string, commentId string) error {
+	photo, comment, err := c.find(userId, photoId, commentId)
+	if err != nil {
+		return fmt.Errorf("DeleteCommentUC : %w", err)
+	}
+
+	if comment.UserId != userId && photo.UserId != userId {
+		return fmt.Errorf("DeleteCommentUC : %w", exception.ForbiddenErr)
+	}
+
+	err = c.commentRepository.Delete(comment.Id)
+	if err != nil {
+		return fmt.Errorf("DeleteCommentUC : %w", err)
+	}
+
+	return nil
+}
+
+// GetComments returns a page of the threads on the photo, viewerId is empty for anonymous callers.
+// Comments of users blocked with viewerId are left out together with the replies to them, so a
+// page may hold fewer threads than asked for.
+func (c *commentUCImpl) GetComments(viewerId string, photoId string, page dto.PageRequest) (dto.CommentListResponse, error) {
+	keyset, limit, err := newKeyset(page)
+	if err != nil {
+		return dto.CommentListResponse{}, fmt.Errorf("GetCommentsUC : %w", err)
+	}
+
+	_, err = c.access.find(viewerId, photoId)
+	if err != nil {
+		return dto.CommentListResponse{}, fmt.Errorf("GetCommentsUC : %w", err)
+	}
+
+	comments, err := c.commentRepository.FindThreads(photoId, keyset)
+	if err != nil {
+		return dto.CommentListResponse{}, fmt.Errorf("GetCommentsUC : %w", err)
+	}
+
+	hidden := make(map[string]bool)
+	if viewerId != "" {
+		blockedIds, err := c.blockRepository.FindBlockedIds(viewerId)
+		if err != nil {
+			return dto.CommentListResponse{}, fmt.Errorf("GetCommentsUC : %w", err)
+		}
+
+		for _, id := range blockedIds {
+			hidden[id] = true
+		}
+	}
+
+	roots := make([]entity.Comment, 0)
+	replies := make(map[string][]entity.Comment)
+	for _, comment := range comments {
+		if comment.ParentId == "" {
+			roots = append(roots, comment)
+			continue
+		}
+
+		replies[comment.ParentId] = append(replies[comment.ParentId], comment)
+	}
+
+	response := dto.CommentListResponse{Items: make([]dto.CommentResponse, 0, len(roots)), Page: pageResponse(limit, nil)}
+	if len(roots) > limit {
+		roots = roots[:limit]
+		last := roots[limit-1]
+		response.Page = pageResponse(limit, &pageCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+
+	var build func(comment entity.Comment) dto.CommentResponse
+	build = func(comment entity.Comment) dto.CommentResponse {
+		result := mapping.MapCommentToResponse(comment)
+		result.Replies = make([]dto.CommentResponse, 0, len(replies[comment.Id]))
+		for _, reply := range replies[comment.Id] {
+			if !hidden[reply.UserId] {
+				result.Replies = append(result.Replies, build(reply))
+			}
+		}
+
+		return result
+	}
+
+	for _, root := range roots {
+		if !hidden[root.UserId] {
+			response.Items = append(response.Items, build(root))
+		}
+	}
+
+	return response, nil
+}
+
+// find returns the photo and the comment on it when userId may see the photo.
+func (c *commentUCImpl) find(userId string, photoId string, commentId string) (entity.Photos, entity.Comment, error) {
+	photo, err := c.access.find(userId, photoId)
+	if err != nil {
+		return entity.Photos{}, entity.Comment{}, err
+	}
+
+	comment, err := c.commentRepository.FindById(commentId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && comment.PhotoId != photoId) {
+		return entity.Photos{}, entity.Comment{}, exception.NotFoundErr
+	}
+
+	if err != nil {
+		return entity.Photos{}, entity.Comment{}, err
+	}
+
+	return photo, comment, nil
+}
+
+func commentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > maxCommentLength {
+		return "", fmt.Errorf("comment must have 1 to %d characters : %w", maxCommentLength, exception.InvalidErr)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/pkg/util/exception"
+)
+
+// Limits of the pages returned by keyset paginated lists.
+const (
+	pageDefaultLimit = 20
+	pageMaxLimit     = 50
+)
+
+// pageCursor is the position after the last item of a page. Lists ordered by
+// creation time use CreatedAt, search results ordered by relevance use Rank and lists
+// ordered by a text column use Key. Sort is the sort of the list the cursor was made
+// for, a cursor is only valid for the same sort.
+type pageCursor struct {
+	Rank      float32   `json:"r,omitempty"`
+	CreatedAt time.Time `json:"t,omitempty"`
+	Key       string    `json:"k,omitempty"`
+	Sort      string    `json:"s,omitempty"`
+	Id        string    `json:"i"`
+}
+
+// pageLimit applies the default to an unset limit and rejects values out of range.
+func pageLimit(limit int) (int, error) {
+	if limit == 0 {
+		return pageDefaultLimit, nil
+	}
+
+	if limit < 1 || limit > pageMaxLimit {
+		return 0, fmt.Errorf("limit %d : %w", limit, exception.InvalidErr)
+	}
+
+	return limit, nil
+}
+
+// newKeyset validates page and returns the keyset to query with and the page size. The
+// keyset asks for one extra row, which tells whether there is a next page.
+func newKeyset(page dto.PageRequest) (entity.Keyset, int, error) {
+	return newSortedKeyset(page, "")
+}
+
+// newSortedKeyset is newKeyset for lists that can be sorted, it rejects cursors made
+// for another sort.
+func newSortedKeyset(page dto.PageRequest, sort string) (entity.Keyset, int, error) {
+	limit, err := pageLimit(page.Limit)
+	if err != nil {
+		return entity.Keyset{}, 0, err
+	}
+
+	keyset := entity.Keyset{Limit: limit + 1}
+	if page.Cursor != "" {
+		cursor, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return entity.Keyset{}, 0, err
+		}
+
+		if cursor.Sort != sort {
+			return entity.Keyset{}, 0, fmt.Errorf("cursor is not valid for sort %q : %w", sort, exception.InvalidErr)
+		}
+
+		keyset.HasCursor = true
+		keyset.AfterCreatedAt = cursor.CreatedAt
+		keyset.AfterKey = cursor.Key
+		keyset.AfterId = cursor.Id
+	}
+
+	return keyset, limit, nil
+}
+
+// pageResponse is the pagination metadata of a page of at most limit items, next is the
+// position after its last item and nil on the last page.
+func pageResponse(limit int, next *pageCursor) dto.PageResponse {
+	page := dto.PageResponse{Limit: limit}
+	if next != nil {
+		page.HasMore = true
+		page.NextCursor = encodeCursor(*next)
+	}
+
+	return page
+}
+
+func encodeCursor(cursor pageCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("cursor : %v : %w", err, exception.InvalidErr)
+	}
+
+	var cursor pageCursor
+	err = json.Unmarshal(raw, &cursor)
+	if err != nil || cursor.Id == "" {
+		return pageCursor{}, fmt.Errorf("cursor is not valid : %w", exception.InvalidErr)
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/pkg/util/exception"
+)
+
+func TestNewSortedKeyset(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	byDate := encodeCursor(pageCursor{CreatedAt: createdAt, Id: "a"})
+	byName := encodeCursor(pageCursor{Key: "alice", Sort: "username", Id: "b"})
+
+	tests := []struct {
+		name      string
+		page      dto.PageRequest
+		sort      string
+		want      entity.Keyset
+		wantLimit int
+		err       error
+	}{
+		{name: "first page with the default limit", page: dto.PageRequest{}, want: entity.Keyset{Limit: pageDefaultLimit + 1}, wantLimit: pageDefaultLimit},
+		{name: "cursor of the same sort", page: dto.PageRequest{Limit: 5, Cursor: byDate}, want: entity.Keyset{Limit: 6, HasCursor: true, AfterCreatedAt: createdAt, AfterId: "a"}, wantLimit: 5},
+		{name: "cursor of a named sort", page: dto.PageRequest{Limit: 5, Cursor: byName}, sort: "username", want: entity.Keyset{Limit: 6, HasCursor: true, AfterKey: "alice", AfterId: "b"}, wantLimit: 5},
+		{name: "cursor of another sort", page: dto.PageRequest{Cursor: byName}, sort: "created_at", err: exception.InvalidErr},
+		{name: "cursor without a sort for a sorted list", page: dto.PageRequest{Cursor: byDate}, sort: "username", err: exception.InvalidErr},
+		{name: "cursor that is not base64", page: dto.PageRequest{Cursor: "%%%"}, err: exception.InvalidErr},
+		{name: "cursor without an id", page: dto.PageRequest{Cursor: encodeCursor(pageCursor{Key: "alice"})}, err: exception.InvalidErr},
+		{name: "limit above the maximum", page: dto.PageRequest{Limit: pageMaxLimit + 1}, err: exception.InvalidErr},
+		{name: "negative limit", page: dto.PageRequest{Limit: -1}, err: exception.InvalidErr},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keyset, limit, err := newSortedKeyset(test.page, test.sort)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			if !keyset.AfterCreatedAt.Equal(test.want.AfterCreatedAt) {
+				t.Fatalf("got created at %v, want %v", keyset.AfterCreatedAt, test.want.AfterCreatedAt)
+			}
+
+			keyset.AfterCreatedAt, test.want.AfterCreatedAt = time.Time{}, time.Time{}
+			if keyset != test.want || limit != test.wantLimit {
+				t.Fatalf("got %+v and limit %d, want %+v and limit %d", keyset, limit, test.want, test.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"time"
+	"user-personalize/internal/config"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/service"
+)
+
+// ErasureUC deletes accounts. A user deleting their account only deactivates it, it is
+// erased once the grace period is over unless the user reactivates it in the meantime.
+// Admins can erase an account right away.
+//
+// Erasure suspends the account, so that it takes no more requests, then removes the files:
+// photos, earlier versions and the blobs only they referenced, unfinished uploads and
+// exports. The user row goes last, taking every row that references it along, while
+// reports and the audit trail, which outlive the user, are anonymized. Every erasure leaves
+// a signed receipt.
+//
+// An erasure that fails halfway leaves the account in place and can simply be run again.
+type ErasureUC interface {
+	ScheduleDeletion(requesterId string, userId string) (dto.AccountDeletionResponse, error)
+	EraseUser(requesterId string, userId string) (dto.ErasureReceipt, error)
+	GetReceipt(receiptId string) (dto.ErasureReceipt, error)
+	VerifyReceipt(receipt dto.ErasureReceipt) (dto.ErasureReceiptVerification, error)
+	PurgeDeactivated() (int, error)
+	RunDeletionPurge()
+}
+
+type erasureUCImpl struct {
+	userRepository           repository.UserRepository
+	personalDataRepository   repository.PersonalDataRepository
+	erasureReceiptRepository repository.ErasureReceiptRepository
+	transactor               repository.Transactor
+	photosUC                 PhotosUC
+	uploadUC                 UploadUC
+	exportUC                 ExportUC
+	receiptSigner            service.ReceiptSignerService
+	cfg                      config.AccountDeletionConfig
+}
+
+func NewErasureUC(userRepository repository.UserRepository, personalDataRepository repository.PersonalDataRepository, erasureReceiptRepository repository.ErasureReceiptRepository,
+	transactor repository.Transactor, photosUC PhotosUC, uploadUC UploadUC, exportUC ExportUC, receiptSigner service.ReceiptSignerService, cfg config.AccountDeletionConfig) ErasureUC {
+	return &erasureUCImpl{
+		userRepository:           userRepository,
+		personalDataRepository:   personalDataRepository,
+		erasureReceiptRepository: erasureReceiptRepository,
+		transactor:               transactor,
+		photosUC:                 photosUC,
+		uploadUC:                 uploadUC,
+		exportUC:                 exportUC,
+		receiptSigner:            receiptSigner,
+		cfg:                      cfg,
+	}
+}
+
+// ScheduleDeletion deactivates the account of the user, users can only delete their own.
+// Deleting a deactivated account again keeps the original schedule.
+func (e *erasureUCImpl) ScheduleDeletion(requesterId string, userId string) (dto.AccountDeletionResponse, error) {
+	if requesterId != userId {
+		return dto.AccountDeletionResponse{}, fmt.Errorf("ScheduleDeletionUC : %w", exception.ForbiddenErr)
+	}
+
+	user, err := e.userRepository.Deactivate(userId, uuid.NewString())
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.AccountDeletionResponse{}, fmt.Errorf("ScheduleDeletionUC : %w", exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return dto.AccountDeletionResponse{}, fmt.Errorf("ScheduleDeletionUC : %w", err)
+	}
+
+	return dto.AccountDeletionResponse{
+		UserId:        user.Id,
+		DeactivatedAt: user.DeactivatedAt.Time.Format(time.RFC3339),
+		DeleteAt:      user.DeactivatedAt.Time.Add(e.cfg.GracePeriod).Format(time.RFC3339),
+		ReceiptId:     user.DeletionReceiptId,
+	}, nil
+}
+
+// EraseUser is the hard delete for admins, it skips the grace period.
+func (e *erasureUCImpl) EraseUser(requesterId string, userId string) (dto.ErasureReceipt, error) {
+	err := requireAdmin(e.userRepository, requesterId)
+	if err != nil {
+		return dto.ErasureReceipt{}, fmt.Errorf("EraseUserUC : %w", err)
+	}
+
+	_, err = e.userRepository.GetById(userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.ErasureReceipt{}, fmt.Errorf("EraseUserUC : %w", exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return dto.ErasureReceipt{}, fmt.Errorf("EraseUserUC : %w", err)
+	}
+
+	// a user who deleted the account before was promised a receipt id, it is kept
+	user, err := e.userRepository.Deactivate(userId, uuid.NewString())
+	if err != nil {
+		return dto.ErasureReceipt{}, fmt.Errorf("EraseUserUC : %w", err)
+	}
+
+	receipt, err := e.erase(user)
+	if err != nil {
+		return dto.ErasureReceipt{}, fmt.Errorf("EraseUserUC : %w", err)
+	}
+
+	return receipt, nil
+}
+
+// PurgeDeactivated erases the accounts whose grace period is over and returns how many
+// it erased. Reactivation checks the grace period in the same statement that clears the
+// deactivation and is refused once the account is suspended, which erasing does first, so
+// a user cannot take back an account while it is being erased. Each receipt is stored
+// under the id the user got when deleting the account, see GetReceipt.
+func (e *erasureUCImpl) PurgeDeactivated() (int, error) {
+	users, err := e.userRepository.FindDeactivatedBefore(time.Now().Add(-e.cfg.GracePeriod))
+	if err != nil {
+		return 0, fmt.Errorf("PurgeDeactivatedUC : %w", err)
+	}
+
+	erased := 0
+	for _, user := range users {
+		_, err := e.erase(user)
+		if err != nil {
+			log.Println(fmt.Errorf("PurgeDeactivatedUC : user %s : %w", user.Id, err))
+			continue
+		}
+
+		erased++
+	}
+
+	return erased, nil
+}
+
+// RunDeletionPurge is meant to be started in its own goroutine, a negative interval
+// disables it.
+func (e *erasureUCImpl) RunDeletionPurge() {
+	if e.cfg.PurgeInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		erased, err := e.PurgeDeactivated()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		if erased > 0 {
+			log.Printf("account deletion purge : erased %d accounts", erased)
+		}
+	}
+}
+
+func (e *erasureUCImpl) erase(user entity.User) (dto.ErasureReceipt, error) {
+	userId := user.Id
+	receiptId := user.DeletionReceiptId
+	if receiptId == "" {
+		receiptId = uuid.NewString()
+	}
+
+	err := e.userRepository.Suspend(userId)
+	if err != nil {
+		return dto.ErasureReceipt{}, err
+	}
+
+	count, err := e.personalDataRepository.Count(userId)
+	if err != nil {
+		return dto.ErasureReceipt{}, err
+	}
+
+	photos, err := e.photosUC.PurgeUserPhotos(userId)
+	if err != nil {
+		return dto.ErasureReceipt{}, err
+	}
+
+	uploads, err := e.uploadUC.DiscardUserUploads(userId)
+	if err != nil {
+		return dto.ErasureReceipt{}, err
+	}
+
+	exports, err := e.exportUC.DiscardUserExports(userId)
+	if err != nil {
+		return dto.ErasureReceipt{}, err
+	}
+
+	erasedAt := time.Now().UTC().Truncate(time.Second)
+	receipt := dto.ErasureReceipt{
+		Id:       receiptId,
+		Subject:  e.subject(userId),
+		ErasedAt: erasedAt.Format(time.RFC3339),
+		Deleted: map[string]int64{
+			"account":        1,
+			"photos":         int64(photos),
+			"photo_versions": count.PhotoVersions,
+			"likes":          count.Likes,
+			"comments":       count.Comments,
+			"follows":        count.Follows,
+			"blocks":         count.Blocks,
+			"mutes":          count.Mutes,
+			"uploads":        int64(uploads),
+			"exports":        int64(exports),
+		},
+		Anonymized: map[string]int64{
+			"reports_filed":    count.ReportsFiled,
+			"reports_received": count.ReportsReceived,
+			"audit_events":     count.AuditEvents,
+			"comments_replied": count.RepliedComments,
+		},
+	}
+
+	payload, err := json.Marshal(receipt)
+	if err != nil {
+		return dto.ErasureReceipt{}, err
+	}
+
+	receipt.Signature = e.receiptSigner.Sign(payload)
+
+	// the pseudonym is not kept anywhere, the anonymized rows cannot be tied back to the user or the receipt
+	pseudonym := "erased-" + uuid.NewString()
+	err = e.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		err := e.userRepository.WithTx(tx).Lock(userId)
+		if err != nil {
+			return err
+		}
+
+		// the user may have reactivated the account between the purge listing it and the suspension
+		current, err := e.userRepository.WithTx(tx).GetById(userId)
+		if err != nil {
+			return err
+		}
+
+		if !current.DeactivatedAt.Valid {
+			return fmt.Errorf("account was reactivated : %w", exception.ConflictErr)
+		}
+
+		// a request authorized before the suspension may have stored a file since the purge,
+		// deleting the user now would leave its blob referenced, so the erasure is run again
+		left, err := e.personalDataRepository.WithTx(tx).Count(userId)
+		if err != nil {
+			return err
+		}
+
+		if left.Photos > 0 || left.Uploads > 0 || left.Exports > 0 {
+			return fmt.Errorf("files were stored during the erasure : %w", exception.ConflictErr)
+		}
+
+		err = e.personalDataRepository.WithTx(tx).Anonymize(userId, pseudonym)
+		if err != nil {
+			return err
+		}
+
+		err = e.userRepository.WithTx(tx).Delete(userId)
+		if err != nil {
+			return err
+		}
+
+		return e.erasureReceiptRepository.WithTx(tx).Insert(entity.ErasureReceipt{
+			Id:        receipt.Id,
+			Subject:   receipt.Subject,
+			Receipt:   string(payload),
+			Signature: receipt.Signature,
+			ErasedAt:  erasedAt,
+		})
+	})
+	if err != nil {
+		return dto.ErasureReceipt{}, err
+	}
+
+	return receipt, nil
+}
+
+// GetReceipt returns the signed receipt of an erasure, it is not found until the account is gone.
+func (e *erasureUCImpl) GetReceipt(receiptId string) (dto.ErasureReceipt, error) {
+	stored, err := e.erasureReceiptRepository.FindById(receiptId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.ErasureReceipt{}, fmt.Errorf("GetReceiptUC : %w", exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return dto.ErasureReceipt{}, fmt.Errorf("GetReceiptUC : %w", err)
+	}
+
+	var receipt dto.ErasureReceipt
+	err = json.Unmarshal([]byte(stored.Receipt), &receipt)
+	if err != nil {
+		return dto.ErasureReceipt{}, fmt.Errorf("GetReceiptUC : %w", err)
+	}
+	receipt.Signature = stored.Signature
+
+	return receipt, nil
+}
+
+// VerifyReceipt checks the signature of the receipt and that it was recorded when the
+// account was erased.
+func (e *erasureUCImpl) VerifyReceipt(receipt dto.ErasureReceipt) (dto.ErasureReceiptVerification, error) {
+	signature := receipt.Signature
+	receipt.Signature = ""
+
+	payload, err := json.Marshal(receipt)
+	if err != nil {
+		return dto.ErasureReceiptVerification{}, fmt.Errorf("VerifyReceiptUC : %w", err)
+	}
+
+	if !e.receiptSigner.Verify(payload, signature) {
+		return dto.ErasureReceiptVerification{Id: receipt.Id}, nil
+	}
+
+	stored, err := e.erasureReceiptRepository.FindById(receipt.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.ErasureReceiptVerification{Id: receipt.Id}, nil
+	}
+
+	if err != nil {
+		return dto.ErasureReceiptVerification{}, fmt.Errorf("VerifyReceiptUC : %w", err)
+	}
+
+	return dto.ErasureReceiptVerification{Id: receipt.Id, Valid: stored.Signature == signature}, nil
+}
+
+// subject is the hex sha-256 of the user id.
+func (e *erasureUCImpl) subject(userId string) string {
+	sum := sha256.Sum256([]byte(userId))
+	return hex.EncodeToString(sum[:])
+}
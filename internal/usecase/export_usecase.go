@@ -0,0 +1,433 @@
+package usecase
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"net/url"
+	"path/filepath"
+	"time"
+	"user-personalize/internal/config"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/mapping"
+	"user-personalize/pkg/util/storage"
+)
+
+const (
+	exportsPrefix = "exports/"
+	// exportStaleAfter is how long a running export may take before another worker takes it over.
+	exportStaleAfter = 30 * time.Minute
+)
+
+// ExportUC packs the original files of a user's photo, its earlier versions and a
+// metadata.json into a zip archive. Photos in the trash are left out.
+//
+// It also writes the personal data export, a json document of everything stored about
+// the user which is always built in the background.
+type ExportUC interface {
+	ExportPhotos(userId string) (dto.PhotoExport, error)
+	ExportPersonalData(userId string) (dto.ExportResponse, error)
+	GetExport(userId string, kind string, exportId string) (dto.ExportResponse, error)
+	GetExportContent(userId string, kind string, exportId string) (dto.PhotoContent, error)
+	DiscardUserExports(userId string) (int, error)
+	RunWorker()
+}
+
+type exportUCImpl struct {
+	exportRepository       repository.ExportRepository
+	photosRepository       repository.PhotosRepository
+	versionRepository      repository.PhotoVersionRepository
+	personalDataRepository repository.PersonalDataRepository
+	blobStore              storage.BlobStore
+	baseUrl                string
+	cfg                    config.ExportConfig
+}
+
+func NewExportUC(exportRepository repository.ExportRepository, photosRepository repository.PhotosRepository, versionRepository repository.PhotoVersionRepository,
+	personalDataRepository repository.PersonalDataRepository, blobStore storage.BlobStore, baseUrl string, cfg config.ExportConfig) ExportUC {
+	return &exportUCImpl{
+		exportRepository:       exportRepository,
+		photosRepository:       photosRepository,
+		versionRepository:      versionRepository,
+		personalDataRepository: personalDataRepository,
+		blobStore:              blobStore,
+		baseUrl:                baseUrl,
+		cfg:                    cfg,
+	}
+}
+
+type exportFile struct {
+	Key      string
+	Metadata dto.ExportPhotoMetadata
+}
+
+// ExportPhotos streams the archive while it is written when the files add up to less
+// than cfg.AsyncThreshold. Larger exports are queued, a user has at most one at a time.
+func (e *exportUCImpl) ExportPhotos(userId string) (dto.PhotoExport, error) {
+	files, size, err := e.collect(userId)
+	if err != nil {
+		return dto.PhotoExport{}, fmt.Errorf("ExportPhotosUC : %w", err)
+	}
+
+	if size <= e.cfg.AsyncThreshold {
+		reader, writer := io.Pipe()
+		go func() {
+			writer.CloseWithError(e.write(writer, userId, files))
+		}()
+
+		return dto.PhotoExport{Archive: reader, Filename: e.filename(userId)}, nil
+	}
+
+	export, err := e.queue(userId, entity.ExportKindPhotos)
+	if err != nil {
+		return dto.PhotoExport{}, fmt.Errorf("ExportPhotosUC : %w", err)
+	}
+
+	response := e.response(export)
+	return dto.PhotoExport{Job: &response}, nil
+}
+
+// ExportPersonalData queues the personal data export of the user, or returns the one
+// that is already waiting.
+func (e *exportUCImpl) ExportPersonalData(userId string) (dto.ExportResponse, error) {
+	export, err := e.queue(userId, entity.ExportKindPersonalData)
+	if err != nil {
+		return dto.ExportResponse{}, fmt.Errorf("ExportPersonalDataUC : %w", err)
+	}
+
+	return e.response(export), nil
+}
+
+func (e *exportUCImpl) GetExport(userId string, kind string, exportId string) (dto.ExportResponse, error) {
+	export, err := e.find(userId, kind, exportId)
+	if err != nil {
+		return dto.ExportResponse{}, fmt.Errorf("GetExportUC : %w", err)
+	}
+
+	return e.response(export), nil
+}
+
+func (e *exportUCImpl) GetExportContent(userId string, kind string, exportId string) (dto.PhotoContent, error) {
+	export, err := e.find(userId, kind, exportId)
+	if err != nil {
+		return dto.PhotoContent{}, fmt.Errorf("GetExportContentUC : %w", err)
+	}
+
+	if export.Status != entity.ExportDone {
+		return dto.PhotoContent{}, fmt.Errorf("GetExportContentUC : export is %s : %w", export.Status, exception.ConflictErr)
+	}
+
+	if export.ExpiresAt.Time.Before(time.Now()) {
+		return dto.PhotoContent{}, fmt.Errorf("GetExportContentUC : %w", exception.ExpiredErr)
+	}
+
+	content, info, err := e.blobStore.Get(export.ObjectKey)
+	if err != nil {
+		return dto.PhotoContent{}, fmt.Errorf("GetExportContentUC : %w", err)
+	}
+
+	_, contentType := e.format(export.Kind)
+	return dto.PhotoContent{
+		Content:      content,
+		ContentType:  contentType,
+		Size:         info.Size,
+		ETag:         fmt.Sprintf("\"%s\"", export.Id),
+		LastModified: info.LastModified,
+	}, nil
+}
+
+// DiscardUserExports removes every export of a user and its file, it returns how many it removed.
+func (e *exportUCImpl) DiscardUserExports(userId string) (int, error) {
+	exports, err := e.exportRepository.FindByUserId(userId)
+	if err != nil {
+		return 0, fmt.Errorf("DiscardUserExportsUC : %w", err)
+	}
+
+	for _, export := range exports {
+		if export.ObjectKey != "" {
+			e.discard(export.ObjectKey)
+		}
+
+		err = e.exportRepository.Delete(export.Id)
+		if err != nil {
+			return 0, fmt.Errorf("DiscardUserExportsUC : %w", err)
+		}
+	}
+
+	return len(exports), nil
+}
+
+// RunWorker builds queued exports and removes expired ones every cfg.WorkerInterval,
+// it is meant to be started in its own goroutine.
+func (e *exportUCImpl) RunWorker() {
+	if e.cfg.WorkerInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.cfg.WorkerInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		e.expire()
+
+		for {
+			export, err := e.exportRepository.ClaimNext(time.Now().Add(-exportStaleAfter))
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+
+			if err != nil {
+				log.Println(err)
+				break
+			}
+
+			e.build(export)
+		}
+	}
+}
+
+// build writes the file of a claimed export to storage, piping it so that it is never held in memory.
+func (e *exportUCImpl) build(export entity.Export) {
+	extension, contentType := e.format(export.Kind)
+	key := exportsPrefix + export.Id + extension
+	expiresAt := time.Now().Add(e.cfg.ExpiredTime)
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(e.produce(writer, export))
+	}()
+
+	info, err := e.blobStore.Put(key, reader, -1, contentType)
+	reader.CloseWithError(err)
+	if err == nil {
+		err = e.exportRepository.Complete(export.Id, key, info.Size, expiresAt)
+	}
+
+	if err != nil {
+		log.Println(fmt.Errorf("ExportWorker : export %s : %w", export.Id, err))
+		e.discard(key)
+
+		err = e.exportRepository.Fail(export.Id, "the export could not be built", expiresAt)
+		if err != nil {
+			log.Println(fmt.Errorf("ExportWorker : export %s : %w", export.Id, err))
+		}
+	}
+}
+
+// expire removes exports and archives that are past their expiry.
+func (e *exportUCImpl) expire() {
+	exports, err := e.exportRepository.FindExpired(time.Now())
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, export := range exports {
+		if export.ObjectKey != "" {
+			e.discard(export.ObjectKey)
+		}
+
+		err = e.exportRepository.Delete(export.Id)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// produce writes the file of the export kind.
+func (e *exportUCImpl) produce(w io.Writer, export entity.Export) error {
+	if export.Kind == entity.ExportKindPersonalData {
+		return e.writePersonalData(w, export.UserId)
+	}
+
+	files, _, err := e.collect(export.UserId)
+	if err != nil {
+		return err
+	}
+
+	return e.write(w, export.UserId, files)
+}
+
+// collect lists the files of the export and their total size.
+func (e *exportUCImpl) collect(userId string) ([]exportFile, int64, error) {
+	photo, err := e.photosRepository.FindByUserId(userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, nil
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	versions, err := e.versionRepository.FindByPhotoId(photo.Id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	files := make([]exportFile, 0, len(versions)+1)
+	files = append(files, exportFile{Key: photo.PhotoUrl, Metadata: dto.ExportPhotoMetadata{
+		File:       "photos/" + photo.Id + filepath.Ext(photo.PhotoUrl),
+		PhotoId:    photo.Id,
+		Title:      photo.Title,
+		Caption:    photo.Caption,
+		Tags:       photo.Tags,
+		Visibility: photo.Visibility,
+		CreatedAt:  photo.CreatedAt,
+		UpdatedAt:  photo.UpdatedAt,
+	}})
+	for _, version := range versions {
+		files = append(files, exportFile{Key: version.PhotoUrl, Metadata: dto.ExportPhotoMetadata{
+			File:      "versions/" + version.Id + filepath.Ext(version.PhotoUrl),
+			PhotoId:   version.PhotoId,
+			VersionId: version.Id,
+			Title:     version.Title,
+			Caption:   version.Caption,
+			CreatedAt: version.CreatedAt,
+			UpdatedAt: version.CreatedAt,
+		}})
+	}
+
+	var size int64
+	for _, file := range files {
+		info, err := e.blobStore.Stat(file.Key)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		size += info.Size
+	}
+
+	return files, size, nil
+}
+
+// write produces the archive file by file, only one file is open at a time. Photos are
+// already compressed and are stored as they are.
+func (e *exportUCImpl) write(w io.Writer, userId string, files []exportFile) error {
+	archive := zip.NewWriter(w)
+
+	metadata := dto.ExportMetadata{UserId: userId, ExportedAt: time.Now(), Photos: make([]dto.ExportPhotoMetadata, 0, len(files))}
+	for _, file := range files {
+		err := e.writeFile(archive, file)
+		if err != nil {
+			return err
+		}
+
+		metadata.Photos = append(metadata.Photos, file.Metadata)
+	}
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: "metadata.json", Method: zip.Deflate, Modified: metadata.ExportedAt})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(metadata)
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func (e *exportUCImpl) writePersonalData(w io.Writer, userId string) error {
+	data, err := e.personalDataRepository.Find(userId)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(mapping.MapPersonalDataToExport(userId, data, time.Now()))
+}
+
+func (e *exportUCImpl) writeFile(archive *zip.Writer, file exportFile) error {
+	content, _, err := e.blobStore.Get(file.Key)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: file.Metadata.File, Method: zip.Store, Modified: file.Metadata.CreatedAt})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(entry, content)
+	return err
+}
+
+// queue returns the export of the kind that is waiting for the user or queues a new one,
+// a user has at most one of each kind at a time.
+func (e *exportUCImpl) queue(userId string, kind string) (entity.Export, error) {
+	export, err := e.exportRepository.FindActiveByUserId(userId, kind)
+	if errors.Is(err, sql.ErrNoRows) {
+		return e.exportRepository.Insert(entity.Export{Id: uuid.NewString(), UserId: userId, Kind: kind})
+	}
+
+	return export, err
+}
+
+func (e *exportUCImpl) find(userId string, kind string, exportId string) (entity.Export, error) {
+	export, err := e.exportRepository.FindById(exportId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (export.UserId != userId || export.Kind != kind)) {
+		return entity.Export{}, exception.NotFoundErr
+	}
+
+	return export, err
+}
+
+// format returns the file extension and content type of the export kind.
+func (e *exportUCImpl) format(kind string) (string, string) {
+	if kind == entity.ExportKindPersonalData {
+		return ".json", "application/json"
+	}
+
+	return ".zip", "application/zip"
+}
+
+func (e *exportUCImpl) response(export entity.Export) dto.ExportResponse {
+	response := dto.ExportResponse{
+		Id:        export.Id,
+		Status:    export.Status,
+		Size:      export.Size,
+		Error:     export.Error,
+		CreatedAt: export.CreatedAt.Format(time.RFC3339),
+	}
+
+	if export.Status == entity.ExportDone && export.Kind == entity.ExportKindPersonalData {
+		response.DownloadUrl = fmt.Sprintf("%s/users/me/data-exports/%s/download", e.baseUrl, url.PathEscape(export.Id))
+	} else if export.Status == entity.ExportDone {
+		response.DownloadUrl = fmt.Sprintf("%s/photos/exports/%s/download", e.baseUrl, url.PathEscape(export.Id))
+	}
+
+	if export.CompletedAt.Valid {
+		response.CompletedAt = export.CompletedAt.Time.Format(time.RFC3339)
+	}
+
+	if export.ExpiresAt.Valid {
+		response.ExpiresAt = export.ExpiresAt.Time.Format(time.RFC3339)
+	}
+
+	return response
+}
+
+func (e *exportUCImpl) filename(userId string) string {
+	return fmt.Sprintf("photos-%s-%s.zip", userId, time.Now().Format("20060102"))
+}
+
+// discard removes an archive, failures are only logged.
+func (e *exportUCImpl) discard(key string) {
+	err := e.blobStore.Delete(key)
+	if err != nil && !errors.Is(err, exception.NotFoundErr) {
+		log.Println("discard export", key, ":", err)
+	}
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/mapping"
+)
+
+// FollowUC manages who follows whom. Following and unfollowing are idempotent.
+// Users that block each other look missing to one another.
+type FollowUC interface {
+	Follow(userId string, targetId string) (dto.FollowResponse, error)
+	Unfollow(userId string, targetId string) (dto.FollowResponse, error)
+	GetFollowers(viewerId string, userId string, page dto.PageRequest) (dto.FollowListResponse, error)
+	GetFollowing(viewerId string, userId string, page dto.PageRequest) (dto.FollowListResponse, error)
+}
+
+type followUCImpl struct {
+	followRepository repository.FollowRepository
+	blockRepository  repository.BlockRepository
+	userRepository   repository.UserRepository
+}
+
+func NewFollowUC(followRepository repository.FollowRepository, blockRepository repository.BlockRepository, userRepository repository.UserRepository) FollowUC {
+	return &followUCImpl{followRepository: followRepository, blockRepository: blockRepository, userRepository: userRepository}
+}
+
+func (f *followUCImpl) Follow(userId string, targetId string) (dto.FollowResponse, error) {
+	if userId == targetId {
+		return dto.FollowResponse{}, fmt.Errorf("FollowUC : cannot follow yourself : %w", exception.InvalidErr)
+	}
+
+	err := f.visible(userId, targetId)
+	if err != nil {
+		return dto.FollowResponse{}, fmt.Errorf("FollowUC : %w", err)
+	}
+
+	err = f.followRepository.Follow(userId, targetId)
+	if err != nil {
+		return dto.FollowResponse{}, fmt.Errorf("FollowUC : %w", err)
+	}
+
+	return f.response(targetId, true)
+}
+
+func (f *followUCImpl) Unfollow(userId string, targetId string) (dto.FollowResponse, error) {
+	err := f.visible(userId, targetId)
+	if err != nil {
+		return dto.FollowResponse{}, fmt.Errorf("UnfollowUC : %w", err)
+	}
+
+	err = f.followRepository.Unfollow(userId, targetId)
+	if err != nil {
+		return dto.FollowResponse{}, fmt.Errorf("UnfollowUC : %w", err)
+	}
+
+	return f.response(targetId, false)
+}
+
+func (f *followUCImpl) GetFollowers(viewerId string, userId string, page dto.PageRequest) (dto.FollowListResponse, error) {
+	response, err := f.list(viewerId, userId, page, f.followRepository.FindFollowers)
+	if err != nil {
+		return dto.FollowListResponse{}, fmt.Errorf("GetFollowersUC : %w", err)
+	}
+
+	return response, nil
+}
+
+func (f *followUCImpl) GetFollowing(viewerId string, userId string, page dto.PageRequest) (dto.FollowListResponse, error) {
+	response, err := f.list(viewerId, userId, page, f.followRepository.FindFollowing)
+	if err != nil {
+		return dto.FollowListResponse{}, fmt.Errorf("GetFollowingUC : %w", err)
+	}
+
+	return response, nil
+}
+
+func (f *followUCImpl) list(viewerId string, userId string, page dto.PageRequest, find func(userId string, viewerId string, keyset entity.Keyset) ([]entity.FollowUser, error)) (dto.FollowListResponse, error) {
+	keyset, limit, err := newKeyset(page)
+	if err != nil {
+		return dto.FollowListResponse{}, err
+	}
+
+	err = f.visible(viewerId, userId)
+	if err != nil {
+		return dto.FollowListResponse{}, err
+	}
+
+	users, err := find(userId, viewerId, keyset)
+	if err != nil {
+		return dto.FollowListResponse{}, err
+	}
+
+	response := dto.FollowListResponse{Items: make([]dto.FollowUserResponse, 0, len(users)), Page: pageResponse(limit, nil)}
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[limit-1]
+		response.Page = pageResponse(limit, &pageCursor{CreatedAt: last.FollowedAt, Id: last.UserId})
+	}
+
+	for _, user := range users {
+		response.Items = append(response.Items, mapping.MapFollowUserToResponse(user))
+	}
+
+	return response, nil
+}
+
+// visible fails with exception.NotFoundErr when userId does not exist, is deactivated or is blocked with viewerId.
+func (f *followUCImpl) visible(viewerId string, userId string) error {
+	user, err := f.userRepository.GetById(userId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.DeactivatedAt.Valid) {
+		return fmt.Errorf("user %q : %w", userId, exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	blocked, err := f.blockRepository.IsBlocked(viewerId, userId)
+	if err != nil {
+		return err
+	}
+
+	if blocked {
+		return fmt.Errorf("user %q : %w", userId, exception.NotFoundErr)
+	}
+
+	return nil
+}
+
+func (f *followUCImpl) response(targetId string, following bool) (dto.FollowResponse, error) {
+	count, err := f.followRepository.CountFollowers(targetId)
+	if err != nil {
+		return dto.FollowResponse{}, err
+	}
+
+	return dto.FollowResponse{UserId: targetId, Following: following, FollowerCount: count}, nil
+}
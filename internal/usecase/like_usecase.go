@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"fmt"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/repository"
+)
+
+// LikeUC sets whether a user likes a photo. Both calls are idempotent, repeating
+// one leaves the like and the count as they are.
+type LikeUC interface {
+	LikePhoto(userId string, photoId string) (dto.LikeResponse, error)
+	UnlikePhoto(userId string, photoId string) (dto.LikeResponse, error)
+}
+
+type likeUCImpl struct {
+	likeRepository repository.LikeRepository
+	access         *photoAccess
+}
+
+func NewLikeUC(likeRepository repository.LikeRepository, photosRepository repository.PhotosRepository, followRepository repository.FollowRepository, blockRepository repository.BlockRepository) LikeUC {
+	return &likeUCImpl{likeRepository: likeRepository, access: newPhotoAccess(photosRepository, followRepository, blockRepository)}
+}
+
+func (l *likeUCImpl) LikePhoto(userId string, photoId string) (dto.LikeResponse, error) {
+	_, err := l.access.find(userId, photoId)
+	if err != nil {
+		return dto.LikeResponse{}, fmt.Errorf("LikePhotoUC : %w", err)
+	}
+
+	err = l.likeRepository.Like(photoId, userId)
+	if err != nil {
+		return dto.LikeResponse{}, fmt.Errorf("LikePhotoUC : %w", err)
+	}
+
+	return l.response(photoId, true)
+}
+
+func (l *likeUCImpl) UnlikePhoto(userId string, photoId string) (dto.LikeResponse, error) {
+	_, err := l.access.find(userId, photoId)
+	if err != nil {
+		return dto.LikeResponse{}, fmt.Errorf("UnlikePhotoUC : %w", err)
+	}
+
+	err = l.likeRepository.Unlike(photoId, userId)
+	if err != nil {
+		return dto.LikeResponse{}, fmt.Errorf("UnlikePhotoUC : %w", err)
+	}
+
+	return l.response(photoId, false)
+}
+
+func (l *likeUCImpl) response(photoId string, liked bool) (dto.LikeResponse, error) {
+	count, err := l.likeRepository.CountByPhotoId(photoId)
+	if err != nil {
+		return dto.LikeResponse{}, fmt.Errorf("LikeResponseUC : %w", err)
+	}
+
+	return dto.LikeResponse{PhotoId: photoId, Liked: liked, LikeCount: count}, nil
+}
@@ -0,0 +1,336 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"unicode/utf8"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/mapping"
+)
+
+const maxReportTextLength = 1000
+
+var reportReasons = map[string]bool{
+	entity.ReportReasonSpam:       true,
+	entity.ReportReasonNudity:     true,
+	entity.ReportReasonViolence:   true,
+	entity.ReportReasonHarassment: true,
+	entity.ReportReasonHate:       true,
+	entity.ReportReasonCopyright:  true,
+	entity.ReportReasonIllegal:    true,
+	entity.ReportReasonOther:      true,
+}
+
+var reportStatuses = map[string]bool{
+	entity.ReportStatusOpen:     true,
+	entity.ReportStatusClaimed:  true,
+	entity.ReportStatusResolved: true,
+}
+
+var resolutions = map[string]bool{
+	entity.ResolutionDismiss:     true,
+	entity.ResolutionHidePhoto:   true,
+	entity.ResolutionDeletePhoto: true,
+	entity.ResolutionSuspendUser: true,
+}
+
+// ModerationUC takes abuse reports from users and lets admins work them off: a report
+// is claimed by one admin and then resolved with an action. Claims and resolutions are
+// written to the audit trail in the same transaction as their effect.
+type ModerationUC interface {
+	ReportPhoto(userId string, photoId string, request dto.ReportRequest) (dto.ReportResponse, error)
+	GetReports(moderatorId string, status string, page dto.PageRequest) (dto.ReportListResponse, error)
+	ClaimReport(moderatorId string, reportId string) (dto.ReportResponse, error)
+	ResolveReport(moderatorId string, reportId string, request dto.ResolveReportRequest) (dto.ReportResponse, error)
+	GetActions(moderatorId string, page dto.PageRequest) (dto.ModerationActionListResponse, error)
+}
+
+type moderationUCImpl struct {
+	reportRepository repository.ReportRepository
+	actionRepository repository.ModerationActionRepository
+	photosRepository repository.PhotosRepository
+	userRepository   repository.UserRepository
+	transactor       repository.Transactor
+	access           *photoAccess
+	photosUC         PhotosUC
+}
+
+func NewModerationUC(reportRepository repository.ReportRepository, actionRepository repository.ModerationActionRepository, photosRepository repository.PhotosRepository, userRepository repository.UserRepository,
+	followRepository repository.FollowRepository, blockRepository repository.BlockRepository, transactor repository.Transactor, photosUC PhotosUC) ModerationUC {
+	return &moderationUCImpl{
+		reportRepository: reportRepository,
+		actionRepository: actionRepository,
+		photosRepository: photosRepository,
+		userRepository:   userRepository,
+		transactor:       transactor,
+		access:           newPhotoAccess(photosRepository, followRepository, blockRepository),
+		photosUC:         photosUC,
+	}
+}
+
+func (m *moderationUCImpl) ReportPhoto(userId string, photoId string, request dto.ReportRequest) (dto.ReportResponse, error) {
+	photo, err := m.access.find(userId, photoId)
+	if err != nil {
+		return dto.ReportResponse{}, fmt.Errorf("ReportPhotoUC : %w", err)
+	}
+
+	if photo.UserId == userId {
+		return dto.ReportResponse{}, fmt.Errorf("ReportPhotoUC : cannot report your own photo : %w", exception.InvalidErr)
+	}
+
+	details := strings.TrimSpace(request.Details)
+	if !reportReasons[request.Reason] || utf8.RuneCountInString(details) > maxReportTextLength {
+		return dto.ReportResponse{}, fmt.Errorf("ReportPhotoUC : reason %q : %w", request.Reason, exception.InvalidErr)
+	}
+
+	report, err := m.reportRepository.Insert(entity.Report{
+		Id:           uuid.NewString(),
+		PhotoId:      photo.Id,
+		PhotoOwnerId: photo.UserId,
+		ReporterId:   userId,
+		Reason:       request.Reason,
+		Details:      details,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.ReportResponse{}, fmt.Errorf("ReportPhotoUC : photo already reported : %w", exception.ConflictErr)
+	}
+
+	if err != nil {
+		return dto.ReportResponse{}, fmt.Errorf("ReportPhotoUC : %w", err)
+	}
+
+	return mapping.MapReportToResponse(report), nil
+}
+
+// GetReports lists the queue oldest first, status defaults to open.
+func (m *moderationUCImpl) GetReports(moderatorId string, status string, page dto.PageRequest) (dto.ReportListResponse, error) {
+	err := requireAdmin(m.userRepository, moderatorId)
+	if err != nil {
+		return dto.ReportListResponse{}, fmt.Errorf("GetReportsUC : %w", err)
+	}
+
+	if status == "" {
+		status = entity.ReportStatusOpen
+	}
+
+	if !reportStatuses[status] {
+		return dto.ReportListResponse{}, fmt.Errorf("GetReportsUC : status %q : %w", status, exception.InvalidErr)
+	}
+
+	keyset, limit, err := newKeyset(page)
+	if err != nil {
+		return dto.ReportListResponse{}, fmt.Errorf("GetReportsUC : %w", err)
+	}
+
+	reports, err := m.reportRepository.FindByStatus(status, keyset)
+	if err != nil {
+		return dto.ReportListResponse{}, fmt.Errorf("GetReportsUC : %w", err)
+	}
+
+	response := dto.ReportListResponse{Items: make([]dto.ReportResponse, 0, len(reports)), Page: pageResponse(limit, nil)}
+	if len(reports) > limit {
+		reports = reports[:limit]
+		last := reports[limit-1]
+		response.Page = pageResponse(limit, &pageCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+
+	for _, report := range reports {
+		response.Items = append(response.Items, mapping.MapReportToResponse(report))
+	}
+
+	return response, nil
+}
+
+// ClaimReport assigns an open report to moderatorId, a report claimed by someone else is a conflict.
+func (m *moderationUCImpl) ClaimReport(moderatorId string, reportId string) (dto.ReportResponse, error) {
+	err := requireAdmin(m.userRepository, moderatorId)
+	if err != nil {
+		return dto.ReportResponse{}, fmt.Errorf("ClaimReportUC : %w", err)
+	}
+
+	var report entity.Report
+	err = m.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		var err error
+		report, err = m.reportRepository.WithTx(tx).Claim(reportId, moderatorId)
+		if err != nil {
+			return err
+		}
+
+		return m.actionRepository.WithTx(tx).Insert(entity.ModerationAction{
+			Id:       uuid.NewString(),
+			ActorId:  moderatorId,
+			Action:   entity.ActionClaim,
+			ReportId: report.Id,
+			PhotoId:  report.PhotoId,
+			UserId:   report.PhotoOwnerId,
+		})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		_, findErr := m.reportRepository.FindById(reportId)
+		if findErr != nil {
+			return dto.ReportResponse{}, fmt.Errorf("ClaimReportUC : %w", exception.NotFoundErr)
+		}
+
+		return dto.ReportResponse{}, fmt.Errorf("ClaimReportUC : report is not open : %w", exception.ConflictErr)
+	}
+
+	if err != nil {
+		return dto.ReportResponse{}, fmt.Errorf("ClaimReportUC : %w", err)
+	}
+
+	return mapping.MapReportToResponse(report), nil
+}
+
+// ResolveReport applies the action to the photo of a report moderatorId claimed and resolves
+// every unresolved report on that photo. A deleted photo is hidden within the transaction,
+// so it is gone for everyone even when removing its file fails afterwards.
+func (m *moderationUCImpl) ResolveReport(moderatorId string, reportId string, request dto.ResolveReportRequest) (dto.ReportResponse, error) {
+	err := requireAdmin(m.userRepository, moderatorId)
+	if err != nil {
+		return dto.ReportResponse{}, fmt.Errorf("ResolveReportUC : %w", err)
+	}
+
+	note := strings.TrimSpace(request.Note)
+	if !resolutions[request.Action] || utf8.RuneCountInString(note) > maxReportTextLength {
+		return dto.ReportResponse{}, fmt.Errorf("ResolveReportUC : action %q : %w", request.Action, exception.InvalidErr)
+	}
+
+	var report entity.Report
+	var photo entity.Photos
+	var photoExists bool
+	err = m.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		var err error
+		// the claim is checked on the locked row, a claim released or taken over
+		// after an unlocked read must not be resolved by the previous moderator
+		report, err = m.reportRepository.WithTx(tx).FindByIdForUpdate(reportId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return exception.NotFoundErr
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if report.Status != entity.ReportStatusClaimed || report.ClaimedBy != moderatorId {
+			return fmt.Errorf("report is not claimed by you : %w", exception.ConflictErr)
+		}
+
+		// the owner may have moved the photo to the trash or had it purged since it was reported,
+		// a decision on a trashed photo still applies when it is restored
+		photosRepository := m.photosRepository.WithTx(tx)
+		photo, err = photosRepository.FindById(report.PhotoId)
+		if errors.Is(err, sql.ErrNoRows) {
+			photo, err = photosRepository.FindTrashById(report.PhotoId)
+		}
+
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		photoExists = err == nil
+
+		err = nil
+		switch request.Action {
+		case entity.ResolutionDismiss:
+			// dismissing releases a photo the classifier flagged, not one a moderator hid
+			if photoExists && photo.ModerationStatus == entity.ModerationFlagged {
+				err = photosRepository.UpdateModerationStatus(photo.Id, entity.ModerationVisible)
+			}
+		case entity.ResolutionHidePhoto, entity.ResolutionDeletePhoto:
+			if photoExists {
+				err = photosRepository.UpdateModerationStatus(photo.Id, entity.ModerationHidden)
+			}
+		case entity.ResolutionSuspendUser:
+			err = m.userRepository.WithTx(tx).Suspend(report.PhotoOwnerId)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = m.reportRepository.WithTx(tx).ResolveByPhotoId(report.PhotoId, moderatorId, request.Action)
+		if err != nil {
+			return err
+		}
+
+		return m.actionRepository.WithTx(tx).Insert(entity.ModerationAction{
+			Id:       uuid.NewString(),
+			ActorId:  moderatorId,
+			Action:   request.Action,
+			ReportId: report.Id,
+			PhotoId:  report.PhotoId,
+			UserId:   report.PhotoOwnerId,
+			Note:     note,
+		})
+	})
+	if err != nil {
+		return dto.ReportResponse{}, fmt.Errorf("ResolveReportUC : %w", err)
+	}
+
+	if request.Action == entity.ResolutionDeletePhoto && photoExists && !photo.DeletedAt.Valid {
+		err = m.photosUC.DeletePhotos(entity.Photos{Id: photo.Id, UserId: photo.UserId})
+		if err != nil {
+			return dto.ReportResponse{}, fmt.Errorf("ResolveReportUC : %w", err)
+		}
+	}
+
+	report, err = m.reportRepository.FindById(reportId)
+	if err != nil {
+		return dto.ReportResponse{}, fmt.Errorf("ResolveReportUC : %w", err)
+	}
+
+	return mapping.MapReportToResponse(report), nil
+}
+
+func (m *moderationUCImpl) GetActions(moderatorId string, page dto.PageRequest) (dto.ModerationActionListResponse, error) {
+	err := requireAdmin(m.userRepository, moderatorId)
+	if err != nil {
+		return dto.ModerationActionListResponse{}, fmt.Errorf("GetActionsUC : %w", err)
+	}
+
+	keyset, limit, err := newKeyset(page)
+	if err != nil {
+		return dto.ModerationActionListResponse{}, fmt.Errorf("GetActionsUC : %w", err)
+	}
+
+	actions, err := m.actionRepository.FindAll(keyset)
+	if err != nil {
+		return dto.ModerationActionListResponse{}, fmt.Errorf("GetActionsUC : %w", err)
+	}
+
+	response := dto.ModerationActionListResponse{Items: make([]dto.ModerationActionResponse, 0, len(actions)), Page: pageResponse(limit, nil)}
+	if len(actions) > limit {
+		actions = actions[:limit]
+		last := actions[limit-1]
+		response.Page = pageResponse(limit, &pageCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+
+	for _, action := range actions {
+		response.Items = append(response.Items, mapping.MapModerationActionToResponse(action))
+	}
+
+	return response, nil
+}
+
+// requireAdmin reads the role from the database rather than from the token, so a revoked
+// role takes effect immediately.
+func requireAdmin(userRepository repository.UserRepository, userId string) error {
+	user, err := userRepository.GetById(userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return exception.ForbiddenErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if user.Role != entity.RoleAdmin {
+		return exception.ForbiddenErr
+	}
+
+	return nil
+}
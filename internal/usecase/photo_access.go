@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"log"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+)
+
+// photoAccess is shared by the usecases that act on a photo on behalf of a viewer,
+// so likes and comments follow the same visibility rules as the photo itself.
+type photoAccess struct {
+	photosRepository repository.PhotosRepository
+	followRepository repository.FollowRepository
+	blockRepository  repository.BlockRepository
+}
+
+func newPhotoAccess(photosRepository repository.PhotosRepository, followRepository repository.FollowRepository, blockRepository repository.BlockRepository) *photoAccess {
+	return &photoAccess{photosRepository: photosRepository, followRepository: followRepository, blockRepository: blockRepository}
+}
+
+// canView is the single place that decides who sees a photo, repository visibleTo
+// is its SQL counterpart. Photos taken down by moderation are seen by their owner only,
+// and users that block each other never see each other's photos.
+// When a relationship cannot be checked the photo stays hidden.
+func (a *photoAccess) canView(viewerId string, photo entity.Photos) bool {
+	if viewerId != "" && viewerId == photo.UserId {
+		return true
+	}
+
+	if !a.shareable(photo) {
+		return false
+	}
+
+	if viewerId != "" {
+		blocked, err := a.blockRepository.IsBlocked(viewerId, photo.UserId)
+		if err != nil {
+			log.Println(err)
+			return false
+		}
+
+		if blocked {
+			return false
+		}
+	}
+
+	switch photo.Visibility {
+	case entity.VisibilityPublic:
+		return true
+	case entity.VisibilityFollowers:
+		if viewerId == "" {
+			return false
+		}
+
+		following, err := a.followRepository.IsFollowing(viewerId, photo.UserId)
+		if err != nil {
+			log.Println(err)
+			return false
+		}
+
+		return following
+	default:
+		return false
+	}
+}
+
+// shareable reports whether the photo may be seen by anyone but its owner at all,
+// signed links bypass the relationship checks but not moderation or deactivation.
+func (a *photoAccess) shareable(photo entity.Photos) bool {
+	return photo.ModerationStatus == entity.ModerationVisible && !photo.OwnerInactive
+}
+
+// find returns the photo when viewerId may see it. Hidden photos look exactly like missing ones.
+func (a *photoAccess) find(viewerId string, photoId string) (entity.Photos, error) {
+	photo, err := a.photosRepository.FindById(photoId)
+	if err != nil || !a.canView(viewerId, photo) {
+		return entity.Photos{}, exception.NotFoundErr
+	}
+
+	return photo, nil
+}
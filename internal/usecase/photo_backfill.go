@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"user-personalize/internal/model/entity"
+)
+
+// analysisBackfillBatch is the number of photos without analysis read per query.
+const analysisBackfillBatch = 100
+
+// RunAnalysisBackfill analyzes the photos stored before their hash and placeholders were
+// computed on upload, once per start. It is meant to be started in its own goroutine,
+// after RunLegacyMigration since it reads the files the migration moves.
+func (p *photosUCImpl) RunAnalysisBackfill() {
+	analyzed, err := p.BackfillAnalysis()
+	if err != nil {
+		log.Println(err)
+	}
+
+	if analyzed > 0 {
+		log.Printf("analysis backfill : analyzed %d photos", analyzed)
+	}
+}
+
+// BackfillAnalysis stores the analysis of every photo that has none. A file that cannot
+// be decoded is marked so that it is not tried again, a file that cannot be read is left
+// for the next start.
+func (p *photosUCImpl) BackfillAnalysis() (int, error) {
+	analyzed := 0
+	afterId := ""
+	for {
+		photos, err := p.photosRepository.FindUnanalyzed(afterId, analysisBackfillBatch)
+		if err != nil {
+			return analyzed, fmt.Errorf("BackfillAnalysisUC : %w", err)
+		}
+
+		for _, photo := range photos {
+			err = p.backfill(photo)
+			if err != nil {
+				log.Println("BackfillAnalysisUC", photo.Id, ":", err)
+				continue
+			}
+			analyzed++
+		}
+
+		if len(photos) < analysisBackfillBatch {
+			return analyzed, nil
+		}
+		afterId = photos[len(photos)-1].Id
+	}
+}
+
+func (p *photosUCImpl) backfill(photo entity.Photos) error {
+	content, _, err := p.blobStore.Get(photo.PhotoUrl)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	analyzed, err := p.analyzeContent(content, photo)
+	if err != nil {
+		markErr := p.photosRepository.MarkAnalysisFailed(photo.Id)
+		if markErr != nil {
+			log.Println("BackfillAnalysisUC", photo.Id, ":", markErr)
+		}
+		return err
+	}
+
+	return p.photosRepository.UpdateAnalysis(analyzed)
+}
@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"fmt"
+	"user-personalize/internal/model/dto"
+	"user-personalize/pkg/util/mapping"
+)
+
+// GetFeed returns the photos of the users userId follows, newest first. Private photos
+// never show up, followers-only ones do since userId follows their owner.
+func (p *photosUCImpl) GetFeed(userId string, page dto.PageRequest) (dto.FeedResponse, error) {
+	keyset, limit, err := newKeyset(page)
+	if err != nil {
+		return dto.FeedResponse{}, fmt.Errorf("GetFeedUC : %w", err)
+	}
+
+	photos, err := p.photosRepository.FindFeed(userId, keyset)
+	if err != nil {
+		return dto.FeedResponse{}, fmt.Errorf("GetFeedUC : %w", err)
+	}
+
+	response := dto.FeedResponse{Items: make([]dto.PhotosResponse, 0, len(photos)), Page: pageResponse(limit, nil)}
+	if len(photos) > limit {
+		photos = photos[:limit]
+		last := photos[limit-1]
+		response.Page = pageResponse(limit, &pageCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+
+	for _, photo := range photos {
+		response.Items = append(response.Items, mapping.MapPhotosToResponse(photo))
+	}
+
+	return response, nil
+}
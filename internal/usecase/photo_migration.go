@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+)
+
+// legacyMigrationBatch is the number of legacy photos read per query.
+const legacyMigrationBatch = 100
+
+// RunLegacyMigration moves the files of photos stored before content addressing into blobs,
+// once per start. It is meant to be started in its own goroutine.
+func (p *photosUCImpl) RunLegacyMigration() {
+	migrated, err := p.MigrateLegacyPhotos()
+	if err != nil {
+		log.Println(err)
+	}
+
+	if migrated > 0 {
+		log.Printf("legacy migration : moved %d photos to blobs", migrated)
+	}
+}
+
+// MigrateLegacyPhotos copies every legacy file into a blob and points its photo at it, so
+// that it is served, versioned and reconciled like any other. A photo that fails is left
+// as it is and tried again on the next start, the rest go on.
+func (p *photosUCImpl) MigrateLegacyPhotos() (int, error) {
+	migrated := 0
+	afterId := ""
+	for {
+		photos, err := p.photosRepository.FindLegacy(afterId, legacyMigrationBatch)
+		if err != nil {
+			return migrated, fmt.Errorf("MigrateLegacyPhotosUC : %w", err)
+		}
+
+		for _, photo := range photos {
+			err = p.migrateLegacy(photo)
+			if err != nil {
+				log.Println("MigrateLegacyPhotosUC", photo.Id, ":", err)
+				continue
+			}
+			migrated++
+		}
+
+		if len(photos) < legacyMigrationBatch {
+			return migrated, nil
+		}
+		afterId = photos[len(photos)-1].Id
+	}
+}
+
+// migrateLegacy follows the stage, commit, promote order of SavePhotos. The legacy file
+// is only removed once the blob holds its content.
+func (p *photosUCImpl) migrateLegacy(photo entity.Photos) error {
+	content, size, err := p.blobs.openLegacy(photo.PhotoUrl)
+	if err != nil {
+		return err
+	}
+
+	staged, err := p.blobs.stage(dto.PhotoFile{Content: content, Size: size})
+	content.Close()
+	if err != nil {
+		return err
+	}
+
+	legacySize := p.quota.legacySize(photo)
+
+	var blob entity.Blob
+	var created bool
+	err = p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		blob, created, err = p.blobs.acquire(tx, staged)
+		if err != nil {
+			return err
+		}
+
+		err = p.photosRepository.WithTx(tx).AdoptBlob(photo.Id, photo.PhotoUrl, blob)
+		if err != nil {
+			return err
+		}
+
+		return p.quota.rebase(tx, photo.UserId, legacySize, blob.Size)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// the photo was replaced or removed meanwhile, its file went with it
+		p.blobs.discard(staged.Key)
+		return nil
+	}
+
+	if err != nil {
+		p.blobs.discard(staged.Key)
+		return err
+	}
+
+	err = p.blobs.settle(staged, blob, created)
+	if err != nil {
+		compensateErr := p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+			// a photo that moved on from the blob meanwhile has released it already
+			err := p.photosRepository.WithTx(tx).RevertBlob(photo.Id, blob.Hash, photo.PhotoUrl)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			err = p.quota.rebase(tx, photo.UserId, blob.Size, legacySize)
+			if err != nil {
+				return err
+			}
+
+			_, _, err = p.blobs.release(tx, blob.Hash)
+			return err
+		})
+		if compensateErr != nil {
+			log.Println("MigrateLegacyPhotosUC compensation :", compensateErr)
+		}
+		p.blobs.discard(staged.Key)
+		return err
+	}
+
+	p.blobs.discardWithVariants(photo.PhotoUrl)
+	return nil
+}
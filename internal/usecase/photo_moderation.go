@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"user-personalize/internal/model/entity"
+	"user-personalize/pkg/util/service"
+)
+
+// classify runs the content classifier over a staged upload. A classifier that fails
+// flags the photo, so nothing reaches other users unchecked.
+func (p *photosUCImpl) classify(staged stagedBlob) service.Classification {
+	content, _, err := p.blobStore.Get(staged.Key)
+	if err != nil {
+		log.Println("classify photo", staged.Key, ":", err)
+		return service.Classification{Flagged: true, Reason: entity.ReportReasonOther, Note: "content could not be classified"}
+	}
+	defer content.Close()
+
+	classification, err := p.classifier.Classify(content, staged.ContentType)
+	if err != nil {
+		log.Println("classify photo", staged.Key, ":", err)
+		return service.Classification{Flagged: true, Reason: entity.ReportReasonOther, Note: "content could not be classified"}
+	}
+
+	return classification
+}
+
+// flag files a report for a photo the classifier flagged and keeps the photo from other
+// users until a moderator resolves it. A photo a moderator hid already stays hidden.
+func (p *photosUCImpl) flag(tx *sql.Tx, photo entity.Photos, classification service.Classification) (entity.Photos, error) {
+	if !classification.Flagged {
+		return photo, nil
+	}
+
+	reason := classification.Reason
+	if !reportReasons[reason] {
+		reason = entity.ReportReasonOther
+	}
+
+	if photo.ModerationStatus == entity.ModerationVisible {
+		err := p.photosRepository.WithTx(tx).UpdateModerationStatus(photo.Id, entity.ModerationFlagged)
+		if err != nil {
+			return entity.Photos{}, fmt.Errorf("flag photo : %w", err)
+		}
+		photo.ModerationStatus = entity.ModerationFlagged
+	}
+
+	report, err := p.reportRepository.WithTx(tx).Insert(entity.Report{
+		Id:           uuid.NewString(),
+		PhotoId:      photo.Id,
+		PhotoOwnerId: photo.UserId,
+		Reason:       reason,
+		Details:      classification.Note,
+	})
+	if err != nil {
+		return entity.Photos{}, fmt.Errorf("flag photo : %w", err)
+	}
+
+	err = p.actionRepository.WithTx(tx).Insert(entity.ModerationAction{
+		Id:       uuid.NewString(),
+		Action:   entity.ActionAutoFlag,
+		ReportId: report.Id,
+		PhotoId:  photo.Id,
+		UserId:   photo.UserId,
+		Note:     classification.Note,
+	})
+	if err != nil {
+		return entity.Photos{}, fmt.Errorf("flag photo : %w", err)
+	}
+
+	return photo, nil
+}
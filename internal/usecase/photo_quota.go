@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"user-personalize/internal/config"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/storage"
+)
+
+// photoQuota charges stored files to their owner's usage and enforces the quota of the
+// owner's plan. Every reference is charged the full size of its blob, even when the
+// content is shared with another photo.
+type photoQuota struct {
+	usageRepository repository.UsageRepository
+	userRepository  repository.UserRepository
+	blobStore       storage.BlobStore
+	cfg             config.QuotaConfig
+}
+
+func newPhotoQuota(usageRepository repository.UsageRepository, userRepository repository.UserRepository, blobStore storage.BlobStore, cfg config.QuotaConfig) *photoQuota {
+	return &photoQuota{usageRepository: usageRepository, userRepository: userRepository, blobStore: blobStore, cfg: cfg}
+}
+
+// limit returns the quota of the plan that goes with the user's role.
+func (q *photoQuota) limit(userId string) (config.Quota, error) {
+	user, err := q.userRepository.GetById(userId)
+	if err != nil {
+		return config.Quota{}, err
+	}
+
+	return q.plan(user.Role), nil
+}
+
+// plan returns the quota of role. A role without a plan of its own gets the one of
+// regular users rather than no limit at all.
+func (q *photoQuota) plan(role string) config.Quota {
+	if quota, ok := q.cfg.Plans[role]; ok {
+		return quota
+	}
+
+	return q.cfg.Plans[entity.RoleUser]
+}
+
+// usage returns the counters of the user, zero when nothing was charged yet.
+func (q *photoQuota) usage(userId string) (entity.Usage, error) {
+	usage, err := q.usageRepository.FindByUserId(userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Usage{UserId: userId}, nil
+	}
+
+	return usage, err
+}
+
+// check rejects a file of size bytes before it is stored, charge has the final say.
+func (q *photoQuota) check(userId string, size int64) error {
+	limit, err := q.limit(userId)
+	if err != nil {
+		return err
+	}
+
+	usage, err := q.usage(userId)
+	if err != nil {
+		return err
+	}
+
+	return q.enforce(limit, entity.Usage{Bytes: usage.Bytes + size, PhotoCount: usage.PhotoCount + 1}, size)
+}
+
+// charge adds a stored file to the usage of the user and fails, rolling back tx, when
+// that exceeds the quota.
+func (q *photoQuota) charge(tx *sql.Tx, userId string, size int64) error {
+	limit, err := q.limit(userId)
+	if err != nil {
+		return err
+	}
+
+	usage, err := q.add(tx, userId, size, 1)
+	if err != nil {
+		return err
+	}
+
+	return q.enforce(limit, usage, size)
+}
+
+// recharge puts back a charge that was refunded earlier in the same change, when the
+// change is undone. The quota is not enforced, the file was stored all along.
+func (q *photoQuota) recharge(tx *sql.Tx, userId string, bytes int64, photos int) error {
+	if bytes == 0 && photos == 0 {
+		return nil
+	}
+
+	_, err := q.add(tx, userId, bytes, photos)
+	return err
+}
+
+// refund takes photos files of bytes in total off the usage of the user.
+func (q *photoQuota) refund(tx *sql.Tx, userId string, bytes int64, photos int) error {
+	if bytes == 0 && photos == 0 {
+		return nil
+	}
+
+	_, err := q.add(tx, userId, -bytes, -photos)
+	return err
+}
+
+// rebase replaces a charge of from bytes by one of to bytes for a file that stays stored,
+// e.g. when a legacy file moves to a blob. The quota is not enforced, nothing new is stored.
+func (q *photoQuota) rebase(tx *sql.Tx, userId string, from int64, to int64) error {
+	if from == to {
+		return nil
+	}
+
+	_, err := q.add(tx, userId, to-from, 0)
+	return err
+}
+
+// add changes the counters of the user. A counter that would drop below zero means
+// the usage has drifted from what is stored, it is logged so that it gets recalculated.
+func (q *photoQuota) add(tx *sql.Tx, userId string, bytes int64, photos int) (entity.Usage, error) {
+	usage, err := q.usageRepository.WithTx(tx).Add(userId, bytes, photos)
+	if err != nil {
+		return entity.Usage{}, err
+	}
+
+	if usage.Underflow {
+		log.Printf("usage of user %s : a change of %d bytes, %d photos went below zero and was kept at zero, recalculate usage", userId, bytes, photos)
+	}
+
+	return usage, nil
+}
+
+// legacySize is the size of a file stored before content addressing, which has no blob
+// row to read it from. Files with a blob and files that are gone count zero.
+func (q *photoQuota) legacySize(photo entity.Photos) int64 {
+	if photo.BlobHash != "" {
+		return 0
+	}
+
+	info, err := q.blobStore.Stat(photo.PhotoUrl)
+	if err != nil {
+		return 0
+	}
+
+	return info.Size
+}
+
+func (q *photoQuota) enforce(limit config.Quota, usage entity.Usage, size int64) error {
+	if limit.MaxBytes > 0 && size > limit.MaxBytes {
+		return fmt.Errorf("file of %d bytes is larger than the quota of %d bytes : %w", size, limit.MaxBytes, exception.TooLargeErr)
+	}
+
+	if limit.MaxBytes > 0 && usage.Bytes > limit.MaxBytes {
+		return fmt.Errorf("%d of %d bytes used : %w", usage.Bytes, limit.MaxBytes, exception.QuotaErr)
+	}
+
+	if limit.MaxPhotos > 0 && usage.PhotoCount > limit.MaxPhotos {
+		return fmt.Errorf("%d of %d photos stored : %w", usage.PhotoCount, limit.MaxPhotos, exception.QuotaErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/mapping"
+)
+
+const (
+	maxTagsPerPhoto = 10
+	maxTagLength    = 32
+	maxSearchLength = 200
+)
+
+// SearchPhotos runs a full-text query over titles and captions, narrowed down to the
+// given tags, over the photos viewerId may see.
+func (p *photosUCImpl) SearchPhotos(viewerId string, request dto.PhotoSearchRequest) (dto.PhotoSearchResponse, error) {
+	query := strings.TrimSpace(request.Query)
+	if utf8.RuneCountInString(query) > maxSearchLength {
+		return dto.PhotoSearchResponse{}, fmt.Errorf("SearchPhotosUC : query is longer than %d : %w", maxSearchLength, exception.InvalidErr)
+	}
+
+	tags, err := normalizeTags(request.Tags)
+	if err != nil {
+		return dto.PhotoSearchResponse{}, fmt.Errorf("SearchPhotosUC : %w", err)
+	}
+
+	if query == "" && len(tags) == 0 {
+		return dto.PhotoSearchResponse{}, fmt.Errorf("SearchPhotosUC : query or tag is required : %w", exception.InvalidErr)
+	}
+
+	limit, err := pageLimit(request.Limit)
+	if err != nil {
+		return dto.PhotoSearchResponse{}, fmt.Errorf("SearchPhotosUC : %w", err)
+	}
+
+	search := entity.PhotoSearch{ViewerId: viewerId, Query: query, Tags: tags, Limit: limit + 1}
+	if request.Cursor != "" {
+		cursor, err := decodeCursor(request.Cursor)
+		if err != nil {
+			return dto.PhotoSearchResponse{}, fmt.Errorf("SearchPhotosUC : %w", err)
+		}
+
+		search.HasCursor = true
+		search.AfterRank = cursor.Rank
+		search.AfterCreatedAt = cursor.CreatedAt
+		search.AfterId = cursor.Id
+	}
+
+	// one extra row tells whether there is a next page
+	results, err := p.photosRepository.Search(search)
+	if err != nil {
+		return dto.PhotoSearchResponse{}, fmt.Errorf("SearchPhotosUC : %w", err)
+	}
+
+	response := dto.PhotoSearchResponse{Items: make([]dto.PhotoSearchResult, 0, len(results)), Page: pageResponse(limit, nil)}
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[limit-1]
+		response.Page = pageResponse(limit, &pageCursor{Rank: last.Rank, CreatedAt: last.Photo.CreatedAt, Id: last.Photo.Id})
+	}
+
+	for _, result := range results {
+		response.Items = append(response.Items, dto.PhotoSearchResult{
+			PhotosResponse:   mapping.MapPhotosToResponse(result.Photo),
+			Rank:             result.Rank,
+			TitleHighlight:   highlight(result.TitleHighlight),
+			CaptionHighlight: highlight(result.CaptionHighlight),
+		})
+	}
+
+	return response, nil
+}
+
+// normalizeTags lower-cases tags, drops a leading '#' and joins words with '-'. Tags may
+// only hold letters, digits, '-' and '_'. A nil slice stays nil, see entity.Photos.Tags.
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))), "-")
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d : %w", tag, maxTagLength, exception.InvalidErr)
+		}
+
+		for _, r := range tag {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+				return nil, fmt.Errorf("tag %q : %w", tag, exception.InvalidErr)
+			}
+		}
+
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	if len(result) > maxTagsPerPhoto {
+		return nil, fmt.Errorf("more than %d tags : %w", maxTagsPerPhoto, exception.InvalidErr)
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+// highlight escapes user text and turns the match markers set by the repository into <mark>.
+func highlight(text string) string {
+	text = html.EscapeString(text)
+	return strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>").Replace(text)
+}
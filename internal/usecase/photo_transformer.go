@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"user-personalize/internal/config"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/imaging"
+	"user-personalize/pkg/util/storage"
+)
+
+// FormatAuto keeps the format of the source, jpeg for jpeg photos and png for the rest.
+// Clients whose Accept header lists image/webp get webp instead of png, which is lossless
+// as well and smaller. Jpeg photos stay jpeg, a lossless webp of a photo is larger.
+// Avif is not offered, there is no encoder for it without cgo.
+const FormatAuto = "auto"
+
+// Transforms are limited to these values so that the number of renditions per photo,
+// and with it the storage and cpu an anonymous loop of requests can cost, stays bounded.
+var (
+	transformWidths    = map[int]bool{100: true, 200: true, 400: true, 800: true, 1200: true, 1600: true, 2048: true}
+	transformQualities = map[int]bool{50: true, 65: true, 80: true, 90: true}
+	transformCrops     = map[string][2]int{"1:1": {1, 1}, "4:3": {4, 3}, "3:4": {3, 4}, "16:9": {16, 9}, "9:16": {9, 16}}
+	transformFormats   = map[string]bool{FormatAuto: true, imaging.FormatJpeg: true, imaging.FormatPng: true, imaging.FormatWebp: true}
+)
+
+const (
+	transformDefaultQuality = 80
+	variantQuality          = 85
+	avatarSize              = 256
+)
+
+type transformSpec struct {
+	Width   int
+	Quality int
+	Crop    string
+	Format  string
+}
+
+// key names the cached rendition, e.g. "t-w400-q80-c16x9.jpg" for a 16:9 crop scaled to 400 pixels.
+// The extension tells the formats apart, so a webp and a png rendition of a spec are both kept.
+func (t transformSpec) key() string {
+	return fmt.Sprintf("t-w%d-q%d-c%s%s", t.Width, t.Quality, strings.Replace(t.Crop, ":", "x", 1), imaging.Extension(t.Format))
+}
+
+// photoTransformer renders crops, sizes and formats of a photo on request and keeps
+// the result next to the photo's variants, so every spec is only computed once.
+type photoTransformer struct {
+	blobs     *blobManager
+	blobStore storage.BlobStore
+	cfg       config.TransformConfig
+	slots     chan struct{}
+}
+
+func newPhotoTransformer(blobs *blobManager, blobStore storage.BlobStore, cfg config.TransformConfig) *photoTransformer {
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	return &photoTransformer{blobs: blobs, blobStore: blobStore, cfg: cfg, slots: make(chan struct{}, concurrency)}
+}
+
+// parse validates the query parameters against the allow-lists. ok is false when
+// no transform was requested at all, the Accept header alone does not ask for one.
+func (t *photoTransformer) parse(key string, request dto.PhotoTransformRequest) (transformSpec, bool, error) {
+	if request.Width == "" && request.Quality == "" && request.Format == "" && request.Crop == "" {
+		return transformSpec{}, false, nil
+	}
+
+	spec := transformSpec{Quality: transformDefaultQuality, Crop: request.Crop, Format: request.Format}
+
+	if request.Width != "" {
+		width, err := strconv.Atoi(request.Width)
+		if err != nil || !transformWidths[width] {
+			return transformSpec{}, false, fmt.Errorf("width %q : %w", request.Width, exception.InvalidErr)
+		}
+		spec.Width = width
+	}
+
+	if request.Quality != "" {
+		quality, err := strconv.Atoi(request.Quality)
+		if err != nil || !transformQualities[quality] {
+			return transformSpec{}, false, fmt.Errorf("quality %q : %w", request.Quality, exception.InvalidErr)
+		}
+		spec.Quality = quality
+	}
+
+	if _, ok := transformCrops[spec.Crop]; spec.Crop != "" && !ok {
+		return transformSpec{}, false, fmt.Errorf("crop %q : %w", spec.Crop, exception.InvalidErr)
+	}
+
+	if spec.Format == "" {
+		spec.Format = FormatAuto
+	}
+
+	if !transformFormats[spec.Format] {
+		return transformSpec{}, false, fmt.Errorf("format %q : %w", spec.Format, exception.InvalidErr)
+	}
+
+	if spec.Format == FormatAuto {
+		spec.Format = t.sourceFormat(key)
+		if spec.Format == imaging.FormatPng && accepts(request.Accept, imaging.ContentType(imaging.FormatWebp)) {
+			spec.Format = imaging.FormatWebp
+		}
+	}
+
+	// lossless formats ignore the quality, one rendition serves every value
+	if spec.Format == imaging.FormatPng || spec.Format == imaging.FormatWebp {
+		spec.Quality = 0
+	}
+
+	return spec, true, nil
+}
+
+// render returns the key of the rendition of the photo stored at key, generating it on first use.
+func (t *photoTransformer) render(key string, spec transformSpec) (string, error) {
+	return t.produce(key, t.blobs.variantPrefix(key)+spec.key(), spec.Format, spec.Quality, func(img image.Image) image.Image {
+		if ratio, ok := transformCrops[spec.Crop]; ok {
+			img = imaging.CropToAspect(img, ratio[0], ratio[1])
+		}
+
+		return imaging.ResizeToWidth(img, spec.Width)
+	})
+}
+
+// variant returns the key of the named downscaled rendition of the photo stored at key.
+// Its format follows the extension of key, so it is found by its exact key.
+func (t *photoTransformer) variant(key string, name string, width int) (string, error) {
+	format := t.sourceFormat(key)
+	return t.produce(key, t.blobs.variantPrefix(key)+name+imaging.Extension(format), format, variantQuality, func(img image.Image) image.Image {
+		return imaging.ResizeToWidth(img, width)
+	})
+}
+
+// avatar returns the key of the square avatar cut from crop of the photo stored at key.
+// The crop is part of the key, so editing it never serves a stale avatar.
+func (t *photoTransformer) avatar(key string, crop entity.PhotoCrop) (string, error) {
+	name := "avatar-centre"
+	if crop.Width > 0 {
+		name = fmt.Sprintf("avatar-%d-%d-%d-%d", crop.X, crop.Y, crop.Width, crop.Height)
+	}
+
+	format := t.sourceFormat(key)
+	return t.produce(key, t.blobs.variantPrefix(key)+name+imaging.Extension(format), format, transformDefaultQuality, func(img image.Image) image.Image {
+		if crop.Width > 0 {
+			bounds := img.Bounds()
+			img = imaging.Crop(img, image.Rect(crop.X, crop.Y, crop.X+crop.Width, crop.Y+crop.Height).Add(bounds.Min))
+		}
+
+		return imaging.ResizeToWidth(imaging.CropToAspect(img, 1, 1), avatarSize)
+	})
+}
+
+// produce stores apply(original) under renditionKey unless it is there already.
+// At most cfg.Concurrency renditions are computed at the same time.
+func (t *photoTransformer) produce(key string, renditionKey string, format string, quality int, apply func(img image.Image) image.Image) (string, error) {
+	_, err := t.blobStore.Stat(renditionKey)
+	if err == nil {
+		return renditionKey, nil
+	}
+
+	if !errors.Is(err, exception.NotFoundErr) {
+		return "", err
+	}
+
+	select {
+	case t.slots <- struct{}{}:
+		defer func() { <-t.slots }()
+	case <-time.After(t.cfg.QueueTimeout):
+		return "", fmt.Errorf("transform queue is full : %w", exception.UnavailableErr)
+	}
+
+	original, _, err := t.blobStore.Get(key)
+	if err != nil {
+		return "", err
+	}
+	defer original.Close()
+
+	img, err := t.decode(original)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = imaging.Encode(&buf, apply(img), format, quality)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = t.blobStore.Put(renditionKey, &buf, int64(buf.Len()), imaging.ContentType(format))
+	if err != nil {
+		return "", err
+	}
+
+	return renditionKey, nil
+}
+
+// decode refuses images whose pixel count exceeds cfg.MaxPixels before allocating them.
+func (t *photoTransformer) decode(content io.ReadSeeker) (image.Image, error) {
+	imgConfig, _, err := imaging.DecodeConfig(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(imgConfig.Width)*int64(imgConfig.Height) > t.cfg.MaxPixels {
+		return nil, fmt.Errorf("image has %dx%d pixels : %w", imgConfig.Width, imgConfig.Height, exception.TooLargeErr)
+	}
+
+	_, err = content.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := imaging.Decode(content)
+	return img, err
+}
+
+// sourceFormat keeps jpeg photos as jpeg and turns everything else into png.
+func (t *photoTransformer) sourceFormat(key string) string {
+	if ext := strings.ToLower(filepath.Ext(key)); ext == ".jpg" || ext == ".jpeg" {
+		return imaging.FormatJpeg
+	}
+
+	return imaging.FormatPng
+}
+
+// accepts reports whether the Accept header lists contentType itself with a non-zero
+// weight. Wildcards do not count, clients that send */* for images may not decode webp.
+func accepts(accept string, contentType string) bool {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(mediaRange, ";")
+		if !strings.EqualFold(strings.TrimSpace(mediaType), contentType) {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(name) == "q" {
+				weight, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			}
+		}
+
+		return weight > 0
+	}
+
+	return false
+}
@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"user-personalize/internal/model/dto"
+	"user-personalize/pkg/util/exception"
+)
+
+func TestTransformFormat(t *testing.T) {
+	const browser = "image/avif,image/webp,image/apng,image/*,*/*;q=0.8"
+
+	tests := []struct {
+		name    string
+		key     string
+		request dto.PhotoTransformRequest
+		want    string
+		wantKey string
+		err     error
+	}{
+		{name: "png source for a client without webp", key: "blobs/ab/ab.png", request: dto.PhotoTransformRequest{Width: "400", Accept: "*/*"}, want: "png", wantKey: "t-w400-q0-c.png"},
+		{name: "png source for a browser", key: "blobs/ab/ab.png", request: dto.PhotoTransformRequest{Width: "400", Accept: browser}, want: "webp", wantKey: "t-w400-q0-c.webp"},
+		{name: "webp refused by weight", key: "blobs/ab/ab.gif", request: dto.PhotoTransformRequest{Width: "400", Accept: "image/webp;q=0, image/png"}, want: "png", wantKey: "t-w400-q0-c.png"},
+		{name: "jpeg source for a browser", key: "blobs/ab/ab.jpg", request: dto.PhotoTransformRequest{Width: "400", Accept: browser}, want: "jpeg", wantKey: "t-w400-q80-c.jpg"},
+		{name: "webp asked for", key: "blobs/ab/ab.jpg", request: dto.PhotoTransformRequest{Format: "webp", Quality: "50", Crop: "1:1"}, want: "webp", wantKey: "t-w0-q0-c1x1.webp"},
+		{name: "png asked for by a browser", key: "blobs/ab/ab.jpg", request: dto.PhotoTransformRequest{Format: "png", Accept: browser}, want: "png", wantKey: "t-w0-q0-c.png"},
+		{name: "avif is not offered", key: "blobs/ab/ab.jpg", request: dto.PhotoTransformRequest{Format: "avif"}, err: exception.InvalidErr},
+	}
+
+	transformer := &photoTransformer{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec, ok, err := transformer.parse(test.key, test.request)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			if test.err != nil {
+				return
+			}
+
+			if !ok || spec.Format != test.want || spec.key() != test.wantKey {
+				t.Fatalf("got format %s and key %s, want %s and %s", spec.Format, spec.key(), test.want, test.wantKey)
+			}
+		})
+	}
+
+	_, ok, _ := transformer.parse("blobs/ab/ab.png", dto.PhotoTransformRequest{Accept: browser})
+	if ok {
+		t.Fatal("the Accept header alone asked for a transform")
+	}
+}
@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/mapping"
+)
+
+// DeletePhotos moves the photo of the user to the trash. It keeps its file, tags,
+// likes and comments until it is restored or purged.
+func (p *photosUCImpl) DeletePhotos(photos entity.Photos) error {
+	photosByUserId, err := p.photosRepository.FindByUserId(photos.UserId)
+	if err != nil {
+		return exception.NotFoundErr
+	}
+
+	if photos.Id != photosByUserId.Id {
+		return exception.NotFoundErr
+	}
+
+	err = p.photosRepository.Trash(photosByUserId.Id)
+	if err != nil {
+		return fmt.Errorf("DeletePhotosUC : %w", err)
+	}
+
+	return nil
+}
+
+// GetTrash lists the deleted photos of the user together with the time each one is purged.
+func (p *photosUCImpl) GetTrash(userId string, page dto.PageRequest) (dto.TrashListResponse, error) {
+	keyset, limit, err := newKeyset(page)
+	if err != nil {
+		return dto.TrashListResponse{}, fmt.Errorf("GetTrashUC : %w", err)
+	}
+
+	photos, err := p.photosRepository.FindTrashPage(userId, keyset)
+	if err != nil {
+		return dto.TrashListResponse{}, fmt.Errorf("GetTrashUC : %w", err)
+	}
+
+	response := dto.TrashListResponse{Items: make([]dto.TrashPhotoResponse, 0, len(photos)), Page: pageResponse(limit, nil)}
+	if len(photos) > limit {
+		photos = photos[:limit]
+		last := photos[limit-1]
+		response.Page = pageResponse(limit, &pageCursor{CreatedAt: last.DeletedAt.Time, Id: last.Id})
+	}
+
+	for _, photo := range photos {
+		response.Items = append(response.Items, mapping.MapPhotosToTrashResponse(photo, photo.DeletedAt.Time.Add(p.trashConfig.Retention)))
+	}
+
+	return response, nil
+}
+
+// RestorePhoto takes a photo out of the trash. It fails with ConflictErr while the user
+// has another photo, that one has to be deleted first.
+func (p *photosUCImpl) RestorePhoto(userId string, photoId string) (dto.PhotosResponse, error) {
+	photo, err := p.photosRepository.FindTrashById(photoId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && photo.UserId != userId) {
+		return dto.PhotosResponse{}, fmt.Errorf("RestorePhotoUC : %w", exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("RestorePhotoUC : %w", err)
+	}
+
+	_, err = p.photosRepository.FindByUserId(userId)
+	if err == nil {
+		return dto.PhotosResponse{}, fmt.Errorf("RestorePhotoUC : user already has a photo : %w", exception.ConflictErr)
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return dto.PhotosResponse{}, fmt.Errorf("RestorePhotoUC : %w", err)
+	}
+
+	err = p.photosRepository.Restore(photo.Id)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("RestorePhotoUC : %w", err)
+	}
+
+	restored, err := p.photosRepository.FindCountedByUserId(userId)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("RestorePhotoUC : %w", err)
+	}
+
+	return mapping.MapPhotosToResponse(restored), nil
+}
+
+// DeleteTrashedPhoto permanently removes a photo of the user from the trash, its storage
+// is freed right away instead of after the retention period.
+func (p *photosUCImpl) DeleteTrashedPhoto(userId string, photoId string) error {
+	photo, err := p.photosRepository.FindTrashById(photoId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && photo.UserId != userId) {
+		return fmt.Errorf("DeleteTrashedPhotoUC : %w", exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("DeleteTrashedPhotoUC : %w", err)
+	}
+
+	err = p.purge(photo)
+	if err != nil {
+		return fmt.Errorf("DeleteTrashedPhotoUC : %w", err)
+	}
+
+	return nil
+}
+
+// EmptyTrash permanently removes every photo in the trash of the user and returns how many
+// it removed, it stops at the first photo that cannot be removed.
+func (p *photosUCImpl) EmptyTrash(userId string) (int, error) {
+	photos, err := p.photosRepository.FindTrashByUserId(userId)
+	if err != nil {
+		return 0, fmt.Errorf("EmptyTrashUC : %w", err)
+	}
+
+	for i, photo := range photos {
+		err := p.purge(photo)
+		if err != nil {
+			return i, fmt.Errorf("EmptyTrashUC : photo %s : %w", photo.Id, err)
+		}
+	}
+
+	return len(photos), nil
+}
+
+// PurgeTrash permanently removes the photos that have been in the trash for longer than
+// the retention period and returns how many it removed.
+func (p *photosUCImpl) PurgeTrash() (int, error) {
+	photos, err := p.photosRepository.FindTrashedBefore(time.Now().Add(-p.trashConfig.Retention))
+	if err != nil {
+		return 0, fmt.Errorf("PurgeTrashUC : %w", err)
+	}
+
+	purged := 0
+	for _, photo := range photos {
+		err := p.purge(photo)
+		if err != nil {
+			log.Println(fmt.Errorf("PurgeTrashUC : photo %s : %w", photo.Id, err))
+			continue
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
+
+// PurgeUserPhotos permanently removes the photo of the user and everything in their trash
+// right away, it stops at the first photo that cannot be removed.
+func (p *photosUCImpl) PurgeUserPhotos(userId string) (int, error) {
+	photos, err := p.photosRepository.FindTrashByUserId(userId)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeUserPhotosUC : %w", err)
+	}
+
+	photo, err := p.photosRepository.FindByUserId(userId)
+	if err == nil {
+		photos = append(photos, photo)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("PurgeUserPhotosUC : %w", err)
+	}
+
+	for i, photo := range photos {
+		err := p.purge(photo)
+		if err != nil {
+			return i, fmt.Errorf("PurgeUserPhotosUC : photo %s : %w", photo.Id, err)
+		}
+	}
+
+	return len(photos), nil
+}
+
+// RunTrashPurge is meant to be started in its own goroutine, a negative interval
+// disables it.
+func (p *photosUCImpl) RunTrashPurge() {
+	if p.trashConfig.PurgeInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.trashConfig.PurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := p.PurgeTrash()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		if purged > 0 {
+			log.Printf("trash purge : removed %d photos", purged)
+		}
+	}
+}
+
+// purge deletes the row and its versions and releases their blobs, a file goes with
+// the last reference.
+func (p *photosUCImpl) purge(photo entity.Photos) error {
+	err := p.removeVersions(photo.UserId, photo.Id, 0)
+	if err != nil {
+		return err
+	}
+
+	legacySize := p.quota.legacySize(photo)
+
+	var released bool
+	var purged bool
+	err = p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		// a photo purged by a concurrent call was released and refunded there
+		err := p.photosRepository.WithTx(tx).Delete(photo.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+		purged = true
+
+		var blob entity.Blob
+		blob, released, err = p.blobs.release(tx, photo.BlobHash)
+		if err != nil {
+			return err
+		}
+
+		return p.quota.refund(tx, photo.UserId, blob.Size+legacySize, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !purged {
+		return nil
+	}
+
+	// a file left behind is picked up by the reconciler
+	if released {
+		p.blobs.discardReleased(photo.BlobHash, photo.PhotoUrl)
+	} else if photo.BlobHash == "" {
+		p.blobs.discardWithVariants(photo.PhotoUrl)
+	}
+
+	return nil
+}
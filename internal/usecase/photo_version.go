@@ -0,0 +1,281 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/mapping"
+)
+
+// maxPhotoVersions is the number of earlier files kept per photo, older ones are removed
+// whenever a new version is added.
+const maxPhotoVersions = 10
+
+// GetPhotoVersions lists the earlier files of the user's photo, newest first.
+func (p *photosUCImpl) GetPhotoVersions(userId string, photoId string, page dto.PageRequest) (dto.PhotoVersionListResponse, error) {
+	keyset, limit, err := newKeyset(page)
+	if err != nil {
+		return dto.PhotoVersionListResponse{}, fmt.Errorf("GetPhotoVersionsUC : %w", err)
+	}
+
+	photo, err := p.photosRepository.FindByUserId(userId)
+	if err != nil || photo.Id != photoId {
+		return dto.PhotoVersionListResponse{}, fmt.Errorf("GetPhotoVersionsUC : %w", exception.NotFoundErr)
+	}
+
+	versions, err := p.versionRepository.FindPageByPhotoId(photo.Id, keyset)
+	if err != nil {
+		return dto.PhotoVersionListResponse{}, fmt.Errorf("GetPhotoVersionsUC : %w", err)
+	}
+
+	response := dto.PhotoVersionListResponse{Items: make([]dto.PhotoVersionResponse, 0, len(versions)), Page: pageResponse(limit, nil)}
+	if len(versions) > limit {
+		versions = versions[:limit]
+		last := versions[limit-1]
+		response.Page = pageResponse(limit, &pageCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+
+	for _, version := range versions {
+		response.Items = append(response.Items, mapping.MapPhotoVersionToResponse(version))
+	}
+
+	return response, nil
+}
+
+// RevertPhotoVersion makes a version the current file, title and caption of the photo again.
+// The state it replaces becomes a version itself, so a revert can be undone the same way.
+// Visibility and tags are not part of a version and stay as they are.
+func (p *photosUCImpl) RevertPhotoVersion(userId string, photoId string, versionId string) (dto.PhotosResponse, error) {
+	current, err := p.photosRepository.FindByUserId(userId)
+	if err != nil || current.Id != photoId {
+		return dto.PhotosResponse{}, fmt.Errorf("RevertPhotoVersionUC : %w", exception.NotFoundErr)
+	}
+	current = p.adopted(current)
+
+	version, err := p.versionRepository.FindById(versionId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && version.PhotoId != current.Id) {
+		return dto.PhotosResponse{}, fmt.Errorf("RevertPhotoVersionUC : %w", exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("RevertPhotoVersionUC : %w", err)
+	}
+
+	photos := current
+	photos.Title = version.Title
+	photos.Caption = version.Caption
+	photos.PhotoUrl = version.PhotoUrl
+	photos.BlobHash = version.BlobHash
+	photos.PerceptualHash = version.PerceptualHash
+	photos.Crop = version.Crop
+	photos.Width = version.Width
+	photos.Height = version.Height
+	photos.BlurHash = version.BlurHash
+	photos.DominantColor = version.DominantColor
+
+	// the references of both blobs only change hands, none is acquired or released
+	legacySize := p.quota.legacySize(current)
+
+	var reverted entity.Photos
+	err = p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		versionId, err := p.archive(tx, current)
+		if err != nil {
+			return err
+		}
+
+		// a current file without a blob is not kept as a version but removed below
+		if versionId == "" {
+			err = p.quota.refund(tx, current.UserId, legacySize, 1)
+			if err != nil {
+				return err
+			}
+		}
+
+		reverted, err = p.photosRepository.WithTx(tx).Update(photos)
+		if err != nil {
+			return err
+		}
+
+		// a version removed meanwhile has released its blob, the photo cannot take it over
+		err = p.versionRepository.WithTx(tx).Delete(version.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return exception.NotFoundErr
+		}
+
+		return err
+	})
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("RevertPhotoVersionUC : %w", err)
+	}
+
+	if current.BlobHash == "" {
+		p.blobs.discardWithVariants(current.PhotoUrl)
+	}
+	p.pruneVersions(current.UserId, current.Id)
+
+	return mapping.MapPhotosToResponse(reverted), nil
+}
+
+// DeletePhotoVersion removes one earlier file of the user's photo and frees its storage.
+func (p *photosUCImpl) DeletePhotoVersion(userId string, photoId string, versionId string) error {
+	photo, err := p.photosRepository.FindByUserId(userId)
+	if err != nil || photo.Id != photoId {
+		return fmt.Errorf("DeletePhotoVersionUC : %w", exception.NotFoundErr)
+	}
+
+	version, err := p.versionRepository.FindById(versionId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && version.PhotoId != photo.Id) {
+		return fmt.Errorf("DeletePhotoVersionUC : %w", exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("DeletePhotoVersionUC : %w", err)
+	}
+
+	var released bool
+	err = p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		err := p.versionRepository.WithTx(tx).Delete(version.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return exception.NotFoundErr
+		}
+
+		if err != nil {
+			return err
+		}
+
+		released, err = p.releaseVersion(tx, userId, version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("DeletePhotoVersionUC : %w", err)
+	}
+
+	if released {
+		p.blobs.discardReleased(version.BlobHash, version.PhotoUrl)
+	}
+
+	return nil
+}
+
+// archive keeps the current state of photo as a version and returns its id, the version
+// takes over the photo's reference on its blob. Files stored before content addressing
+// are moved to a blob first, see adopted, one that could not be moved is not kept.
+func (p *photosUCImpl) archive(tx *sql.Tx, photo entity.Photos) (string, error) {
+	if photo.BlobHash == "" {
+		return "", nil
+	}
+
+	id := uuid.NewString()
+	err := p.versionRepository.WithTx(tx).Insert(entity.PhotoVersion{
+		Id:             id,
+		PhotoId:        photo.Id,
+		BlobHash:       photo.BlobHash,
+		PhotoUrl:       photo.PhotoUrl,
+		Title:          photo.Title,
+		Caption:        photo.Caption,
+		PerceptualHash: photo.PerceptualHash,
+		Crop:           photo.Crop,
+		Width:          photo.Width,
+		Height:         photo.Height,
+		BlurHash:       photo.BlurHash,
+		DominantColor:  photo.DominantColor,
+		CreatedAt:      photo.UpdatedAt,
+	})
+	return id, err
+}
+
+// adopted returns photo with its legacy file moved to a blob, so that the file can be kept
+// as a version. When that fails the photo is returned as it is and the error logged.
+func (p *photosUCImpl) adopted(photo entity.Photos) entity.Photos {
+	if photo.BlobHash != "" {
+		return photo
+	}
+
+	err := p.migrateLegacy(photo)
+	if err != nil {
+		log.Println("adopt legacy photo", photo.Id, ":", err)
+		return photo
+	}
+
+	migrated, err := p.photosRepository.FindById(photo.Id)
+	if err != nil {
+		log.Println("adopt legacy photo", photo.Id, ":", err)
+		return photo
+	}
+
+	return migrated
+}
+
+// pruneVersions removes the versions beyond maxPhotoVersions. It runs after the change
+// that added a version has committed, a failure is only logged and the next change retries.
+func (p *photosUCImpl) pruneVersions(userId string, photoId string) {
+	err := p.removeVersions(userId, photoId, maxPhotoVersions)
+	if err != nil {
+		log.Println("prune photo versions", photoId, ":", err)
+	}
+}
+
+// removeVersions deletes all versions of a photo but the keep newest ones, releases their
+// blobs and refunds them to the owner of the photo. A version another call removed first
+// is skipped, it was released and refunded by that call.
+func (p *photosUCImpl) removeVersions(userId string, photoId string, keep int) error {
+	var discarded []entity.PhotoVersion
+	err := p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		versions, err := p.versionRepository.WithTx(tx).FindSurplus(photoId, keep)
+		if err != nil {
+			return err
+		}
+
+		for _, version := range versions {
+			err = p.versionRepository.WithTx(tx).Delete(version.Id)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			released, err := p.releaseVersion(tx, userId, version)
+			if err != nil {
+				return err
+			}
+
+			if released {
+				discarded = append(discarded, version)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, version := range discarded {
+		p.blobs.discardReleased(version.BlobHash, version.PhotoUrl)
+	}
+
+	return nil
+}
+
+// releaseVersion drops the reference of a version whose row tx deleted and refunds it to
+// the owner of the photo. released reports whether it was the last reference to the blob.
+func (p *photosUCImpl) releaseVersion(tx *sql.Tx, userId string, version entity.PhotoVersion) (bool, error) {
+	blob, released, err := p.blobs.release(tx, version.BlobHash)
+	if err != nil {
+		return false, err
+	}
+
+	err = p.quota.refund(tx, userId, blob.Size, 1)
+	if err != nil {
+		return false, err
+	}
+
+	return released, nil
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"user-personalize/internal/config"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+)
+
+// fakeBlobRepository keeps blob rows in memory, only the methods the blob manager uses
+// to count references are implemented.
+type fakeBlobRepository struct {
+	repository.BlobRepository
+	blobs map[string]entity.Blob
+}
+
+func (f *fakeBlobRepository) WithTx(tx *sql.Tx) repository.BlobRepository { return f }
+
+func (f *fakeBlobRepository) Lock(hash string) error { return nil }
+
+func (f *fakeBlobRepository) Acquire(blob entity.Blob) (entity.Blob, error) {
+	if stored, ok := f.blobs[blob.Hash]; ok {
+		blob = stored
+	}
+
+	blob.RefCount++
+	f.blobs[blob.Hash] = blob
+	return blob, nil
+}
+
+func (f *fakeBlobRepository) Release(hash string) (entity.Blob, error) {
+	blob, ok := f.blobs[hash]
+	if !ok {
+		return entity.Blob{}, sql.ErrNoRows
+	}
+
+	blob.RefCount--
+	f.blobs[hash] = blob
+	return blob, nil
+}
+
+func (f *fakeBlobRepository) Delete(hash string) error {
+	delete(f.blobs, hash)
+	return nil
+}
+
+func (f *fakeBlobRepository) FindByHash(hash string) (entity.Blob, error) {
+	blob, ok := f.blobs[hash]
+	if !ok {
+		return entity.Blob{}, sql.ErrNoRows
+	}
+
+	return blob, nil
+}
+
+// fakeUsageRepository keeps the usage of one user in memory.
+type fakeUsageRepository struct {
+	repository.UsageRepository
+	usage entity.Usage
+}
+
+func (f *fakeUsageRepository) WithTx(tx *sql.Tx) repository.UsageRepository { return f }
+
+func (f *fakeUsageRepository) Add(userId string, bytes int64, photos int) (entity.Usage, error) {
+	f.usage.Bytes += bytes
+	f.usage.PhotoCount += photos
+	return f.usage, nil
+}
+
+type fakeUserRepository struct {
+	repository.UserRepository
+}
+
+func (f *fakeUserRepository) GetById(id string) (entity.User, error) {
+	return entity.User{Id: id, Role: entity.RoleUser}, nil
+}
+
+const versionTestUser = "owner"
+
+func newVersionTestUC(blobs map[string]entity.Blob, usage entity.Usage, quota config.Quota) (*photosUCImpl, *fakeBlobRepository, *fakeUsageRepository) {
+	blobRepository := &fakeBlobRepository{blobs: blobs}
+	usageRepository := &fakeUsageRepository{usage: usage}
+	cfg := config.QuotaConfig{Plans: map[string]config.Quota{entity.RoleUser: quota}}
+
+	return &photosUCImpl{
+		blobs: newBlobManager(blobRepository, nil, nil),
+		quota: newPhotoQuota(usageRepository, &fakeUserRepository{}, nil, cfg),
+	}, blobRepository, usageRepository
+}
+
+func TestReleaseVersionAccounting(t *testing.T) {
+	tests := []struct {
+		name         string
+		refs         int
+		version      entity.PhotoVersion
+		wantReleased bool
+		wantRefs     int
+		wantUsage    entity.Usage
+	}{
+		{name: "blob shared with the photo", refs: 2, version: entity.PhotoVersion{BlobHash: "h"}, wantRefs: 1, wantUsage: entity.Usage{Bytes: 100, PhotoCount: 1}},
+		{name: "last reference", refs: 1, version: entity.PhotoVersion{BlobHash: "h"}, wantReleased: true, wantUsage: entity.Usage{Bytes: 100, PhotoCount: 1}},
+		{name: "legacy version without a blob", refs: 1, version: entity.PhotoVersion{PhotoUrl: "photos/old.jpg"}, wantRefs: 1, wantUsage: entity.Usage{Bytes: 200, PhotoCount: 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			blobs := map[string]entity.Blob{"h": {Hash: "h", Size: 100, RefCount: test.refs}}
+			uc, blobRepository, usageRepository := newVersionTestUC(blobs, entity.Usage{Bytes: 200, PhotoCount: 2}, config.Quota{})
+
+			released, err := uc.releaseVersion(nil, versionTestUser, test.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if released != test.wantReleased {
+				t.Fatalf("got released %v, want %v", released, test.wantReleased)
+			}
+
+			blob, ok := blobRepository.blobs["h"]
+			if ok != (test.wantRefs > 0) || blob.RefCount != test.wantRefs {
+				t.Fatalf("got %d references, row kept %v, want %d", blob.RefCount, ok, test.wantRefs)
+			}
+
+			if usageRepository.usage != test.wantUsage {
+				t.Fatalf("got usage %+v, want %+v", usageRepository.usage, test.wantUsage)
+			}
+		})
+	}
+}
+
+func TestRetainedVersionIsChargedAndRefunded(t *testing.T) {
+	tests := []struct {
+		name  string
+		quota config.Quota
+		err   error
+	}{
+		{name: "within the quota", quota: config.Quota{MaxBytes: 1000, MaxPhotos: 5}},
+		{name: "over the byte quota", quota: config.Quota{MaxBytes: 150}, err: exception.QuotaErr},
+		{name: "over the photo quota", quota: config.Quota{MaxPhotos: 1}, err: exception.QuotaErr},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := entity.Usage{Bytes: 100, PhotoCount: 1}
+			blobs := map[string]entity.Blob{"h": {Hash: "h", Size: 100, RefCount: 1}}
+			uc, blobRepository, usageRepository := newVersionTestUC(blobs, start, test.quota)
+
+			// the steps UpdatePhotos takes in its transaction to keep the current file as a version
+			blob, err := uc.blobs.retain(nil, "h")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = uc.quota.charge(nil, versionTestUser, blob.Size)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			if blob.RefCount != 2 || usageRepository.usage != (entity.Usage{Bytes: 200, PhotoCount: 2}) {
+				t.Fatalf("got %d references and usage %+v after retain", blob.RefCount, usageRepository.usage)
+			}
+
+			if err != nil {
+				// the transaction would roll back, which the fakes do not, the test stops here
+				return
+			}
+
+			released, err := uc.releaseVersion(nil, versionTestUser, entity.PhotoVersion{BlobHash: "h"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if released || blobRepository.blobs["h"].RefCount != 1 || usageRepository.usage != start {
+				t.Fatalf("got released %v, %d references and usage %+v, want the state before the version", released, blobRepository.blobs["h"].RefCount, usageRepository.usage)
+			}
+		})
+	}
+}
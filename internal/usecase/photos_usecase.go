@@ -1,52 +1,238 @@
 package usecase
 
 import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"os"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"user-personalize/internal/config"
 	"user-personalize/internal/model/dto"
 	"user-personalize/internal/model/entity"
 	"user-personalize/internal/repository"
 	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/imaging"
+	"user-personalize/pkg/util/mapping"
+	"user-personalize/pkg/util/service"
+	"user-personalize/pkg/util/storage"
 )
 
 type PhotosUC interface {
-	SavePhotos(photos entity.Photos) (dto.PhotosResponse, error)
-	UpdatePhotos(payload entity.Photos) (dto.PhotosResponse, error)
+	SavePhotos(photos entity.Photos, file dto.PhotoFile, crop dto.PhotoCropRequest) (dto.PhotosResponse, error)
+	SaveStagedPhotos(photos entity.Photos, stagedKey string) (dto.PhotosResponse, error)
+	UpdatePhotos(payload entity.Photos, file dto.PhotoFile, crop dto.PhotoCropRequest) (dto.PhotosResponse, error)
 	DeletePhotos(photos entity.Photos) error
 	GetPhotosByUserId(userId string) (dto.PhotosResponse, error)
+	GetPhotoContent(viewerId string, photoId string, variant string, transform dto.PhotoTransformRequest) (dto.PhotoContent, error)
+	SharePhoto(userId string, photoId string, request dto.SharePhotoRequest) (dto.SharePhotoResponse, error)
+	GetSharedPhotoContent(photoId string, variant string, expires string, signature string) (dto.PhotoContent, error)
+	GetSimilarPhotos(userId string, photoId string, maxDistance int) ([]dto.SimilarPhotoResponse, error)
+	GetUserPhotos(viewerId string, userId string, page dto.PageRequest) (dto.PhotoListResponse, error)
+	SearchPhotos(viewerId string, request dto.PhotoSearchRequest) (dto.PhotoSearchResponse, error)
+	GetFeed(userId string, page dto.PageRequest) (dto.FeedResponse, error)
+	GetPhotoVersions(userId string, photoId string, page dto.PageRequest) (dto.PhotoVersionListResponse, error)
+	RevertPhotoVersion(userId string, photoId string, versionId string) (dto.PhotosResponse, error)
+	DeletePhotoVersion(userId string, photoId string, versionId string) error
+	GetTrash(userId string, page dto.PageRequest) (dto.TrashListResponse, error)
+	RestorePhoto(userId string, photoId string) (dto.PhotosResponse, error)
+	DeleteTrashedPhoto(userId string, photoId string) error
+	EmptyTrash(userId string) (int, error)
+	PurgeTrash() (int, error)
+	PurgeUserPhotos(userId string) (int, error)
+	RunTrashPurge()
+	MigrateLegacyPhotos() (int, error)
+	RunLegacyMigration()
+	BackfillAnalysis() (int, error)
+	RunAnalysisBackfill()
 }
 
+const (
+	VariantOriginal = "original"
+	// VariantAvatar is a square rendition cut from the crop the owner chose.
+	VariantAvatar = "avatar"
+)
+
+// photoVariants maps every downscaled rendition to its maximum width in pixels.
+var photoVariants = map[string]int{
+	"thumbnail": 200,
+	"medium":    800,
+}
+
+// Placeholders use 4x3 BlurHash components, enough for the rough shapes of a photo.
+const (
+	blurHashComponentsX = 4
+	blurHashComponentsY = 3
+)
+
+// Near-duplicates are photos whose perceptual hashes differ in at most this many bits.
+// SimilarMaxDistance is bound by the band indexes of photos.phash.
+const (
+	SimilarDefaultDistance = 5
+	SimilarMaxDistance     = 7
+	similarLimit           = 20
+)
+
 type photosUCImpl struct {
-	photosRepository repository.PhotosRepository
+	photosRepository  repository.PhotosRepository
+	tagRepository     repository.TagRepository
+	versionRepository repository.PhotoVersionRepository
+	reportRepository  repository.ReportRepository
+	actionRepository  repository.ModerationActionRepository
+	userRepository    repository.UserRepository
+	transactor        repository.Transactor
+	access            *photoAccess
+	quota             *photoQuota
+	blobs             *blobManager
+	transformer       *photoTransformer
+	blobStore         storage.BlobStore
+	urlSigner         service.UrlSignerService
+	classifier        service.ContentClassifier
+	trashConfig       config.TrashConfig
 }
 
 func (p *photosUCImpl) GetPhotosByUserId(userId string) (dto.PhotosResponse, error) {
-	photoByUserId, err := p.photosRepository.FindByUserId(userId)
+	photoByUserId, err := p.photosRepository.FindCountedByUserId(userId)
 	if err != nil {
 		return dto.PhotosResponse{}, exception.NotFoundErr
 	}
-	return dto.PhotosResponse(photoByUserId), nil
+	return mapping.MapPhotosToResponse(photoByUserId), nil
+}
+
+// SavePhotos stages the file, commits the row and only then promotes the file
+// to the key stored in photo_url. Every failure undoes the steps already taken.
+// Content that is already stored is not written twice, the photo references the existing blob.
+func (p *photosUCImpl) SavePhotos(photos entity.Photos, file dto.PhotoFile, crop dto.PhotoCropRequest) (dto.PhotosResponse, error) {
+	photos, err := p.details(photos, entity.Photos{Visibility: entity.VisibilityPrivate})
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("SavePhotosUC : %w", err)
+	}
+
+	err = p.quota.check(photos.UserId, file.Size)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("SavePhotosUC : %w", err)
+	}
+
+	staged, err := p.blobs.stage(file)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("SavePhotosUC : %w", err)
+	}
+
+	photos.Crop, err = p.resolveCrop(staged.Key, crop)
+	if err != nil {
+		p.blobs.discard(staged.Key)
+		return dto.PhotosResponse{}, fmt.Errorf("SavePhotosUC : %w", err)
+	}
+
+	photosInserted, err := p.saveStaged(photos, staged)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("SavePhotosUC : %w", err)
+	}
+
+	return p.uploadResponse(photosInserted), nil
+}
+
+// SaveStagedPhotos creates a photo from a file that already sits under stagedKey,
+// e.g. one a client uploaded straight to storage with a presigned url.
+func (p *photosUCImpl) SaveStagedPhotos(photos entity.Photos, stagedKey string) (dto.PhotosResponse, error) {
+	if !strings.HasPrefix(stagedKey, stagingPrefix) && !strings.HasPrefix(stagedKey, directUploadPrefix) {
+		return dto.PhotosResponse{}, fmt.Errorf("SaveStagedPhotosUC : staged key %q : %w", stagedKey, exception.InvalidErr)
+	}
+
+	photos, err := p.details(photos, entity.Photos{Visibility: entity.VisibilityPrivate})
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("SaveStagedPhotosUC : %w", err)
+	}
+
+	staged, err := p.blobs.stageExisting(stagedKey)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("SaveStagedPhotosUC : %w", err)
+	}
+
+	photosInserted, err := p.saveStaged(photos, staged)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("SaveStagedPhotosUC : %w", err)
+	}
+
+	return p.uploadResponse(photosInserted), nil
 }
 
-func (p *photosUCImpl) SavePhotos(photos entity.Photos) (dto.PhotosResponse, error) {
+func (p *photosUCImpl) saveStaged(photos entity.Photos, staged stagedBlob) (entity.Photos, error) {
 	id := uuid.NewString()
 	photos.Id = id
-	photosInserted, err := p.photosRepository.Insert(photos)
+	photos = p.analyze(staged.Key, photos)
+	classification := p.classify(staged)
+
+	var photosInserted entity.Photos
+	var blob entity.Blob
+	var created bool
+	err := p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		var err error
+		blob, created, err = p.blobs.acquire(tx, staged)
+		if err != nil {
+			return err
+		}
+
+		err = p.quota.charge(tx, photos.UserId, blob.Size)
+		if err != nil {
+			return err
+		}
+
+		photos.PhotoUrl = blob.ObjectKey
+		photos.BlobHash = blob.Hash
+		photosInserted, err = p.photosRepository.WithTx(tx).Insert(photos)
+		if err != nil {
+			return err
+		}
+
+		photosInserted.Tags, err = p.replaceTags(tx, photos, photosInserted)
+		if err != nil {
+			return err
+		}
+
+		photosInserted, err = p.flag(tx, photosInserted, classification)
+		return err
+	})
 	if err != nil {
-		return dto.PhotosResponse{}, err
+		p.blobs.discard(staged.Key)
+		return entity.Photos{}, err
 	}
 
-	return dto.PhotosResponse{
-		Id:       photosInserted.Id,
-		Title:    photosInserted.Title,
-		Caption:  photosInserted.Caption,
-		PhotoUrl: photosInserted.PhotoUrl,
-		UserId:   photosInserted.UserId,
-	}, nil
+	err = p.blobs.settle(staged, blob, created)
+	if err != nil {
+		compensateErr := p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+			err := p.photosRepository.WithTx(tx).Delete(photosInserted.Id)
+			if err != nil {
+				return err
+			}
+
+			err = p.quota.refund(tx, photosInserted.UserId, blob.Size, 1)
+			if err != nil {
+				return err
+			}
+
+			_, _, err = p.blobs.release(tx, blob.Hash)
+			return err
+		})
+		if compensateErr != nil {
+			log.Println("SavePhotosUC compensation :", compensateErr)
+		}
+		p.blobs.discard(staged.Key)
+		return entity.Photos{}, err
+	}
+
+	return photosInserted, nil
 }
 
-func (p *photosUCImpl) UpdatePhotos(photos entity.Photos) (dto.PhotosResponse, error) {
+// UpdatePhotos follows the same stage, commit, promote order as SavePhotos.
+// The previous file is kept as a version of the photo, see archive.
+// Without a file only the details and the crop change, the original stays as it is
+// and the previous title, caption and crop are kept as a version of the same file.
+func (p *photosUCImpl) UpdatePhotos(photos entity.Photos, file dto.PhotoFile, crop dto.PhotoCropRequest) (dto.PhotosResponse, error) {
 	photosByUserId, err := p.photosRepository.FindByUserId(photos.UserId)
 	if err != nil {
 		return dto.PhotosResponse{}, exception.NotFoundErr
@@ -55,44 +241,587 @@ func (p *photosUCImpl) UpdatePhotos(photos entity.Photos) (dto.PhotosResponse, e
 	if photos.Id != photosByUserId.Id {
 		return dto.PhotosResponse{}, exception.NotFoundErr
 	}
+	photosByUserId = p.adopted(photosByUserId)
 
-	err = os.Remove(photosByUserId.PhotoUrl)
+	photos, err = p.details(photos, photosByUserId)
 	if err != nil {
 		return dto.PhotosResponse{}, fmt.Errorf("UpdatePhotosUC : %w", err)
 	}
 
-	photosUpdated, err := p.photosRepository.Update(photos)
+	if file.Content == nil {
+		return p.updateDetails(photos, photosByUserId, crop)
+	}
+
+	err = p.quota.check(photos.UserId, file.Size)
 	if err != nil {
 		return dto.PhotosResponse{}, fmt.Errorf("UpdatePhotosUC : %w", err)
 	}
 
-	return dto.PhotosResponse(photosUpdated), nil
-}
+	staged, err := p.blobs.stage(file)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("UpdatePhotosUC : %w", err)
+	}
+	photos = p.analyze(staged.Key, photos)
+	classification := p.classify(staged)
 
-func (p *photosUCImpl) DeletePhotos(photos entity.Photos) error {
-	photosByUserId, err := p.photosRepository.FindByUserId(photos.UserId)
+	// a crop of the previous file means nothing for the new one, so it is reset unless given
+	photos.Crop, err = p.resolveCrop(staged.Key, crop)
 	if err != nil {
-		return exception.NotFoundErr
+		p.blobs.discard(staged.Key)
+		return dto.PhotosResponse{}, fmt.Errorf("UpdatePhotosUC : %w", err)
 	}
 
-	if photos.Id != photosByUserId.Id {
-		return exception.NotFoundErr
+	// a file stored before content addressing is not kept as a version, it is removed below
+	legacySize := p.quota.legacySize(photosByUserId)
+
+	var photosUpdated entity.Photos
+	var blob entity.Blob
+	var created bool
+	var versionId string
+	err = p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		blob, created, err = p.blobs.acquire(tx, staged)
+		if err != nil {
+			return err
+		}
+
+		if photosByUserId.BlobHash == "" {
+			err = p.quota.refund(tx, photos.UserId, legacySize, 1)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = p.quota.charge(tx, photos.UserId, blob.Size)
+		if err != nil {
+			return err
+		}
+
+		photos.PhotoUrl = blob.ObjectKey
+		photos.BlobHash = blob.Hash
+		photosUpdated, err = p.photosRepository.WithTx(tx).Update(photos)
+		if err != nil {
+			return err
+		}
+
+		photosUpdated.Tags, err = p.replaceTags(tx, photos, photosUpdated)
+		if err != nil {
+			return err
+		}
+
+		photosUpdated, err = p.flag(tx, photosUpdated, classification)
+		if err != nil {
+			return err
+		}
+
+		// the previous file is kept as a version instead of being released
+		versionId, err = p.archive(tx, photosByUserId)
+		return err
+	})
+	if err != nil {
+		p.blobs.discard(staged.Key)
+		return dto.PhotosResponse{}, fmt.Errorf("UpdatePhotosUC : %w", err)
 	}
 
-	err = p.photosRepository.Delete(photosByUserId.Id)
+	err = p.blobs.settle(staged, blob, created)
 	if err != nil {
+		compensateErr := p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+			// the version made from the previous file hands its reference back to the photo,
+			// a previous file without a blob was refunded and is charged again
+			var err error
+			if versionId != "" {
+				err = p.versionRepository.WithTx(tx).Delete(versionId)
+			} else {
+				err = p.quota.recharge(tx, photosByUserId.UserId, legacySize, 1)
+			}
+			if err != nil {
+				return err
+			}
+
+			err = p.quota.refund(tx, photosByUserId.UserId, blob.Size, 1)
+			if err != nil {
+				return err
+			}
+
+			_, err = p.photosRepository.WithTx(tx).Update(photosByUserId)
+			if err != nil {
+				return err
+			}
+
+			_, _, err = p.blobs.release(tx, blob.Hash)
+			return err
+		})
+		if compensateErr != nil {
+			log.Println("UpdatePhotosUC compensation :", compensateErr)
+		}
+		p.blobs.discard(staged.Key)
+		return dto.PhotosResponse{}, fmt.Errorf("UpdatePhotosUC : %w", err)
+	}
+
+	if photosByUserId.BlobHash == "" {
+		p.blobs.discardWithVariants(photosByUserId.PhotoUrl)
+	}
+	p.pruneVersions(photosByUserId.UserId, photosByUserId.Id)
+
+	return p.uploadResponse(photosUpdated), nil
+}
+
+func (p *photosUCImpl) updateDetails(photos entity.Photos, current entity.Photos, crop dto.PhotoCropRequest) (dto.PhotosResponse, error) {
+	photos.PhotoUrl = current.PhotoUrl
+	photos.BlobHash = current.BlobHash
+	photos.PerceptualHash = current.PerceptualHash
+	photos.Width = current.Width
+	photos.Height = current.Height
+	photos.BlurHash = current.BlurHash
+	photos.DominantColor = current.DominantColor
+	photos.Crop = current.Crop
+
+	var err error
+	if crop.Rect || crop.Focal {
+		photos.Crop, err = p.resolveCrop(current.PhotoUrl, crop)
+		if err != nil {
+			return dto.PhotosResponse{}, fmt.Errorf("UpdatePhotosUC : %w", err)
+		}
+	}
+
+	// visibility and tags are not part of a version, changing only them adds none
+	versioned := photos.Title != current.Title || photos.Caption != current.Caption || photos.Crop != current.Crop
+
+	var photosUpdated entity.Photos
+	var versionId string
+	err = p.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		if versioned {
+			versionId, err = p.archive(tx, current)
+			if err != nil {
+				return err
+			}
+		}
+
+		// the photo keeps its reference, the version needs one of its own
+		if versionId != "" {
+			blob, err := p.blobs.retain(tx, current.BlobHash)
+			if err != nil {
+				return err
+			}
+
+			err = p.quota.charge(tx, current.UserId, blob.Size)
+			if err != nil {
+				return err
+			}
+		}
+
+		photosUpdated, err = p.photosRepository.WithTx(tx).Update(photos)
+		if err != nil {
+			return err
+		}
+
+		photosUpdated.Tags, err = p.replaceTags(tx, photos, photosUpdated)
 		return err
+	})
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("UpdatePhotosUC : %w", err)
+	}
+
+	if versionId != "" {
+		p.pruneVersions(current.UserId, current.Id)
+	}
+
+	return mapping.MapPhotosToResponse(photosUpdated), nil
+}
+
+// resolveCrop checks a crop against the dimensions of the image stored at key. A focal
+// point becomes the largest square around it that fits, so only rectangles are stored.
+func (p *photosUCImpl) resolveCrop(key string, crop dto.PhotoCropRequest) (entity.PhotoCrop, error) {
+	if !crop.Rect && !crop.Focal {
+		return entity.PhotoCrop{}, nil
 	}
 
-	err = os.Remove(photosByUserId.PhotoUrl)
+	content, _, err := p.blobStore.Get(key)
 	if err != nil {
-		return err
+		return entity.PhotoCrop{}, err
+	}
+	defer content.Close()
+
+	imgConfig, _, err := imaging.DecodeConfig(content)
+	if err != nil {
+		return entity.PhotoCrop{}, fmt.Errorf("crop needs an image : %v : %w", err, exception.InvalidErr)
+	}
+
+	return fitCrop(crop, imgConfig.Width, imgConfig.Height)
+}
+
+// fitCrop checks a crop against an image of width by height and turns a focal point into a rectangle.
+func fitCrop(crop dto.PhotoCropRequest, width int, height int) (entity.PhotoCrop, error) {
+	if crop.Rect {
+		// compared as differences, a sum of large values would overflow
+		if crop.X < 0 || crop.Y < 0 || crop.Width < 1 || crop.Height < 1 || crop.Width > width-crop.X || crop.Height > height-crop.Y {
+			return entity.PhotoCrop{}, fmt.Errorf("crop %dx%d+%d+%d outside %dx%d image : %w", crop.Width, crop.Height, crop.X, crop.Y, width, height, exception.InvalidErr)
+		}
+
+		return entity.PhotoCrop{X: crop.X, Y: crop.Y, Width: crop.Width, Height: crop.Height}, nil
+	}
+
+	// written as negations so that NaN is refused as well
+	if !(crop.FocalX >= 0 && crop.FocalX <= 1) || !(crop.FocalY >= 0 && crop.FocalY <= 1) {
+		return entity.PhotoCrop{}, fmt.Errorf("focal point %v,%v : %w", crop.FocalX, crop.FocalY, exception.InvalidErr)
+	}
+
+	side := min(width, height)
+	x := min(max(int(crop.FocalX*float64(width))-side/2, 0), width-side)
+	y := min(max(int(crop.FocalY*float64(height))-side/2, 0), height-side)
+	return entity.PhotoCrop{X: x, Y: y, Width: side, Height: side}, nil
+}
+
+func (p *photosUCImpl) GetPhotoContent(viewerId string, photoId string, variant string, transform dto.PhotoTransformRequest) (dto.PhotoContent, error) {
+	photo, err := p.photosRepository.FindById(photoId)
+	if err != nil {
+		return dto.PhotoContent{}, exception.NotFoundErr
+	}
+
+	// hidden photos look exactly like missing ones
+	if !p.access.canView(viewerId, photo) {
+		return dto.PhotoContent{}, exception.NotFoundErr
+	}
+
+	spec, ok, err := p.transformer.parse(photo.PhotoUrl, transform)
+	if err != nil {
+		return dto.PhotoContent{}, fmt.Errorf("GetPhotoContentUC : %w", err)
+	}
+
+	if !ok {
+		content, err := p.content(photo, variant)
+		if err != nil {
+			return dto.PhotoContent{}, fmt.Errorf("GetPhotoContentUC : %w", err)
+		}
+
+		return content, nil
+	}
 
+	// transforms start from the original, they cannot be combined with a named variant
+	if variant != "" && variant != VariantOriginal {
+		return dto.PhotoContent{}, fmt.Errorf("GetPhotoContentUC : variant %q with transform : %w", variant, exception.InvalidErr)
 	}
 
-	return nil
+	key, err := p.transformer.render(photo.PhotoUrl, spec)
+	if err != nil {
+		return dto.PhotoContent{}, fmt.Errorf("GetPhotoContentUC : %w", err)
+	}
+
+	content, err := p.open(photo, key)
+	if err != nil {
+		return dto.PhotoContent{}, fmt.Errorf("GetPhotoContentUC : %w", err)
+	}
+
+	return content, nil
+}
+
+func (p *photosUCImpl) SharePhoto(userId string, photoId string, request dto.SharePhotoRequest) (dto.SharePhotoResponse, error) {
+	photo, err := p.photosRepository.FindById(photoId)
+	if err != nil || photo.UserId != userId {
+		return dto.SharePhotoResponse{}, exception.NotFoundErr
+	}
+
+	if !p.access.shareable(photo) {
+		return dto.SharePhotoResponse{}, fmt.Errorf("SharePhotoUC : photo is not visible : %w", exception.ForbiddenErr)
+	}
+
+	if request.Variant == VariantOriginal {
+		request.Variant = ""
+	}
+
+	if _, ok := photoVariants[request.Variant]; request.Variant != "" && request.Variant != VariantAvatar && !ok {
+		return dto.SharePhotoResponse{}, fmt.Errorf("SharePhotoUC : variant %q : %w", request.Variant, exception.InvalidErr)
+	}
+
+	url, expiresAt, err := p.urlSigner.SignPhotoUrl(photo.Id, request.Variant, time.Duration(request.ExpiresIn)*time.Second)
+	if err != nil {
+		return dto.SharePhotoResponse{}, fmt.Errorf("SharePhotoUC : %v : %w", err, exception.InvalidErr)
+	}
+
+	return dto.SharePhotoResponse{Url: url, ExpiresAt: expiresAt}, nil
+}
+
+// GetSharedPhotoContent serves a photo to anyone holding a valid signed link,
+// the signature stands in for the owner check. A link stops working while the photo
+// is taken down by moderation or its owner is deactivated.
+func (p *photosUCImpl) GetSharedPhotoContent(photoId string, variant string, expires string, signature string) (dto.PhotoContent, error) {
+	err := p.urlSigner.Verify(photoId, variant, expires, signature)
+	if err != nil {
+		return dto.PhotoContent{}, fmt.Errorf("GetSharedPhotoContentUC : %v : %w", err, exception.ForbiddenErr)
+	}
+
+	photo, err := p.photosRepository.FindById(photoId)
+	if err != nil || !p.access.shareable(photo) {
+		return dto.PhotoContent{}, exception.NotFoundErr
+	}
+
+	content, err := p.content(photo, variant)
+	if err != nil {
+		return dto.PhotoContent{}, fmt.Errorf("GetSharedPhotoContentUC : %w", err)
+	}
+
+	return content, nil
+}
+
+// GetSimilarPhotos lists the photos that look like the caller's photo, e.g. resized or
+// recompressed copies of it. Only photos the caller may view are considered: their own,
+// public ones and followers-only ones of users they follow, leaving out photos that are
+// taken down, of inactive owners or of users blocked either way.
+func (p *photosUCImpl) GetSimilarPhotos(userId string, photoId string, maxDistance int) ([]dto.SimilarPhotoResponse, error) {
+	photo, err := p.photosRepository.FindById(photoId)
+	if err != nil || photo.UserId != userId {
+		return nil, exception.NotFoundErr
+	}
+
+	if maxDistance < 0 || maxDistance > SimilarMaxDistance {
+		return nil, fmt.Errorf("GetSimilarPhotosUC : distance %d : %w", maxDistance, exception.InvalidErr)
+	}
+
+	// photos stored before hashing wait for RunAnalysisBackfill
+	if !photo.PerceptualHash.Valid {
+		return make([]dto.SimilarPhotoResponse, 0), nil
+	}
+
+	similar, err := p.photosRepository.FindSimilar(photo, userId, maxDistance, similarLimit)
+	if err != nil {
+		return nil, fmt.Errorf("GetSimilarPhotosUC : %w", err)
+	}
+
+	return mapping.MapSimilarPhotosToResponse(similar), nil
+}
+
+// uploadResponse maps a stored photo and warns about near-duplicates that already exist.
+// The lookup is best effort and never fails the upload.
+func (p *photosUCImpl) uploadResponse(photo entity.Photos) dto.PhotosResponse {
+	result := mapping.MapPhotosToResponse(photo)
+	if !photo.PerceptualHash.Valid {
+		return result
+	}
+
+	similar, err := p.photosRepository.FindSimilar(photo, photo.UserId, SimilarDefaultDistance, similarLimit)
+	if err != nil {
+		log.Println("similar photos :", err)
+		return result
+	}
+
+	if len(similar) > 0 {
+		result.SimilarPhotos = mapping.MapSimilarPhotosToResponse(similar)
+	}
+
+	return result
+}
+
+// analyze fills in what is derived from the pixels of the image stored at key: the
+// perceptual hash, the dimensions and the placeholders. Files that cannot be decoded
+// are stored without them, they never show up as near-duplicates.
+func (p *photosUCImpl) analyze(key string, photos entity.Photos) entity.Photos {
+	content, _, err := p.blobStore.Get(key)
+	if err != nil {
+		log.Println("analyze photo", key, ":", err)
+		return photos
+	}
+	defer content.Close()
+
+	analyzed, err := p.analyzeContent(content, photos)
+	if err != nil {
+		log.Println("analyze photo", key, ":", err)
+		return photos
+	}
+
+	return analyzed
+}
+
+// analyzeContent decodes a photo file and derives the values analyze stores.
+func (p *photosUCImpl) analyzeContent(content io.ReadSeeker, photos entity.Photos) (entity.Photos, error) {
+	img, err := p.transformer.decode(content)
+	if err != nil {
+		return photos, err
+	}
+
+	photos.PerceptualHash = sql.NullInt64{Int64: int64(imaging.DHash(img)), Valid: true}
+	photos.Width = img.Bounds().Dx()
+	photos.Height = img.Bounds().Dy()
+	photos.DominantColor = imaging.DominantColor(img)
+	// a photo without placeholder is still served, the blurhash is not worth failing for
+	photos.BlurHash, err = imaging.BlurHash(img, blurHashComponentsX, blurHashComponentsY)
+	if err != nil {
+		log.Println("analyze photo blurhash :", err)
+	}
+
+	return photos, nil
+}
+
+// GetUserPhotos lists the photos of userId that viewerId may see, newest first, viewerId
+// is empty for anonymous callers. The cursor follows every photo, so a page may hold
+// fewer than limit items when some are hidden from viewerId.
+func (p *photosUCImpl) GetUserPhotos(viewerId string, userId string, page dto.PageRequest) (dto.PhotoListResponse, error) {
+	keyset, limit, err := newKeyset(page)
+	if err != nil {
+		return dto.PhotoListResponse{}, fmt.Errorf("GetUserPhotosUC : %w", err)
+	}
+
+	// like the profile, the photos of a user that is gone or blocked either way are not found
+	user, err := p.userRepository.GetById(userId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.DeactivatedAt.Valid) {
+		return dto.PhotoListResponse{}, fmt.Errorf("GetUserPhotosUC : user %q : %w", userId, exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return dto.PhotoListResponse{}, fmt.Errorf("GetUserPhotosUC : %w", err)
+	}
+
+	if viewerId != "" {
+		blocked, err := p.access.blockRepository.IsBlocked(viewerId, userId)
+		if err != nil {
+			return dto.PhotoListResponse{}, fmt.Errorf("GetUserPhotosUC : %w", err)
+		}
+
+		if blocked {
+			return dto.PhotoListResponse{}, fmt.Errorf("GetUserPhotosUC : user %q : %w", userId, exception.NotFoundErr)
+		}
+	}
+
+	photos, err := p.photosRepository.FindPageByUserId(userId, viewerId, keyset)
+	if err != nil {
+		return dto.PhotoListResponse{}, fmt.Errorf("GetUserPhotosUC : %w", err)
+	}
+
+	response := dto.PhotoListResponse{Items: make([]dto.PhotosResponse, 0, len(photos)), Page: pageResponse(limit, nil)}
+	if len(photos) > limit {
+		photos = photos[:limit]
+		last := photos[limit-1]
+		response.Page = pageResponse(limit, &pageCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	}
+
+	for _, photo := range photos {
+		if p.access.canView(viewerId, photo) {
+			response.Items = append(response.Items, mapping.MapPhotosToResponse(photo))
+		}
+	}
+
+	return response, nil
+}
+
+// details validates the visibility and tags of photos, what is left empty is taken from current.
+func (p *photosUCImpl) details(photos entity.Photos, current entity.Photos) (entity.Photos, error) {
+	switch photos.Visibility {
+	case "":
+		photos.Visibility = current.Visibility
+	case entity.VisibilityPublic, entity.VisibilityFollowers, entity.VisibilityPrivate:
+	default:
+		return entity.Photos{}, fmt.Errorf("visibility %q : %w", photos.Visibility, exception.InvalidErr)
+	}
+
+	var err error
+	photos.Tags, err = normalizeTags(photos.Tags)
+	if err != nil {
+		return entity.Photos{}, err
+	}
+
+	return photos, nil
+}
+
+// replaceTags stores the tags requested in photos, when there are any, and returns the tags stored.
+// The row returned by an insert or update reads the tags from before the statement.
+func (p *photosUCImpl) replaceTags(tx *sql.Tx, photos entity.Photos, stored entity.Photos) ([]string, error) {
+	if photos.Tags == nil {
+		return stored.Tags, nil
+	}
+
+	err := p.tagRepository.WithTx(tx).ReplacePhotoTags(stored.Id, photos.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return photos.Tags, nil
+}
+
+func (p *photosUCImpl) content(photo entity.Photos, variant string) (dto.PhotoContent, error) {
+	var err error
+	key := photo.PhotoUrl
+	if variant == VariantAvatar {
+		key, err = p.transformer.avatar(photo.PhotoUrl, photo.Crop)
+		if err != nil {
+			return dto.PhotoContent{}, err
+		}
+	} else if variant != "" && variant != VariantOriginal {
+		width, ok := photoVariants[variant]
+		if !ok {
+			return dto.PhotoContent{}, fmt.Errorf("variant %q : %w", variant, exception.InvalidErr)
+		}
+
+		key, err = p.transformer.variant(photo.PhotoUrl, variant, width)
+		if err != nil {
+			return dto.PhotoContent{}, err
+		}
+	}
+
+	return p.open(photo, key)
+}
+
+// open reads the file of photo, or one of its renditions, stored at key.
+func (p *photosUCImpl) open(photo entity.Photos, key string) (dto.PhotoContent, error) {
+	content, info, err := p.blobStore.Get(key)
+	if err != nil {
+		return dto.PhotoContent{}, err
+	}
+
+	// the type is never derived from the key or from what the uploader claimed
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		content.Close()
+		return dto.PhotoContent{}, err
+	}
+
+	_, err = content.Seek(0, io.SeekStart)
+	if err != nil {
+		content.Close()
+		return dto.PhotoContent{}, err
+	}
+
+	hash := ""
+	etag := p.etag(key)
+	if key == photo.PhotoUrl && photo.BlobHash != "" {
+		hash = photo.BlobHash
+		etag = fmt.Sprintf("\"%s\"", photo.BlobHash)
+	}
+	contentType := p.blobs.servedType(hash, head[:n])
+
+	return dto.PhotoContent{
+		Content:      content,
+		ContentType:  contentType,
+		Size:         info.Size,
+		ETag:         etag,
+		LastModified: info.LastModified,
+	}, nil
 }
 
-func NewPhotosUC(photosRepository repository.PhotosRepository) PhotosUC {
-	return &photosUCImpl{photosRepository: photosRepository}
+// etag is derived from the object key of a rendition. Renditions are never
+// regenerated under the same key with different bytes, so the tag is strong.
+func (p *photosUCImpl) etag(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("\"%s\"", hex.EncodeToString(sum[:16]))
+}
+
+func NewPhotosUC(photosRepository repository.PhotosRepository, blobRepository repository.BlobRepository, tagRepository repository.TagRepository, versionRepository repository.PhotoVersionRepository, followRepository repository.FollowRepository, blockRepository repository.BlockRepository,
+	reportRepository repository.ReportRepository, actionRepository repository.ModerationActionRepository, usageRepository repository.UsageRepository, userRepository repository.UserRepository, transactor repository.Transactor, blobStore storage.BlobStore,
+	urlSigner service.UrlSignerService, classifier service.ContentClassifier, transformConfig config.TransformConfig, trashConfig config.TrashConfig, quotaConfig config.QuotaConfig) PhotosUC {
+	blobs := newBlobManager(blobRepository, transactor, blobStore)
+	return &photosUCImpl{
+		photosRepository:  photosRepository,
+		tagRepository:     tagRepository,
+		versionRepository: versionRepository,
+		reportRepository:  reportRepository,
+		actionRepository:  actionRepository,
+		userRepository:    userRepository,
+		transactor:        transactor,
+		access:            newPhotoAccess(photosRepository, followRepository, blockRepository),
+		quota:             newPhotoQuota(usageRepository, userRepository, blobStore, quotaConfig),
+		blobs:             blobs,
+		transformer:       newPhotoTransformer(blobs, blobStore, transformConfig),
+		blobStore:         blobStore,
+		urlSigner:         urlSigner,
+		classifier:        classifier,
+		trashConfig:       trashConfig,
+	}
 }
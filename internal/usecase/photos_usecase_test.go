@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/pkg/util/exception"
+)
+
+func TestFitCrop(t *testing.T) {
+	tests := []struct {
+		name string
+		crop dto.PhotoCropRequest
+		want entity.PhotoCrop
+		err  error
+	}{
+		{name: "rectangle inside", crop: dto.PhotoCropRequest{Rect: true, X: 10, Y: 20, Width: 100, Height: 50}, want: entity.PhotoCrop{X: 10, Y: 20, Width: 100, Height: 50}},
+		{name: "rectangle to the edges", crop: dto.PhotoCropRequest{Rect: true, X: 0, Y: 0, Width: 400, Height: 300}, want: entity.PhotoCrop{Width: 400, Height: 300}},
+		{name: "rectangle past the right edge", crop: dto.PhotoCropRequest{Rect: true, X: 301, Y: 0, Width: 100, Height: 100}, err: exception.InvalidErr},
+		{name: "negative origin", crop: dto.PhotoCropRequest{Rect: true, X: -1, Y: 0, Width: 10, Height: 10}, err: exception.InvalidErr},
+		{name: "empty rectangle", crop: dto.PhotoCropRequest{Rect: true, X: 0, Y: 0, Width: 0, Height: 10}, err: exception.InvalidErr},
+		{name: "width overflows", crop: dto.PhotoCropRequest{Rect: true, X: 1, Y: 0, Width: math.MaxInt, Height: 10}, err: exception.InvalidErr},
+		{name: "height overflows", crop: dto.PhotoCropRequest{Rect: true, X: 0, Y: math.MaxInt, Width: 10, Height: math.MaxInt}, err: exception.InvalidErr},
+		{name: "focal point in the centre", crop: dto.PhotoCropRequest{Focal: true, FocalX: 0.5, FocalY: 0.5}, want: entity.PhotoCrop{X: 50, Y: 0, Width: 300, Height: 300}},
+		{name: "focal point at the corner", crop: dto.PhotoCropRequest{Focal: true, FocalX: 1, FocalY: 1}, want: entity.PhotoCrop{X: 100, Y: 0, Width: 300, Height: 300}},
+		{name: "focal point outside", crop: dto.PhotoCropRequest{Focal: true, FocalX: 1.5, FocalY: 0}, err: exception.InvalidErr},
+		{name: "focal point not a number", crop: dto.PhotoCropRequest{Focal: true, FocalX: math.NaN(), FocalY: 0}, err: exception.InvalidErr},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := fitCrop(test.crop, 400, 300)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			if got != test.want {
+				t.Fatalf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"user-personalize/internal/config"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/storage"
+)
+
+// ReconcilerUC finds files without a photo row and photo rows without a file.
+type ReconcilerUC interface {
+	Reconcile() (dto.ReconcileReport, error)
+	Run()
+}
+
+type reconcilerUCImpl struct {
+	photosRepository repository.PhotosRepository
+	blobRepository   repository.BlobRepository
+	transactor       repository.Transactor
+	blobs            *blobManager
+	blobStore        storage.BlobStore
+	cfg              config.ReconcileConfig
+}
+
+func NewReconcilerUC(photosRepository repository.PhotosRepository, blobRepository repository.BlobRepository, transactor repository.Transactor, blobStore storage.BlobStore, cfg config.ReconcileConfig) ReconcilerUC {
+	return &reconcilerUCImpl{
+		photosRepository: photosRepository,
+		blobRepository:   blobRepository,
+		transactor:       transactor,
+		blobs:            newBlobManager(blobRepository, transactor, blobStore),
+		blobStore:        blobStore,
+		cfg:              cfg,
+	}
+}
+
+// Run reconciles every cfg.Interval until the process exits, it is meant to be started in
+// its own goroutine. A negative interval disables it.
+func (r *reconcilerUCImpl) Run() {
+	if r.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		report, err := r.Reconcile()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		if len(report.OrphanFiles) > 0 || len(report.StagedFiles) > 0 || len(report.MissingFiles) > 0 {
+			log.Printf("reconcile : orphan files %v, staged files %v, photos without file %v, deleted %t", report.OrphanFiles, report.StagedFiles, report.MissingFiles, report.Deleted)
+		}
+	}
+}
+
+// reconcileBatch is the number of objects or photo rows the reconciler reads at a time.
+const reconcileBatch = 500
+
+// Reconcile only looks at objects and rows older than the grace period, so uploads
+// that are between their commit and promote steps are left alone. Storage and photos
+// are read page by page. Photos stored before content addressing are left to the
+// legacy migration: their files are not listed and their rows are never reported.
+func (r *reconcilerUCImpl) Reconcile() (dto.ReconcileReport, error) {
+	report := dto.ReconcileReport{
+		OrphanFiles:  make([]string, 0),
+		StagedFiles:  make([]string, 0),
+		MissingFiles: make([]string, 0),
+		Deleted:      r.cfg.DeleteOrphans,
+	}
+	cutoff := time.Now().Add(-r.cfg.GracePeriod)
+
+	err := r.eachPage(blobPrefix, func(files []storage.BlobInfo) error {
+		keys := make([]string, 0, len(files))
+		for _, file := range files {
+			keys = append(keys, file.Key)
+		}
+
+		return r.collectOrphans(&report, files, keys, cutoff)
+	})
+	if err != nil {
+		return dto.ReconcileReport{}, fmt.Errorf("ReconcileUC : %w", err)
+	}
+
+	// variants of a legacy file are left to the migration, which removes them with the file
+	err = r.eachPage(variantsPrefix, func(files []storage.BlobInfo) error {
+		variants := make([]storage.BlobInfo, 0, len(files))
+		sources := make([]string, 0, len(files))
+		for _, variant := range files {
+			source, ok := r.blobs.variantSource(variant.Key)
+			if !ok {
+				continue
+			}
+
+			variants = append(variants, variant)
+			sources = append(sources, source)
+		}
+
+		return r.collectOrphans(&report, variants, sources, cutoff)
+	})
+	if err != nil {
+		return dto.ReconcileReport{}, fmt.Errorf("ReconcileUC : %w", err)
+	}
+
+	err = r.eachPage(stagingPrefix, func(staged []storage.BlobInfo) error {
+		for _, file := range staged {
+			if file.LastModified.Before(cutoff) {
+				report.StagedFiles = append(report.StagedFiles, file.Key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return dto.ReconcileReport{}, fmt.Errorf("ReconcileUC : %w", err)
+	}
+
+	missing, err := r.findMissing(&report, cutoff)
+	if err != nil {
+		return dto.ReconcileReport{}, fmt.Errorf("ReconcileUC : %w", err)
+	}
+
+	if !r.cfg.DeleteOrphans {
+		return report, nil
+	}
+
+	for _, key := range append(report.OrphanFiles, report.StagedFiles...) {
+		err = r.blobStore.Delete(key)
+		if err != nil {
+			log.Println("ReconcileUC :", err)
+		}
+	}
+
+	for _, photo := range missing {
+		err = r.transactor.WithinTransaction(func(tx *sql.Tx) error {
+			err := r.photosRepository.WithTx(tx).Delete(photo.Id)
+			if err != nil {
+				return err
+			}
+
+			_, _, err = r.blobs.release(tx, photo.BlobHash)
+			return err
+		})
+		if err != nil {
+			log.Println("ReconcileUC :", err)
+		}
+	}
+
+	return report, nil
+}
+
+// eachPage calls fn with the objects under prefix, reconcileBatch at a time.
+func (r *reconcilerUCImpl) eachPage(prefix string, fn func(files []storage.BlobInfo) error) error {
+	startAfter := ""
+	for {
+		files, err := r.blobStore.ListPage(prefix, startAfter, reconcileBatch)
+		if err != nil {
+			return err
+		}
+
+		if len(files) > 0 {
+			err = fn(files)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(files) < reconcileBatch {
+			return nil
+		}
+		startAfter = files[len(files)-1].Key
+	}
+}
+
+// collectOrphans reports the files whose blob, keys[i] for files[i], is not stored.
+func (r *reconcilerUCImpl) collectOrphans(report *dto.ReconcileReport, files []storage.BlobInfo, keys []string, cutoff time.Time) error {
+	blobs, err := r.blobRepository.FindByObjectKeys(keys)
+	if err != nil {
+		return err
+	}
+
+	stored := make(map[string]bool, len(blobs))
+	for _, blob := range blobs {
+		stored[blob.ObjectKey] = true
+	}
+
+	for i, file := range files {
+		if !stored[keys[i]] && file.LastModified.Before(cutoff) {
+			report.OrphanFiles = append(report.OrphanFiles, file.Key)
+		}
+	}
+
+	return nil
+}
+
+// findMissing reports the photos, in and out of the trash, whose blob has no file.
+func (r *reconcilerUCImpl) findMissing(report *dto.ReconcileReport, cutoff time.Time) ([]entity.Photos, error) {
+	missing := make([]entity.Photos, 0)
+	afterId := ""
+	for {
+		photos, err := r.photosRepository.FindAllAfter(afterId, reconcileBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		// photos share blobs, each file is looked at once per batch
+		exists := make(map[string]bool)
+		for _, photo := range photos {
+			if photo.BlobHash == "" || !photo.UpdatedAt.Before(cutoff) {
+				continue
+			}
+
+			found, checked := exists[photo.PhotoUrl]
+			if !checked {
+				_, err = r.blobStore.Stat(photo.PhotoUrl)
+				if err != nil && !errors.Is(err, exception.NotFoundErr) {
+					return nil, err
+				}
+				found = err == nil
+				exists[photo.PhotoUrl] = found
+			}
+
+			// a legacy photo adopts its blob before the file is promoted, updated_at stays old
+			if !found && r.blobCreatedBefore(photo.BlobHash, cutoff) {
+				report.MissingFiles = append(report.MissingFiles, photo.Id)
+				missing = append(missing, photo)
+			}
+		}
+
+		if len(photos) < reconcileBatch {
+			return missing, nil
+		}
+		afterId = photos[len(photos)-1].Id
+	}
+}
+
+// blobCreatedBefore tells whether the blob row of hash is older than cutoff, a blob that
+// cannot be read is treated as new so that nothing is removed on its account.
+func (r *reconcilerUCImpl) blobCreatedBefore(hash string, cutoff time.Time) bool {
+	blob, err := r.blobRepository.FindByHash(hash)
+	if err != nil {
+		log.Println("ReconcileUC :", err)
+		return false
+	}
+
+	return blob.CreatedAt.Before(cutoff)
+}
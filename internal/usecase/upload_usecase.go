@@ -0,0 +1,582 @@
+package usecase
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"user-personalize/internal/config"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/storage"
+)
+
+// UploadUC implements resumable uploads (tus 1.0 core, creation and expiration).
+// Every PATCH is stored as its own chunk under "uploads/<uploadId>/", and once
+// the last byte arrives the chunks are handed to PhotosUC as a single file.
+//
+// It also hands out presigned upload forms so clients can post large files straight into
+// storage, CompleteUpload then turns the object into a photo.
+type UploadUC interface {
+	CreateUpload(userId string, length int64, metadata string) (dto.UploadResponse, error)
+	GetUpload(userId string, uploadId string) (dto.UploadResponse, error)
+	AppendChunk(userId string, uploadId string, offset int64, chunk io.Reader) (dto.UploadResponse, error)
+	CreateUploadUrl(userId string) (dto.UploadUrlResponse, error)
+	CompleteUpload(userId string, request dto.CompleteUploadRequest) (dto.PhotosResponse, error)
+	CollectExpired() (int, error)
+	DiscardUserUploads(userId string) (int, error)
+	RunGarbageCollector()
+}
+
+type uploadUCImpl struct {
+	uploadRepository       repository.UploadRepository
+	directUploadRepository repository.DirectUploadRepository
+	transactor             repository.Transactor
+	blobStore              storage.BlobStore
+	photosUC               PhotosUC
+	validate               *validator.Validate
+	cfg                    config.UploadConfig
+}
+
+func NewUploadUC(uploadRepository repository.UploadRepository, directUploadRepository repository.DirectUploadRepository, transactor repository.Transactor, blobStore storage.BlobStore, photosUC PhotosUC, validate *validator.Validate, cfg config.UploadConfig) UploadUC {
+	return &uploadUCImpl{uploadRepository: uploadRepository, directUploadRepository: directUploadRepository, transactor: transactor, blobStore: blobStore, photosUC: photosUC, validate: validate, cfg: cfg}
+}
+
+func (u *uploadUCImpl) CreateUpload(userId string, length int64, metadata string) (dto.UploadResponse, error) {
+	if length <= 0 {
+		return dto.UploadResponse{}, fmt.Errorf("CreateUploadUC : upload length %d : %w", length, exception.InvalidErr)
+	}
+
+	if length > u.cfg.MaxSize {
+		return dto.UploadResponse{}, fmt.Errorf("CreateUploadUC : upload length %d : %w", length, exception.TooLargeErr)
+	}
+
+	_, err := u.parseMetadata(metadata)
+	if err != nil {
+		return dto.UploadResponse{}, fmt.Errorf("CreateUploadUC : %v : %w", err, exception.InvalidErr)
+	}
+
+	upload, err := u.uploadRepository.Insert(entity.Upload{
+		Id:        uuid.NewString(),
+		UserId:    userId,
+		Length:    length,
+		Metadata:  metadata,
+		ExpiresAt: time.Now().Add(u.cfg.ExpiredTime),
+	})
+	if err != nil {
+		return dto.UploadResponse{}, fmt.Errorf("CreateUploadUC : %w", err)
+	}
+
+	return u.response(upload), nil
+}
+
+func (u *uploadUCImpl) GetUpload(userId string, uploadId string) (dto.UploadResponse, error) {
+	upload, err := u.find(userId, uploadId)
+	if err != nil {
+		return dto.UploadResponse{}, err
+	}
+
+	return u.response(upload), nil
+}
+
+// AppendChunk stores the bytes of one PATCH request. The body is written to a staging
+// key first, without holding any lock, and only moved to its chunk key once the locked
+// upload row still has the offset the request started from. A concurrent PATCH at the
+// same offset loses with a conflict and its staged bytes are dropped.
+func (u *uploadUCImpl) AppendChunk(userId string, uploadId string, offset int64, chunk io.Reader) (dto.UploadResponse, error) {
+	upload, err := u.find(userId, uploadId)
+	if err != nil {
+		return dto.UploadResponse{}, fmt.Errorf("AppendChunkUC : %w", err)
+	}
+
+	if upload.Offset != offset {
+		return dto.UploadResponse{}, fmt.Errorf("AppendChunkUC : upload offset is %d, got %d : %w", upload.Offset, offset, exception.ConflictErr)
+	}
+
+	if upload.Offset < upload.Length {
+		upload, err = u.appendChunk(upload, chunk)
+		if err != nil {
+			return dto.UploadResponse{}, fmt.Errorf("AppendChunkUC : %w", err)
+		}
+	}
+
+	if upload.Offset == upload.Length && upload.PhotoId == "" {
+		upload, err = u.complete(upload)
+		if err != nil {
+			return u.response(upload), fmt.Errorf("AppendChunkUC : %w", err)
+		}
+	}
+
+	return u.response(upload), nil
+}
+
+// appendChunk stages the body and commits it as the chunk at current.Offset.
+func (u *uploadUCImpl) appendChunk(current entity.Upload, chunk io.Reader) (entity.Upload, error) {
+	// keep whatever arrived before the connection dropped, the client resumes from there
+	reader := &partialReader{reader: io.LimitReader(chunk, current.Length-current.Offset)}
+	stagedKey := stagingPrefix + uuid.NewString()
+	// the size is unknown, a PATCH may carry any part of what is left
+	info, err := u.blobStore.Put(stagedKey, reader, -1, "application/offset+octet-stream")
+	if err != nil {
+		return current, err
+	}
+
+	if info.Size == 0 {
+		u.discardStaged(stagedKey)
+		if reader.err != nil {
+			return current, reader.err
+		}
+		return current, nil
+	}
+
+	var upload entity.Upload
+	err = u.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		uploadRepository := u.uploadRepository.WithTx(tx)
+
+		locked, err := uploadRepository.FindByIdForUpdate(current.Id)
+		if err != nil {
+			return exception.NotFoundErr
+		}
+
+		if locked.Offset != current.Offset {
+			return fmt.Errorf("upload offset is %d, got %d : %w", locked.Offset, current.Offset, exception.ConflictErr)
+		}
+
+		// a chunk left by a move whose commit failed is overwritten here
+		err = u.blobStore.Move(stagedKey, u.chunkKey(locked.Id, locked.Offset))
+		if err != nil {
+			return err
+		}
+
+		upload, err = uploadRepository.UpdateOffset(locked.Id, locked.Offset+info.Size, time.Now().Add(u.cfg.ExpiredTime))
+		return err
+	})
+	if err != nil {
+		u.discardStaged(stagedKey)
+		return current, err
+	}
+
+	if reader.err != nil {
+		log.Println("AppendChunkUC : stored partial chunk :", reader.err)
+	}
+
+	return upload, nil
+}
+
+// discardStaged removes a staged chunk that was not committed, it is gone already when
+// the move went through.
+func (u *uploadUCImpl) discardStaged(key string) {
+	err := u.blobStore.Delete(key)
+	if err != nil && !errors.Is(err, exception.NotFoundErr) {
+		log.Println("discard staged chunk :", err)
+	}
+}
+
+// complete turns the chunks into a photo. With a photo_id metadata entry the
+// existing photo is replaced, otherwise a new one is created.
+func (u *uploadUCImpl) complete(upload entity.Upload) (entity.Upload, error) {
+	metadata, err := u.parseMetadata(upload.Metadata)
+	if err != nil {
+		return upload, fmt.Errorf("%v : %w", err, exception.InvalidErr)
+	}
+
+	chunks, err := u.blobStore.List(u.chunkPrefix(upload.Id))
+	if err != nil {
+		return upload, err
+	}
+
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Key < chunks[j].Key
+	})
+
+	content := &chunkReader{blobStore: u.blobStore, chunks: chunks}
+	defer content.Close()
+
+	photos := entity.Photos{
+		Id:         metadata["photo_id"],
+		Title:      metadata["title"],
+		Caption:    metadata["caption"],
+		Visibility: metadata["visibility"],
+		UserId:     upload.UserId,
+	}
+	file := dto.PhotoFile{
+		Filename:    metadata["filename"],
+		ContentType: metadata["filetype"],
+		Size:        upload.Length,
+		Content:     content,
+	}
+
+	var photo dto.PhotosResponse
+	if photos.Id != "" {
+		photo, err = u.photosUC.UpdatePhotos(photos, file, dto.PhotoCropRequest{})
+	} else {
+		_, err = u.photosUC.GetPhotosByUserId(upload.UserId)
+		if err == nil {
+			return upload, fmt.Errorf("photo already exists : %w", exception.ConflictErr)
+		}
+
+		photo, err = u.photosUC.SavePhotos(photos, file, dto.PhotoCropRequest{})
+	}
+	if err != nil {
+		return upload, err
+	}
+
+	err = u.uploadRepository.UpdatePhotoId(upload.Id, photo.Id)
+	if err != nil {
+		return upload, err
+	}
+	upload.PhotoId = photo.Id
+
+	u.deleteChunks(upload.Id)
+	return upload, nil
+}
+
+// CreateUploadUrl reserves a key under directUploadPrefix and presigns a POST form for it
+// that the storage only accepts up to the upload size limit. The object only becomes a
+// photo once CompleteUpload has checked it.
+func (u *uploadUCImpl) CreateUploadUrl(userId string) (dto.UploadUrlResponse, error) {
+	presigner, ok := u.blobStore.(storage.Presigner)
+	if !ok {
+		return dto.UploadUrlResponse{}, fmt.Errorf("CreateUploadUrlUC : %w", exception.UnsupportedErr)
+	}
+
+	id := uuid.NewString()
+	upload, err := u.directUploadRepository.Insert(entity.DirectUpload{
+		Id:        id,
+		UserId:    userId,
+		ObjectKey: directUploadPrefix + id,
+		ExpiresAt: time.Now().Add(u.cfg.ExpiredTime),
+	})
+	if err != nil {
+		return dto.UploadUrlResponse{}, fmt.Errorf("CreateUploadUrlUC : %w", err)
+	}
+
+	form, err := presigner.PresignPost(upload.ObjectKey, u.cfg.MaxSize, u.cfg.PresignExpiredTime)
+	if err != nil {
+		return dto.UploadUrlResponse{}, fmt.Errorf("CreateUploadUrlUC : %w", err)
+	}
+
+	return dto.UploadUrlResponse{
+		UploadId:  upload.Id,
+		Url:       form.Url,
+		Method:    http.MethodPost,
+		Fields:    form.Fields,
+		ExpiresAt: time.Now().Add(u.cfg.PresignExpiredTime),
+	}, nil
+}
+
+func (u *uploadUCImpl) CompleteUpload(userId string, request dto.CompleteUploadRequest) (dto.PhotosResponse, error) {
+	err := u.validate.Struct(request)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("CompleteUploadUC : %v : %w", err, exception.InvalidErr)
+	}
+
+	upload, err := u.directUploadRepository.FindById(request.UploadId)
+	if err != nil || upload.UserId != userId {
+		return dto.PhotosResponse{}, exception.NotFoundErr
+	}
+
+	if time.Now().After(upload.ExpiresAt) {
+		return dto.PhotosResponse{}, exception.ExpiredErr
+	}
+
+	info, err := u.blobStore.Stat(upload.ObjectKey)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("CompleteUploadUC : %w", err)
+	}
+
+	if info.Size > u.cfg.MaxSize {
+		u.discardDirectUpload(upload)
+		return dto.PhotosResponse{}, fmt.Errorf("CompleteUploadUC : object size %d : %w", info.Size, exception.TooLargeErr)
+	}
+
+	err = u.sniffPhotoType(upload.ObjectKey)
+	if err != nil {
+		u.discardDirectUpload(upload)
+		return dto.PhotosResponse{}, fmt.Errorf("CompleteUploadUC : %w", err)
+	}
+
+	_, err = u.photosUC.GetPhotosByUserId(userId)
+	if err == nil {
+		return dto.PhotosResponse{}, fmt.Errorf("CompleteUploadUC : photo already exists : %w", exception.ConflictErr)
+	}
+
+	photo, err := u.photosUC.SaveStagedPhotos(entity.Photos{
+		Title:      request.Title,
+		Caption:    request.Caption,
+		Visibility: request.Visibility,
+		UserId:     userId,
+	}, upload.ObjectKey)
+	if err != nil {
+		return dto.PhotosResponse{}, fmt.Errorf("CompleteUploadUC : %w", err)
+	}
+
+	err = u.directUploadRepository.Delete(upload.Id)
+	if err != nil {
+		log.Println("CompleteUploadUC :", err)
+	}
+
+	return photo, nil
+}
+
+// sniffPhotoType looks at the first bytes instead of trusting the content type the client sent,
+// so that an upload that is not a photo is discarded before it is hashed.
+func (u *uploadUCImpl) sniffPhotoType(key string) error {
+	content, _, err := u.blobStore.Get(key)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	if _, ok := allowedPhotoTypes[contentType]; !ok {
+		return fmt.Errorf("content type %q : %w", contentType, exception.UnsupportedErr)
+	}
+
+	return nil
+}
+
+func (u *uploadUCImpl) discardDirectUpload(upload entity.DirectUpload) {
+	err := u.blobStore.Delete(upload.ObjectKey)
+	if err != nil && !errors.Is(err, exception.NotFoundErr) {
+		log.Println("discard direct upload :", err)
+	}
+
+	err = u.directUploadRepository.Delete(upload.Id)
+	if err != nil {
+		log.Println("discard direct upload :", err)
+	}
+}
+
+// CollectExpired removes uploads that were abandoned or finished before their expiry.
+func (u *uploadUCImpl) CollectExpired() (int, error) {
+	uploads, err := u.uploadRepository.FindExpired(time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("CollectExpiredUploadUC : %w", err)
+	}
+
+	for _, upload := range uploads {
+		u.deleteChunks(upload.Id)
+
+		err = u.uploadRepository.Delete(upload.Id)
+		if err != nil {
+			return 0, fmt.Errorf("CollectExpiredUploadUC : %w", err)
+		}
+	}
+
+	directUploads, err := u.directUploadRepository.FindExpired(time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("CollectExpiredUploadUC : %w", err)
+	}
+
+	for _, upload := range directUploads {
+		u.discardDirectUpload(upload)
+	}
+
+	return len(uploads) + len(directUploads), nil
+}
+
+// DiscardUserUploads removes every unfinished upload of a user together with its chunks
+// or staged object and returns how many it removed.
+func (u *uploadUCImpl) DiscardUserUploads(userId string) (int, error) {
+	uploads, err := u.uploadRepository.FindByUserId(userId)
+	if err != nil {
+		return 0, fmt.Errorf("DiscardUserUploadsUC : %w", err)
+	}
+
+	for _, upload := range uploads {
+		u.deleteChunks(upload.Id)
+
+		err = u.uploadRepository.Delete(upload.Id)
+		if err != nil {
+			return 0, fmt.Errorf("DiscardUserUploadsUC : %w", err)
+		}
+	}
+
+	directUploads, err := u.directUploadRepository.FindByUserId(userId)
+	if err != nil {
+		return 0, fmt.Errorf("DiscardUserUploadsUC : %w", err)
+	}
+
+	for _, upload := range directUploads {
+		u.discardDirectUpload(upload)
+	}
+
+	return len(uploads) + len(directUploads), nil
+}
+
+// RunGarbageCollector is meant to be started in its own goroutine, a negative interval
+// disables it.
+func (u *uploadUCImpl) RunGarbageCollector() {
+	if u.cfg.GcInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(u.cfg.GcInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		collected, err := u.CollectExpired()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		if collected > 0 {
+			log.Printf("upload gc : removed %d expired uploads", collected)
+		}
+	}
+}
+
+func (u *uploadUCImpl) find(userId string, uploadId string) (entity.Upload, error) {
+	upload, err := u.uploadRepository.FindById(uploadId)
+	if err != nil || upload.UserId != userId {
+		return entity.Upload{}, exception.NotFoundErr
+	}
+
+	if time.Now().After(upload.ExpiresAt) {
+		return entity.Upload{}, exception.ExpiredErr
+	}
+
+	return upload, nil
+}
+
+func (u *uploadUCImpl) deleteChunks(uploadId string) {
+	chunks, err := u.blobStore.List(u.chunkPrefix(uploadId))
+	if err != nil {
+		log.Println("delete upload chunks :", err)
+		return
+	}
+
+	for _, chunk := range chunks {
+		err = u.blobStore.Delete(chunk.Key)
+		if err != nil && !errors.Is(err, exception.NotFoundErr) {
+			log.Println("delete upload chunks :", err)
+		}
+	}
+}
+
+func (u *uploadUCImpl) chunkPrefix(uploadId string) string {
+	return "uploads/" + uploadId + "/"
+}
+
+// chunkKey zero pads the offset so chunks sort in upload order.
+func (u *uploadUCImpl) chunkKey(uploadId string, offset int64) string {
+	return fmt.Sprintf("%s%020d", u.chunkPrefix(uploadId), offset)
+}
+
+// parseMetadata decodes an Upload-Metadata header: "key base64value,key base64value".
+func (u *uploadUCImpl) parseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, fmt.Errorf("upload metadata %q is malformed", pair)
+		}
+
+		value := ""
+		if len(parts) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("upload metadata %q is not base64", parts[0])
+			}
+			value = string(decoded)
+		}
+
+		metadata[parts[0]] = value
+	}
+
+	return metadata, nil
+}
+
+func (u *uploadUCImpl) response(upload entity.Upload) dto.UploadResponse {
+	return dto.UploadResponse{
+		Id:        upload.Id,
+		Length:    upload.Length,
+		Offset:    upload.Offset,
+		Metadata:  upload.Metadata,
+		PhotoId:   upload.PhotoId,
+		ExpiresAt: upload.ExpiresAt,
+	}
+}
+
+// partialReader turns a read error into EOF so the bytes received so far can be stored.
+type partialReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (p *partialReader) Read(buf []byte) (int, error) {
+	n, err := p.reader.Read(buf)
+	if err != nil && err != io.EOF {
+		p.err = err
+		return n, io.EOF
+	}
+
+	return n, err
+}
+
+// chunkReader reads the chunks of an upload one after the other, opening each lazily.
+type chunkReader struct {
+	blobStore storage.BlobStore
+	chunks    []storage.BlobInfo
+	current   io.ReadCloser
+}
+
+func (c *chunkReader) Read(buf []byte) (int, error) {
+	for {
+		if c.current == nil {
+			if len(c.chunks) == 0 {
+				return 0, io.EOF
+			}
+
+			content, _, err := c.blobStore.Get(c.chunks[0].Key)
+			if err != nil {
+				return 0, err
+			}
+
+			c.current = content
+			c.chunks = c.chunks[1:]
+		}
+
+		n, err := c.current.Read(buf)
+		if err == io.EOF {
+			c.current.Close()
+			c.current = nil
+			if n == 0 {
+				continue
+			}
+			return n, nil
+		}
+
+		return n, err
+	}
+}
+
+func (c *chunkReader) Close() error {
+	if c.current != nil {
+		return c.current.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+	"user-personalize/internal/config"
+	"user-personalize/internal/model/entity"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/storage"
+)
+
+// fakeUploadRepository keeps one upload in memory. raced is added to the offset when the
+// row is locked, as if another PATCH had committed between the read and the lock.
+type fakeUploadRepository struct {
+	repository.UploadRepository
+	upload entity.Upload
+	raced  int64
+}
+
+func (f *fakeUploadRepository) WithTx(tx *sql.Tx) repository.UploadRepository { return f }
+
+func (f *fakeUploadRepository) FindById(id string) (entity.Upload, error) {
+	if id != f.upload.Id {
+		return entity.Upload{}, sql.ErrNoRows
+	}
+
+	return f.upload, nil
+}
+
+func (f *fakeUploadRepository) FindByIdForUpdate(id string) (entity.Upload, error) {
+	f.upload.Offset += f.raced
+	f.raced = 0
+	return f.FindById(id)
+}
+
+func (f *fakeUploadRepository) UpdateOffset(id string, offset int64, expiresAt time.Time) (entity.Upload, error) {
+	f.upload.Offset = offset
+	f.upload.ExpiresAt = expiresAt
+	return f.upload, nil
+}
+
+// fakeTransactor runs the function without a transaction, the fakes do not use one.
+type fakeTransactor struct{}
+
+func (fakeTransactor) WithinTransaction(fn func(tx *sql.Tx) error) error { return fn(nil) }
+
+func newUploadTestUC(t *testing.T, offset int64) (*uploadUCImpl, *fakeUploadRepository, storage.BlobStore) {
+	blobStore, err := storage.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// with a photo id set the last byte does not complete the upload, which is not under test
+	uploadRepository := &fakeUploadRepository{upload: entity.Upload{Id: "upload", UserId: "owner", Length: 10, Offset: offset, PhotoId: "photo", ExpiresAt: time.Now().Add(time.Hour)}}
+	return &uploadUCImpl{
+		uploadRepository: uploadRepository,
+		transactor:       fakeTransactor{},
+		blobStore:        blobStore,
+		cfg:              config.UploadConfig{ExpiredTime: time.Hour},
+	}, uploadRepository, blobStore
+}
+
+func TestAppendChunk(t *testing.T) {
+	dropped := errors.New("connection reset")
+
+	tests := []struct {
+		name       string
+		offset     int64
+		raced      int64
+		body       io.Reader
+		err        error
+		wantOffset int64
+		wantChunks []string
+	}{
+		{name: "whole chunk", offset: 4, body: strings.NewReader("abc"), wantOffset: 7, wantChunks: []string{"abc"}},
+		{name: "body longer than the upload", offset: 4, body: strings.NewReader("abcdefghij"), wantOffset: 10, wantChunks: []string{"abcdef"}},
+		{name: "offset behind the upload", offset: 2, body: strings.NewReader("abc"), err: exception.ConflictErr, wantOffset: 4},
+		{name: "concurrent patch at the same offset", offset: 4, raced: 3, body: strings.NewReader("abc"), err: exception.ConflictErr, wantOffset: 7},
+		{name: "connection dropped after some bytes", offset: 4, body: io.MultiReader(strings.NewReader("ab"), iotest.ErrReader(dropped)), wantOffset: 6, wantChunks: []string{"ab"}},
+		{name: "connection dropped before any byte", offset: 4, body: iotest.ErrReader(dropped), err: dropped, wantOffset: 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uc, uploadRepository, blobStore := newUploadTestUC(t, 4)
+			uploadRepository.raced = test.raced
+
+			got, err := uc.AppendChunk("owner", "upload", test.offset, test.body)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			if err == nil && got.Offset != test.wantOffset {
+				t.Fatalf("got offset %d, want %d", got.Offset, test.wantOffset)
+			}
+
+			if uploadRepository.upload.Offset != test.wantOffset {
+				t.Fatalf("stored offset is %d, want %d", uploadRepository.upload.Offset, test.wantOffset)
+			}
+
+			staged, err := blobStore.List(stagingPrefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(staged) > 0 {
+				t.Fatalf("staged chunks left behind : %v", staged)
+			}
+
+			chunks, err := blobStore.List(uc.chunkPrefix("upload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(chunks) != len(test.wantChunks) {
+				t.Fatalf("got %d chunks, want %d", len(chunks), len(test.wantChunks))
+			}
+
+			for i, chunk := range chunks {
+				if chunk.Key != uc.chunkKey("upload", 4) {
+					t.Fatalf("chunk stored under %s, want the offset it started from", chunk.Key)
+				}
+
+				reader, _, err := blobStore.Get(chunk.Key)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				content, err := io.ReadAll(reader)
+				reader.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if string(content) != test.wantChunks[i] {
+					t.Fatalf("chunk holds %q, want %q", content, test.wantChunks[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"user-personalize/internal/config"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
+	"user-personalize/pkg/util/storage"
+)
+
+const recalculateBatch = 500
+
+// UsageUC reports the storage users take up and repairs the counters PhotosUC keeps.
+type UsageUC interface {
+	GetUsage(userId string) (dto.UsageResponse, error)
+	Recalculate() (int64, error)
+}
+
+type usageUCImpl struct {
+	usageRepository  repository.UsageRepository
+	userRepository   repository.UserRepository
+	photosRepository repository.PhotosRepository
+	quota            *photoQuota
+}
+
+func NewUsageUC(usageRepository repository.UsageRepository, userRepository repository.UserRepository, photosRepository repository.PhotosRepository, blobStore storage.BlobStore,
+	quotaConfig config.QuotaConfig) UsageUC {
+	return &usageUCImpl{
+		usageRepository:  usageRepository,
+		userRepository:   userRepository,
+		photosRepository: photosRepository,
+		quota:            newPhotoQuota(usageRepository, userRepository, blobStore, quotaConfig),
+	}
+}
+
+func (u *usageUCImpl) GetUsage(userId string) (dto.UsageResponse, error) {
+	user, err := u.userRepository.GetById(userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.UsageResponse{}, fmt.Errorf("GetUsageUC : %w", exception.NotFoundErr)
+	}
+
+	if err != nil {
+		return dto.UsageResponse{}, fmt.Errorf("GetUsageUC : %w", err)
+	}
+
+	usage, err := u.quota.usage(userId)
+	if err != nil {
+		return dto.UsageResponse{}, fmt.Errorf("GetUsageUC : %w", err)
+	}
+
+	limit := u.quota.plan(user.Role)
+	response := dto.UsageResponse{
+		Plan:       user.Role,
+		Bytes:      usage.Bytes,
+		MaxBytes:   limit.MaxBytes,
+		PhotoCount: usage.PhotoCount,
+		MaxPhotos:  limit.MaxPhotos,
+	}
+	if !usage.UpdatedAt.IsZero() {
+		response.UpdatedAt = usage.UpdatedAt.Format(time.RFC3339)
+	}
+
+	return response, nil
+}
+
+// Recalculate rebuilds the counters of every user from the database and adds the files
+// stored before content addressing, whose size only storage knows. Those are read first,
+// page by page and trash included, then every counter is rebuilt in one statement. A legacy
+// photo migrated in between is counted twice, so it is best not run alongside the migration.
+func (u *usageUCImpl) Recalculate() (int64, error) {
+	legacyBytes := make(map[string]int64)
+	afterId := ""
+	for {
+		photos, err := u.photosRepository.FindLegacy(afterId, recalculateBatch)
+		if err != nil {
+			return 0, fmt.Errorf("RecalculateUsageUC : %w", err)
+		}
+
+		for _, photo := range photos {
+			legacyBytes[photo.UserId] += u.quota.legacySize(photo)
+		}
+
+		if len(photos) < recalculateBatch {
+			break
+		}
+		afterId = photos[len(photos)-1].Id
+	}
+
+	users, err := u.usageRepository.Recalculate(legacyBytes)
+	if err != nil {
+		return 0, fmt.Errorf("RecalculateUsageUC : %w", err)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+	"user-personalize/pkg/util/exception"
+)
+
+// Trigram similarity needs a few characters to tell names apart, shorter queries would
+// match almost everyone.
+const (
+	minUserSearchLength = 3
+	maxUserSearchLength = 100
+)
+
+// userSearchThreshold is the word similarity a name needs to match. The pg_trgm default
+// of 0.6 misses most misspellings, a transposed letter in a short name scores around 0.4.
+const userSearchThreshold = 0.3
+
+// userSearchSort tags the cursors of user search, so cursors of other lists are refused.
+const userSearchSort = "relevance"
+
+// SearchUsers finds users by partial or misspelled usernames and display names, leaving
+// out the users blocked with viewerId. Each viewer is rate limited.
+func (u *userUCImpl) SearchUsers(viewerId string, request dto.UserSearchRequest) (dto.UserSearchResponse, error) {
+	query := strings.TrimSpace(request.Query)
+	length := utf8.RuneCountInString(query)
+	if length < minUserSearchLength || length > maxUserSearchLength {
+		return dto.UserSearchResponse{}, fmt.Errorf("SearchUsersUC : query must be %d to %d characters : %w", minUserSearchLength, maxUserSearchLength, exception.InvalidErr)
+	}
+
+	limit, err := pageLimit(request.Page.Limit)
+	if err != nil {
+		return dto.UserSearchResponse{}, fmt.Errorf("SearchUsersUC : %w", err)
+	}
+
+	search := entity.UserSearch{ViewerId: viewerId, Query: query, Threshold: userSearchThreshold, Limit: limit + 1}
+	if request.Page.Cursor != "" {
+		cursor, err := decodeCursor(request.Page.Cursor)
+		if err != nil {
+			return dto.UserSearchResponse{}, fmt.Errorf("SearchUsersUC : %w", err)
+		}
+
+		if cursor.Sort != userSearchSort {
+			return dto.UserSearchResponse{}, fmt.Errorf("SearchUsersUC : cursor is not valid for user search : %w", exception.InvalidErr)
+		}
+
+		search.HasCursor = true
+		search.AfterRank = cursor.Rank
+		search.AfterId = cursor.Id
+	}
+
+	// checked last so that invalid requests do not use up the limit
+	if !u.searchLimiter.Allow(viewerId) {
+		return dto.UserSearchResponse{}, fmt.Errorf("SearchUsersUC : %w", exception.RateLimitedErr)
+	}
+
+	// one extra row tells whether there is a next page
+	var results []entity.UserSearchResult
+	err = u.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		results, err = u.userRepository.WithTx(tx).Search(search)
+		return err
+	})
+	if err != nil {
+		return dto.UserSearchResponse{}, fmt.Errorf("SearchUsersUC : %w", err)
+	}
+
+	response := dto.UserSearchResponse{Items: make([]dto.UserSearchResult, 0, len(results)), Page: pageResponse(limit, nil)}
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[limit-1]
+		response.Page = pageResponse(limit, &pageCursor{Rank: last.Rank, Sort: userSearchSort, Id: last.User.Id})
+	}
+
+	for _, result := range results {
+		response.Items = append(response.Items, dto.UserSearchResult{
+			Id:          result.User.Id,
+			Username:    result.User.Username,
+			DisplayName: result.User.DisplayName,
+			Rank:        result.Rank,
+		})
+	}
+
+	return response, nil
+}
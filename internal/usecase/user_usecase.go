@@ -1,32 +1,41 @@
 package usecase
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"time"
 	"user-personalize/internal/model/dto"
 	"user-personalize/internal/model/entity"
 	"user-personalize/internal/repository"
+	"user-personalize/pkg/util/exception"
 	mapping2 "user-personalize/pkg/util/mapping"
+	"user-personalize/pkg/util/service"
 )
 
 type UserUC interface {
 	CreateUser(payload dto.UserRequest) (dto.UserResponse, error)
-	GetAllUser() ([]dto.UserResponse, error)
-	GetUserById(userId string) (dto.UserResponse, error)
+	GetAllUser(viewerId string, request dto.UserListRequest) (dto.UserListResponse, error)
+	GetUserById(viewerId string, userId string) (dto.UserResponse, error)
 	Update(id string, payload dto.UserUpdateRequest) (dto.UserResponse, error)
 	UpdatePassword(id string, payload dto.UpdatePasswordRequest) (dto.UserResponse, error)
-	DeleteUser(id string) error
+	SearchUsers(viewerId string, request dto.UserSearchRequest) (dto.UserSearchResponse, error)
 }
 
 type userUCImpl struct {
-	userRepository repository.UserRepository
-	validate       *validator.Validate
+	userRepository  repository.UserRepository
+	blockRepository repository.BlockRepository
+	transactor      repository.Transactor
+	searchLimiter   service.RateLimiterService
+	validate        *validator.Validate
 }
 
-func NewUserUC(userRepository repository.UserRepository, validate *validator.Validate) UserUC {
-	return &userUCImpl{userRepository: userRepository, validate: validate}
+func NewUserUC(userRepository repository.UserRepository, blockRepository repository.BlockRepository, transactor repository.Transactor, searchLimiter service.RateLimiterService, validate *validator.Validate) UserUC {
+	return &userUCImpl{userRepository: userRepository, blockRepository: blockRepository, transactor: transactor, searchLimiter: searchLimiter, validate: validate}
 }
 
 func (u *userUCImpl) CreateUser(payload dto.UserRequest) (dto.UserResponse, error) {
@@ -55,27 +64,120 @@ func (u *userUCImpl) CreateUser(payload dto.UserRequest) (dto.UserResponse, erro
 	return mapping2.MapUserToResponse(userCreated), nil
 }
 
-func (u *userUCImpl) GetAllUser() ([]dto.UserResponse, error) {
-	users, err := u.userRepository.GetAll()
+// userSorts are the sorts the user list accepts, newest first is the default.
+var userSorts = map[string]entity.UserFilter{
+	"created_at":  {SortBy: entity.UserSortCreatedAt},
+	"-created_at": {SortBy: entity.UserSortCreatedAt, Descending: true},
+	"username":    {SortBy: entity.UserSortUsername},
+	"-username":   {SortBy: entity.UserSortUsername, Descending: true},
+}
+
+const defaultUserSort = "-created_at"
+
+// GetAllUser leaves out the users that block or are blocked by viewerId.
+func (u *userUCImpl) GetAllUser(viewerId string, request dto.UserListRequest) (dto.UserListResponse, error) {
+	sort := request.Sort
+	if sort == "" {
+		sort = defaultUserSort
+	}
+
+	filter, ok := userSorts[sort]
+	if !ok {
+		return dto.UserListResponse{}, fmt.Errorf("GetAllUserUC : sort %q : %w", sort, exception.InvalidErr)
+	}
+
+	filter.EmailDomain = strings.TrimPrefix(strings.TrimSpace(request.EmailDomain), "@")
+	if strings.Contains(filter.EmailDomain, "@") || len(filter.EmailDomain) > 255 {
+		return dto.UserListResponse{}, fmt.Errorf("GetAllUserUC : email domain %q : %w", request.EmailDomain, exception.InvalidErr)
+	}
+
+	filter.UsernamePrefix = strings.TrimSpace(request.UsernamePrefix)
+	if len(filter.UsernamePrefix) > 255 {
+		return dto.UserListResponse{}, fmt.Errorf("GetAllUserUC : username prefix : %w", exception.InvalidErr)
+	}
+
+	var err error
+	filter.CreatedAfter, err = parseTimeFilter(request.CreatedAfter)
 	if err != nil {
-		return nil, fmt.Errorf("GetAllUserUC : %w", err)
+		return dto.UserListResponse{}, fmt.Errorf("GetAllUserUC : created_after : %w", err)
+	}
+
+	filter.CreatedBefore, err = parseTimeFilter(request.CreatedBefore)
+	if err != nil {
+		return dto.UserListResponse{}, fmt.Errorf("GetAllUserUC : created_before : %w", err)
+	}
+
+	if !filter.CreatedAfter.IsZero() && !filter.CreatedBefore.IsZero() && !filter.CreatedAfter.Before(filter.CreatedBefore) {
+		return dto.UserListResponse{}, fmt.Errorf("GetAllUserUC : created_after must be before created_before : %w", exception.InvalidErr)
+	}
+
+	keyset, limit, err := newSortedKeyset(request.Page, sort)
+	if err != nil {
+		return dto.UserListResponse{}, fmt.Errorf("GetAllUserUC : %w", err)
+	}
+
+	users, err := u.userRepository.GetAll(viewerId, filter, keyset)
+	if err != nil {
+		return dto.UserListResponse{}, fmt.Errorf("GetAllUserUC : %w", err)
+	}
+
+	response := dto.UserListResponse{Items: make([]dto.UserResponse, 0, len(users)), Page: pageResponse(limit, nil)}
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[limit-1]
+		next := pageCursor{CreatedAt: last.CreatedAt, Sort: sort, Id: last.Id}
+		if filter.SortBy == entity.UserSortUsername {
+			next = pageCursor{Key: last.Username, Sort: sort, Id: last.Id}
+		}
+
+		response.Page = pageResponse(limit, &next)
 	}
 
-	result := make([]dto.UserResponse, len(users))
+	response.Page.Sort = sort
+	for _, user := range users {
+		response.Items = append(response.Items, mapping2.MapUserToResponse(user))
+	}
+
+	return response, nil
+}
 
-	for i, user := range users {
-		result[i] = mapping2.MapUserToResponse(user)
+// parseTimeFilter reads an RFC 3339 timestamp or a date, which stands for its midnight in UTC.
+// An empty value is the zero time.
+func parseTimeFilter(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
 	}
 
-	return result, nil
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%q is not a timestamp : %w", value, exception.InvalidErr)
 }
 
-func (u *userUCImpl) GetUserById(userId string) (dto.UserResponse, error) {
+// GetUserById hides deactivated users and users that block or are blocked by viewerId as if they did not exist.
+func (u *userUCImpl) GetUserById(viewerId string, userId string) (dto.UserResponse, error) {
 	user, err := u.userRepository.GetById(userId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.DeactivatedAt.Valid) {
+		return dto.UserResponse{}, fmt.Errorf("GetUserByIdUC : %w", exception.NotFoundErr)
+	}
+
 	if err != nil {
 		return dto.UserResponse{}, fmt.Errorf("GetUserByIdUC : %w", err)
 	}
 
+	blocked, err := u.blockRepository.IsBlocked(viewerId, userId)
+	if err != nil {
+		return dto.UserResponse{}, fmt.Errorf("GetUserByIdUC : %w", err)
+	}
+
+	if blocked {
+		return dto.UserResponse{}, fmt.Errorf("GetUserByIdUC : %w", exception.NotFoundErr)
+	}
+
 	return mapping2.MapUserToResponse(user), nil
 }
 
@@ -85,10 +187,15 @@ func (u *userUCImpl) Update(id string, payload dto.UserUpdateRequest) (dto.UserR
 		return dto.UserResponse{}, fmt.Errorf("UpdateUC : %w", err)
 	}
 
-	user := entity.User{
-		Id:       id,
-		Username: payload.Username,
-		Email:    payload.Email,
+	user, err := u.userRepository.GetById(id)
+	if err != nil {
+		return dto.UserResponse{}, fmt.Errorf("UpdateUserUC : %w", err)
+	}
+
+	user.Username = payload.Username
+	user.Email = payload.Email
+	if payload.DisplayName != nil {
+		user.DisplayName = *payload.DisplayName
 	}
 
 	updatedUser, err := u.userRepository.Update(user)
@@ -112,17 +219,3 @@ func (u *userUCImpl) UpdatePassword(id string, payload dto.UpdatePasswordRequest
 
 	return mapping2.MapUserToResponse(user), nil
 }
-
-func (u *userUCImpl) DeleteUser(id string) error {
-	_, err := u.GetUserById(id)
-	if err != nil {
-		return err
-	}
-
-	err = u.userRepository.Delete(id)
-	if err != nil {
-		return fmt.Errorf("DeleteUserUC : %w", err)
-	}
-
-	return nil
-}
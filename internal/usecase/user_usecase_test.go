@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"user-personalize/pkg/util/exception"
+)
+
+func TestParseTimeFilter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		err   error
+	}{
+		{value: "", want: time.Time{}},
+		{value: "2024-05-01", want: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01T12:30:00Z", want: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)},
+		{value: "2024-05-01T12:30:00+02:00", want: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)},
+		{value: "2024-05-01 12:30", err: exception.InvalidErr},
+		{value: "2024-13-01", err: exception.InvalidErr},
+		{value: "yesterday", err: exception.InvalidErr},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := parseTimeFilter(test.value)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			if !got.Equal(test.want) || got.Location() != test.want.Location() {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
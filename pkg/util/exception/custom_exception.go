@@ -3,6 +3,16 @@ package exception
 import "errors"
 
 var (
-	NotFoundErr  = errors.New("not found")
-	DuplicateErr = errors.New("value is duplicated")
+	NotFoundErr    = errors.New("not found")
+	DuplicateErr   = errors.New("value is duplicated")
+	InvalidErr     = errors.New("value is invalid")
+	ForbiddenErr   = errors.New("access is forbidden")
+	ConflictErr    = errors.New("value is conflicted")
+	ExpiredErr     = errors.New("value is expired")
+	TooLargeErr    = errors.New("value is too large")
+	UnsupportedErr = errors.New("operation is not supported")
+	UnavailableErr = errors.New("service is unavailable")
+	QuotaErr       = errors.New("quota is exceeded")
+	DeactivatedErr = errors.New("account is deactivated")
+	RateLimitedErr = errors.New("rate limit is exceeded")
 )
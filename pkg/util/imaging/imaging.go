@@ -0,0 +1,163 @@
+package imaging
+
+import (
+	"fmt"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// Formats are named like the ones image.Decode reports. Jpeg, png and webp can be encoded,
+// webp always lossless, gif is read only.
+const (
+	FormatJpeg = "jpeg"
+	FormatPng  = "png"
+	FormatWebp = "webp"
+)
+
+// Decode reads a jpeg, png, gif or webp image and reports its format name.
+func Decode(reader io.Reader) (image.Image, string, error) {
+	img, format, err := image.Decode(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("imaging decode : %w", err)
+	}
+
+	return img, format, nil
+}
+
+// ResizeToWidth scales img down to width while keeping its aspect ratio.
+// Images that are already narrower are returned unchanged.
+func ResizeToWidth(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if width <= 0 || bounds.Dx() <= width {
+		return img
+	}
+
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// Encoder writes images in one output format. Quality is ignored by lossless formats.
+type Encoder struct {
+	ContentType string
+	Extension   string
+	Encode      func(writer io.Writer, img image.Image, quality int) error
+}
+
+var encoders = map[string]Encoder{
+	FormatJpeg: {
+		ContentType: "image/jpeg",
+		Extension:   ".jpg",
+		Encode: func(writer io.Writer, img image.Image, quality int) error {
+			return jpeg.Encode(writer, img, &jpeg.Options{Quality: quality})
+		},
+	},
+	FormatPng: {
+		ContentType: "image/png",
+		Extension:   ".png",
+		Encode: func(writer io.Writer, img image.Image, quality int) error {
+			return png.Encode(writer, img)
+		},
+	},
+	FormatWebp: {
+		ContentType: "image/webp",
+		Extension:   ".webp",
+		Encode: func(writer io.Writer, img image.Image, quality int) error {
+			return encodeWebp(writer, img)
+		},
+	},
+}
+
+// Encode writes img in format. Formats without an encoder are written as png.
+func Encode(writer io.Writer, img image.Image, format string, quality int) error {
+	err := encoder(format).Encode(writer, img, quality)
+	if err != nil {
+		return fmt.Errorf("imaging encode : %w", err)
+	}
+
+	return nil
+}
+
+func ContentType(format string) string {
+	return encoder(format).ContentType
+}
+
+func Extension(format string) string {
+	return encoder(format).Extension
+}
+
+func encoder(format string) Encoder {
+	if encoder, ok := encoders[format]; ok {
+		return encoder
+	}
+
+	return encoders[FormatPng]
+}
+
+// DecodeConfig reads the dimensions of an image without decoding its pixels.
+func DecodeConfig(reader io.Reader) (image.Config, string, error) {
+	cfg, format, err := image.DecodeConfig(reader)
+	if err != nil {
+		return image.Config{}, "", fmt.Errorf("imaging decode config : %w", err)
+	}
+
+	return cfg, format, nil
+}
+
+// CropToAspect cuts the largest centred region with the aspect ratio width:height out of img.
+func CropToAspect(img image.Image, width int, height int) image.Image {
+	bounds := img.Bounds()
+	cropWidth, cropHeight := bounds.Dx(), bounds.Dx()*height/width
+	if cropHeight > bounds.Dy() {
+		cropWidth, cropHeight = bounds.Dy()*width/height, bounds.Dy()
+	}
+
+	x := bounds.Min.X + (bounds.Dx()-cropWidth)/2
+	y := bounds.Min.Y + (bounds.Dy()-cropHeight)/2
+	return Crop(img, image.Rect(x, y, x+cropWidth, y+cropHeight))
+}
+
+// Crop cuts rect out of img, rect is clipped to the bounds of img. The pixels are
+// shared with img whenever its type supports SubImage.
+func Crop(img image.Image, rect image.Rectangle) image.Image {
+	rect = rect.Intersect(img.Bounds())
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst
+}
+
+// DHash computes the 64 bit difference hash of img: the image is reduced to 9x8
+// grey pixels and every bit records whether a pixel is brighter than its right neighbour.
+// Resized or recompressed copies of a photo end up a few bits apart.
+func DHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.CatmullRom.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
@@ -0,0 +1,478 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/image/draw"
+	"image"
+	"io"
+	"sort"
+)
+
+// The encoder writes lossless WebP (VP8L), see
+// https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification.
+// It applies the subtract green and predictor transforms and codes the residuals with
+// backward references to the pixel on the left or above, without a color cache.
+const (
+	webpMaxSize       = 1 << 14
+	webpMaxCodeLength = 15
+	webpMaxRun        = 4096
+	webpMinRun        = 3
+
+	webpTransformPredictor     = 0
+	webpTransformSubtractGreen = 2
+
+	// webpPredictorBits is the log-2 tile size of the predictor transform, every tile
+	// uses webpPredictorMode, ClampAddSubtractFull(L, T, TL).
+	webpPredictorBits = 9
+	webpPredictorMode = 12
+
+	// webpDistanceUp and webpDistanceLeft are the distance codes of the pixel above and
+	// of the pixel on the left.
+	webpDistanceUp   = 1
+	webpDistanceLeft = 2
+)
+
+// webpAlphabetSizes are the sizes of the green, red, blue, alpha and distance codes.
+var webpAlphabetSizes = [5]int{256 + 24, 256, 256, 256, 40}
+
+// webpCodeLengthOrder is the order the code lengths of the code length code are written in.
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// encodeWebp writes img as a lossless WebP.
+func encodeWebp(writer io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > webpMaxSize || height > webpMaxSize {
+		return fmt.Errorf("webp of %dx%d pixels is not supported", width, height)
+	}
+
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || nrgba.Rect.Min != (image.Point{}) || nrgba.Stride != 4*width {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+
+	// the pixels are kept in the byte order of image.NRGBA, the transforms work per channel
+	pix := make([]byte, len(nrgba.Pix))
+	copy(pix, nrgba.Pix)
+
+	alpha := uint32(0)
+	for p := 3; p < len(pix); p += 4 {
+		if pix[p] != 0xff {
+			alpha = 1
+			break
+		}
+	}
+
+	var bits webpBitWriter
+	bits.write(0x2f, 8)
+	bits.write(uint32(width-1), 14)
+	bits.write(uint32(height-1), 14)
+	bits.write(alpha, 1)
+	bits.write(0, 3)
+
+	// the decoder undoes the transforms in reverse order, predictor first
+	bits.write(1, 1)
+	bits.write(webpTransformSubtractGreen, 2)
+	for p := 0; p < len(pix); p += 4 {
+		pix[p] -= pix[p+1]
+		pix[p+2] -= pix[p+1]
+	}
+
+	bits.write(1, 1)
+	bits.write(webpTransformPredictor, 2)
+	bits.write(webpPredictorBits-2, 3)
+	tilesX, tilesY := webpTiles(width), webpTiles(height)
+	modes := make([]byte, 4*tilesX*tilesY)
+	for p := 1; p < len(modes); p += 4 {
+		modes[p] = webpPredictorMode
+	}
+	bits.writeImage(modes, tilesX, false)
+
+	bits.write(0, 1)
+	bits.writeImage(webpPredict(pix, width), width, true)
+
+	data := bits.bytes()
+	padded := len(data) + len(data)%2
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+padded))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if padded > len(data) {
+		data = append(data, 0)
+	}
+
+	_, err := writer.Write(append(header, data...))
+	return err
+}
+
+func webpTiles(size int) int {
+	return (size + 1<<webpPredictorBits - 1) >> webpPredictorBits
+}
+
+// webpPredict returns the residuals of pix against the predictor the decoder uses for each
+// position: opaque black for the first pixel, L for the first row, T for the first column
+// and webpPredictorMode for the rest.
+func webpPredict(pix []byte, width int) []byte {
+	residuals := make([]byte, len(pix))
+	stride := 4 * width
+	for p := 0; p < len(pix); p += 4 {
+		x := (p % stride) / 4
+		for c := 0; c < 4; c++ {
+			var predicted byte
+			switch {
+			case p == 0:
+				if c == 3 {
+					predicted = 0xff
+				}
+			case p < stride:
+				predicted = pix[p-4+c]
+			case x == 0:
+				predicted = pix[p-stride+c]
+			default:
+				predicted = clampAddSubtract(pix[p-4+c], pix[p-stride+c], pix[p-stride-4+c])
+			}
+			residuals[p+c] = pix[p+c] - predicted
+		}
+	}
+
+	return residuals
+}
+
+func clampAddSubtract(left byte, top byte, topLeft byte) byte {
+	return byte(min(max(int(left)+int(top)-int(topLeft), 0), 255))
+}
+
+// webpToken is a literal pixel or, when length is set, a copy of length pixels from distance.
+type webpToken struct {
+	pixel    [4]byte
+	length   int
+	distance int
+}
+
+// webpTokens replaces runs of pixels that repeat the one on the left or above by backward references.
+func webpTokens(pix []byte, width int) []webpToken {
+	count := len(pix) / 4
+	same := func(i int, j int) bool {
+		return pix[4*i] == pix[4*j] && pix[4*i+1] == pix[4*j+1] && pix[4*i+2] == pix[4*j+2] && pix[4*i+3] == pix[4*j+3]
+	}
+	run := func(i int, offset int) int {
+		if i < offset {
+			return 0
+		}
+
+		n := 0
+		for i+n < count && n < webpMaxRun && same(i+n, i+n-offset) {
+			n++
+		}
+		return n
+	}
+
+	tokens := make([]webpToken, 0, count)
+	for i := 0; i < count; {
+		left, up := run(i, 1), run(i, width)
+		switch {
+		case left >= webpMinRun && left >= up:
+			tokens = append(tokens, webpToken{length: left, distance: webpDistanceLeft})
+			i += left
+		case up >= webpMinRun:
+			tokens = append(tokens, webpToken{length: up, distance: webpDistanceUp})
+			i += up
+		default:
+			tokens = append(tokens, webpToken{pixel: [4]byte(pix[4*i : 4*i+4])})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// webpPrefix splits a length or distance code into its prefix symbol and extra bits.
+func webpPrefix(value int) (symbol int, extraBits uint, extra uint32) {
+	n := value - 1
+	if n < 4 {
+		return n, 0, 0
+	}
+
+	highest := 0
+	for n>>(highest+1) != 0 {
+		highest++
+	}
+	second := (n >> (highest - 1)) & 1
+	extraBits = uint(highest - 1)
+	return 2*highest + second, extraBits, uint32(n & (1<<extraBits - 1))
+}
+
+// webpCode is a canonical prefix code, codes holds the bits in the order they are written.
+// The symbol of a code with a single symbol takes no bits at all.
+type webpCode struct {
+	lengths []uint8
+	codes   []uint32
+	single  bool
+}
+
+// newWebpCode builds the code for the symbol counts in freq, a code without any symbols
+// gets symbol 0 so that it can be written.
+func newWebpCode(freq []int, maxLength int) webpCode {
+	lengths := webpCodeLengths(freq, maxLength)
+	used := 0
+	for _, length := range lengths {
+		if length > 0 {
+			used++
+		}
+	}
+
+	if used == 0 {
+		lengths[0] = 1
+	}
+
+	code := webpCode{lengths: lengths, codes: make([]uint32, len(lengths)), single: used <= 1}
+	if code.single {
+		return code
+	}
+
+	var counts [webpMaxCodeLength + 1]uint32
+	for _, length := range lengths {
+		counts[length]++
+	}
+	counts[0] = 0
+
+	var next [webpMaxCodeLength + 1]uint32
+	for length, current := 1, uint32(0); length <= webpMaxCodeLength; length++ {
+		current = (current + counts[length-1]) << 1
+		next[length] = current
+	}
+
+	for symbol, length := range lengths {
+		if length > 0 {
+			// the decoder reads the most significant bit of a code first
+			code.codes[symbol] = webpReverse(next[length], uint(length))
+			next[length]++
+		}
+	}
+
+	return code
+}
+
+func webpReverse(value uint32, n uint) uint32 {
+	reversed := uint32(0)
+	for i := uint(0); i < n; i++ {
+		reversed = reversed<<1 | (value>>i)&1
+	}
+
+	return reversed
+}
+
+// webpCodeLengths returns Huffman code lengths of at most maxLength bits. When the
+// tree is too deep the rarest symbols are counted as more frequent until it fits.
+func webpCodeLengths(freq []int, maxLength int) []uint8 {
+	lengths := make([]uint8, len(freq))
+	symbols := make([]int, 0, len(freq))
+	for symbol, count := range freq {
+		if count > 0 {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	if len(symbols) == 1 {
+		lengths[symbols[0]] = 1
+	}
+
+	if len(symbols) < 2 {
+		return lengths
+	}
+
+	type node struct {
+		weight int
+		parent int
+	}
+
+	for floor := 1; ; floor *= 2 {
+		weight := func(symbol int) int { return max(freq[symbol], floor) }
+		sort.SliceStable(symbols, func(i int, j int) bool { return weight(symbols[i]) < weight(symbols[j]) })
+
+		// leaves sorted by weight followed by the inner nodes, which are created in order
+		// of weight as well, so the two lightest nodes are always at the head of either part
+		nodes := make([]node, 0, 2*len(symbols)-1)
+		for _, symbol := range symbols {
+			nodes = append(nodes, node{weight: weight(symbol)})
+		}
+
+		leaf, inner := 0, len(symbols)
+		lightest := func() int {
+			if leaf < len(symbols) && (inner >= len(nodes) || nodes[leaf].weight <= nodes[inner].weight) {
+				leaf++
+				return leaf - 1
+			}
+			inner++
+			return inner - 1
+		}
+
+		for len(nodes) < cap(nodes) {
+			a, b := lightest(), lightest()
+			nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight})
+			nodes[a].parent, nodes[b].parent = len(nodes)-1, len(nodes)-1
+		}
+
+		depths := make([]int, len(nodes))
+		deepest := 0
+		for i := len(nodes) - 2; i >= 0; i-- {
+			depths[i] = depths[nodes[i].parent] + 1
+			deepest = max(deepest, depths[i])
+		}
+
+		if deepest <= maxLength {
+			for i, symbol := range symbols {
+				lengths[symbol] = uint8(depths[i])
+			}
+			return lengths
+		}
+	}
+}
+
+type webpBitWriter struct {
+	buf   []byte
+	acc   uint64
+	nBits uint
+}
+
+// write appends the n low bits of value, least significant bit first.
+func (b *webpBitWriter) write(value uint32, n uint) {
+	b.acc |= uint64(value&(1<<n-1)) << b.nBits
+	b.nBits += n
+	for b.nBits >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.nBits -= 8
+	}
+}
+
+func (b *webpBitWriter) writeSymbol(code webpCode, symbol int) {
+	if !code.single {
+		b.write(code.codes[symbol], uint(code.lengths[symbol]))
+	}
+}
+
+func (b *webpBitWriter) bytes() []byte {
+	if b.nBits > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.nBits = 0, 0
+	}
+
+	return b.buf
+}
+
+// writeImage writes an entropy coded image, the main image when topLevel is set and a
+// transform image otherwise. It uses one set of codes for the whole image.
+func (b *webpBitWriter) writeImage(pix []byte, width int, topLevel bool) {
+	b.write(0, 1)
+	if topLevel {
+		b.write(0, 1)
+	}
+
+	tokens := webpTokens(pix, width)
+	var freq [5][]int
+	for i, size := range webpAlphabetSizes {
+		freq[i] = make([]int, size)
+	}
+
+	for _, token := range tokens {
+		if token.length > 0 {
+			symbol, _, _ := webpPrefix(token.length)
+			freq[0][256+symbol]++
+			symbol, _, _ = webpPrefix(token.distance)
+			freq[4][symbol]++
+			continue
+		}
+
+		freq[0][token.pixel[1]]++
+		freq[1][token.pixel[0]]++
+		freq[2][token.pixel[2]]++
+		freq[3][token.pixel[3]]++
+	}
+
+	var codes [5]webpCode
+	for i := range codes {
+		codes[i] = newWebpCode(freq[i], webpMaxCodeLength)
+		b.writeCode(codes[i])
+	}
+
+	for _, token := range tokens {
+		if token.length > 0 {
+			symbol, extraBits, extra := webpPrefix(token.length)
+			b.writeSymbol(codes[0], 256+symbol)
+			b.write(extra, extraBits)
+			symbol, extraBits, extra = webpPrefix(token.distance)
+			b.writeSymbol(codes[4], symbol)
+			b.write(extra, extraBits)
+			continue
+		}
+
+		b.writeSymbol(codes[0], int(token.pixel[1]))
+		b.writeSymbol(codes[1], int(token.pixel[0]))
+		b.writeSymbol(codes[2], int(token.pixel[2]))
+		b.writeSymbol(codes[3], int(token.pixel[3]))
+	}
+}
+
+// writeCode writes the code lengths of code, themselves coded with a code length code.
+// Runs of zero lengths use the repeat symbols 17 and 18.
+func (b *webpBitWriter) writeCode(code webpCode) {
+	type lengthToken struct {
+		symbol int
+		extra  uint32
+	}
+
+	tokens := make([]lengthToken, 0, len(code.lengths))
+	for i := 0; i < len(code.lengths); {
+		zeros := 0
+		for i+zeros < len(code.lengths) && code.lengths[i+zeros] == 0 && zeros < 138 {
+			zeros++
+		}
+
+		switch {
+		case zeros >= 11:
+			tokens = append(tokens, lengthToken{symbol: 18, extra: uint32(zeros - 11)})
+			i += zeros
+		case zeros >= 3:
+			tokens = append(tokens, lengthToken{symbol: 17, extra: uint32(zeros - 3)})
+			i += zeros
+		default:
+			tokens = append(tokens, lengthToken{symbol: int(code.lengths[i])})
+			i++
+		}
+	}
+
+	freq := make([]int, len(webpCodeLengthOrder))
+	for _, token := range tokens {
+		freq[token.symbol]++
+	}
+	lengthCode := newWebpCode(freq, 7)
+
+	written := 4
+	for i, symbol := range webpCodeLengthOrder {
+		if lengthCode.lengths[symbol] > 0 {
+			written = max(written, i+1)
+		}
+	}
+
+	b.write(0, 1)
+	b.write(uint32(written-4), 4)
+	for _, symbol := range webpCodeLengthOrder[:written] {
+		b.write(uint32(lengthCode.lengths[symbol]), 3)
+	}
+
+	// the lengths of the whole alphabet follow, no max_symbol
+	b.write(0, 1)
+	for _, token := range tokens {
+		b.writeSymbol(lengthCode, token.symbol)
+		switch token.symbol {
+		case 17:
+			b.write(token.extra, 3)
+		case 18:
+			b.write(token.extra, 7)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package imaging
+
+import (
+	"bytes"
+	"golang.org/x/image/webp"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestEncodeWebpRoundTrips(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		pixel  func(x int, y int) color.NRGBA
+	}{
+		{name: "single pixel", width: 1, height: 1, pixel: func(x int, y int) color.NRGBA { return color.NRGBA{R: 10, G: 200, B: 30, A: 255} }},
+		{name: "flat", width: 64, height: 48, pixel: func(x int, y int) color.NRGBA { return color.NRGBA{R: 90, G: 90, B: 200, A: 255} }},
+		{name: "gradient across predictor tiles", width: 700, height: 9, pixel: func(x int, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(x), G: uint8(y * 20), B: uint8(x + y), A: 255}
+		}},
+		{name: "noise", width: 37, height: 53, pixel: func(x int, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(random.Intn(256)), G: uint8(random.Intn(256)), B: uint8(random.Intn(256)), A: 255}
+		}},
+		{name: "translucent stripes", width: 40, height: 40, pixel: func(x int, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(y * 6), G: 0, B: 255, A: uint8(x / 8 * 60)}
+		}},
+		{name: "tall column", width: 1, height: 300, pixel: func(x int, y int) color.NRGBA { return color.NRGBA{R: uint8(y % 7), G: 1, B: 2, A: 255} }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, test.width, test.height))
+			for y := 0; y < test.height; y++ {
+				for x := 0; x < test.width; x++ {
+					img.SetNRGBA(x, y, test.pixel(x, y))
+				}
+			}
+
+			var buf bytes.Buffer
+			err := Encode(&buf, img, FormatWebp, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if decoded.Bounds() != img.Bounds() {
+				t.Fatalf("got bounds %v, want %v", decoded.Bounds(), img.Bounds())
+			}
+
+			for y := 0; y < test.height; y++ {
+				for x := 0; x < test.width; x++ {
+					got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+					if want := img.NRGBAAt(x, y); got != want {
+						t.Fatalf("pixel %d,%d : got %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebpRefusesOversizedImages(t *testing.T) {
+	err := Encode(&bytes.Buffer{}, image.NewNRGBA(image.Rect(0, 0, webpMaxSize+1, 1)), FormatWebp, 0)
+	if err == nil {
+		t.Fatal("got no error for an image wider than webp allows")
+	}
+}
+
+func TestWebpCodeLengthsStayWithinTheLimit(t *testing.T) {
+	// fibonacci counts give the deepest unlimited Huffman tree
+	freq := make([]int, 30)
+	a, b := 1, 1
+	for i := range freq {
+		freq[i] = a
+		a, b = b, a+b
+	}
+
+	lengths := webpCodeLengths(freq, webpMaxCodeLength)
+	kraft := 0.0
+	for symbol, length := range lengths {
+		if length == 0 || length > webpMaxCodeLength {
+			t.Fatalf("symbol %d : got length %d", symbol, length)
+		}
+		kraft += 1 / float64(uint(1)<<length)
+	}
+
+	if kraft != 1 {
+		t.Fatalf("got kraft sum %v, want a complete code", kraft)
+	}
+}
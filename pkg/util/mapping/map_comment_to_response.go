@@ -0,0 +1,20 @@
+package mapping
+
+import (
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+)
+
+func MapCommentToResponse(comment entity.Comment) dto.CommentResponse {
+	return dto.CommentResponse{
+		Id:        comment.Id,
+		PhotoId:   comment.PhotoId,
+		UserId:    comment.UserId,
+		ParentId:  comment.ParentId,
+		Body:      comment.Body,
+		Edited:    comment.UpdatedAt.After(comment.CreatedAt),
+		CreatedAt: comment.CreatedAt.String(),
+		UpdatedAt: comment.UpdatedAt.String(),
+		Replies:   make([]dto.CommentResponse, 0),
+	}
+}
@@ -0,0 +1,14 @@
+package mapping
+
+import (
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+)
+
+func MapFollowUserToResponse(user entity.FollowUser) dto.FollowUserResponse {
+	return dto.FollowUserResponse{
+		UserId:     user.UserId,
+		Username:   user.Username,
+		FollowedAt: user.FollowedAt.String(),
+	}
+}
@@ -0,0 +1,36 @@
+package mapping
+
+import (
+	"encoding/json"
+	"time"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+)
+
+func MapPersonalDataToExport(userId string, data entity.PersonalData, exportedAt time.Time) dto.PersonalDataExport {
+	return dto.PersonalDataExport{
+		UserId:     userId,
+		ExportedAt: exportedAt,
+		Notes: []string{
+			"Sign-in tokens are stateless and are never stored, so there are no sessions to export.",
+			"Reports about your photos and moderation events leave out who reported and which moderator acted.",
+		},
+		Profile:         data.Profile,
+		Sessions:        json.RawMessage("[]"),
+		Usage:           data.Usage,
+		Photos:          data.Photos,
+		PhotoVersions:   data.PhotoVersions,
+		Likes:           data.Likes,
+		Comments:        data.Comments,
+		Following:       data.Following,
+		Followers:       data.Followers,
+		Blocks:          data.Blocks,
+		Mutes:           data.Mutes,
+		Uploads:         data.Uploads,
+		DirectUploads:   data.DirectUploads,
+		Exports:         data.Exports,
+		ReportsFiled:    data.ReportsFiled,
+		ReportsReceived: data.ReportsReceived,
+		AuditEvents:     data.AuditEvents,
+	}
+}
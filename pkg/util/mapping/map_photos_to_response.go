@@ -0,0 +1,81 @@
+package mapping
+
+import (
+	"time"
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+)
+
+func MapPhotosToResponse(photos entity.Photos) dto.PhotosResponse {
+	result := dto.PhotosResponse{
+		Id:         photos.Id,
+		Title:      photos.Title,
+		Caption:    photos.Caption,
+		PhotoUrl:   photos.PhotoUrl,
+		UserId:     photos.UserId,
+		Visibility: photos.Visibility,
+
+		ModerationStatus: photos.ModerationStatus,
+		Tags:             photos.Tags,
+		Hash:             photos.BlobHash,
+		CreatedAt:        photos.CreatedAt.String(),
+		UpdatedAt:        photos.UpdatedAt.String(),
+
+		Width:         photos.Width,
+		Height:        photos.Height,
+		BlurHash:      photos.BlurHash,
+		DominantColor: photos.DominantColor,
+		LikeCount:     photos.LikeCount,
+		CommentCount:  photos.CommentCount,
+	}
+
+	if result.Tags == nil {
+		result.Tags = make([]string, 0)
+	}
+
+	if photos.Crop.Width > 0 {
+		result.Crop = &dto.PhotoCropResponse{
+			X:      photos.Crop.X,
+			Y:      photos.Crop.Y,
+			Width:  photos.Crop.Width,
+			Height: photos.Crop.Height,
+		}
+	}
+
+	return result
+}
+
+func MapPhotosToTrashResponse(photos entity.Photos, purgeAt time.Time) dto.TrashPhotoResponse {
+	return dto.TrashPhotoResponse{
+		PhotosResponse: MapPhotosToResponse(photos),
+		DeletedAt:      photos.DeletedAt.Time.String(),
+		PurgeAt:        purgeAt.String(),
+	}
+}
+
+func MapPhotoVersionToResponse(version entity.PhotoVersion) dto.PhotoVersionResponse {
+	result := dto.PhotoVersionResponse{
+		Id:            version.Id,
+		PhotoId:       version.PhotoId,
+		Title:         version.Title,
+		Caption:       version.Caption,
+		PhotoUrl:      version.PhotoUrl,
+		Hash:          version.BlobHash,
+		Width:         version.Width,
+		Height:        version.Height,
+		BlurHash:      version.BlurHash,
+		DominantColor: version.DominantColor,
+		CreatedAt:     version.CreatedAt.String(),
+	}
+
+	if version.Crop.Width > 0 {
+		result.Crop = &dto.PhotoCropResponse{
+			X:      version.Crop.X,
+			Y:      version.Crop.Y,
+			Width:  version.Crop.Width,
+			Height: version.Crop.Height,
+		}
+	}
+
+	return result
+}
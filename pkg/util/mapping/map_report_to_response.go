@@ -0,0 +1,45 @@
+package mapping
+
+import (
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+)
+
+func MapReportToResponse(report entity.Report) dto.ReportResponse {
+	result := dto.ReportResponse{
+		Id:           report.Id,
+		PhotoId:      report.PhotoId,
+		PhotoOwnerId: report.PhotoOwnerId,
+		ReporterId:   report.ReporterId,
+		Reason:       report.Reason,
+		Details:      report.Details,
+		Status:       report.Status,
+		ClaimedBy:    report.ClaimedBy,
+		ResolvedBy:   report.ResolvedBy,
+		Resolution:   report.Resolution,
+		CreatedAt:    report.CreatedAt.String(),
+	}
+
+	if report.ClaimedAt.Valid {
+		result.ClaimedAt = report.ClaimedAt.Time.String()
+	}
+
+	if report.ResolvedAt.Valid {
+		result.ResolvedAt = report.ResolvedAt.Time.String()
+	}
+
+	return result
+}
+
+func MapModerationActionToResponse(action entity.ModerationAction) dto.ModerationActionResponse {
+	return dto.ModerationActionResponse{
+		Id:        action.Id,
+		ActorId:   action.ActorId,
+		Action:    action.Action,
+		ReportId:  action.ReportId,
+		PhotoId:   action.PhotoId,
+		UserId:    action.UserId,
+		Note:      action.Note,
+		CreatedAt: action.CreatedAt.String(),
+	}
+}
@@ -0,0 +1,19 @@
+package mapping
+
+import (
+	"user-personalize/internal/model/dto"
+	"user-personalize/internal/model/entity"
+)
+
+func MapSimilarPhotosToResponse(photos []entity.SimilarPhoto) []dto.SimilarPhotoResponse {
+	result := make([]dto.SimilarPhotoResponse, 0, len(photos))
+	for _, photo := range photos {
+		result = append(result, dto.SimilarPhotoResponse{
+			PhotoId:  photo.PhotoId,
+			UserId:   photo.UserId,
+			Distance: photo.Distance,
+		})
+	}
+
+	return result
+}
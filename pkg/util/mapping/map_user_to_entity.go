@@ -7,8 +7,9 @@ import (
 
 func MapUserToEntity(request dto.UserRequest) entity.User {
 	return entity.User{
-		Username: request.Username,
-		Password: request.Password,
-		Email:    request.Email,
+		Username:    request.Username,
+		DisplayName: request.DisplayName,
+		Password:    request.Password,
+		Email:       request.Email,
 	}
 }
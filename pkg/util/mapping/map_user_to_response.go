@@ -7,10 +7,11 @@ import (
 
 func MapUserToResponse(user entity.User) dto.UserResponse {
 	return dto.UserResponse{
-		Id:        user.Id,
-		Email:     user.Email,
-		Username:  user.Username,
-		CreatedAt: user.CreatedAt.String(),
-		UpdatedAt: user.UpdatedAt.String(),
+		Id:          user.Id,
+		Email:       user.Email,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		CreatedAt:   user.CreatedAt.String(),
+		UpdatedAt:   user.UpdatedAt.String(),
 	}
 }
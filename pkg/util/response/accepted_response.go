@@ -0,0 +1,15 @@
+package response
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"user-personalize/internal/model/dto"
+)
+
+func AcceptedResponse(ctx *gin.Context, message string, data interface{}) {
+	ctx.JSON(http.StatusAccepted, dto.WebResponse{
+		Code:    http.StatusAccepted,
+		Message: message,
+		Data:    data,
+	})
+}
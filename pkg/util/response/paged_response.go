@@ -0,0 +1,17 @@
+package response
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"user-personalize/internal/model/dto"
+)
+
+// PagedResponse sends a page of a list as Data and its pagination metadata next to it.
+func PagedResponse(ctx *gin.Context, message string, data interface{}, page dto.PageResponse) {
+	ctx.JSON(http.StatusOK, dto.WebResponse{
+		Code:       http.StatusOK,
+		Message:    message,
+		Data:       data,
+		Pagination: &page,
+	})
+}
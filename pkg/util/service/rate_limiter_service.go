@@ -0,0 +1,76 @@
+package service
+
+import (
+	"math"
+	"sync"
+	"time"
+	"user-personalize/internal/config"
+)
+
+// RateLimiterService counts requests per key, e.g. per user. The counts are kept in
+// memory, so each instance of the api enforces the limit on its own.
+type RateLimiterService interface {
+	Allow(key string) bool
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+type rateLimiterServiceImpl struct {
+	limit   config.RateLimit
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	pruned  time.Time
+}
+
+func NewRateLimiterService(limit config.RateLimit) RateLimiterService {
+	return &rateLimiterServiceImpl{limit: limit, buckets: make(map[string]*tokenBucket), pruned: time.Now()}
+}
+
+// Allow takes a token from the bucket of key. A bucket holds limit.Requests tokens and
+// refills at an even pace over limit.Window.
+func (r *rateLimiterServiceImpl) Allow(key string) bool {
+	return r.allow(key, time.Now())
+}
+
+func (r *rateLimiterServiceImpl) allow(key string, now time.Time) bool {
+	capacity := float64(r.limit.Requests)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune(now)
+	bucket, ok := r.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updatedAt: now}
+		r.buckets[key] = bucket
+	}
+
+	refill := now.Sub(bucket.updatedAt).Seconds() * capacity / r.limit.Window.Seconds()
+	bucket.tokens = math.Min(capacity, bucket.tokens+refill)
+	bucket.updatedAt = now
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+	return true
+}
+
+// prune drops the buckets that had the time to refill completely, they are no different
+// from new ones. It runs at most once per window.
+func (r *rateLimiterServiceImpl) prune(now time.Time) {
+	if now.Sub(r.pruned) < r.limit.Window {
+		return
+	}
+
+	for key, bucket := range r.buckets {
+		if now.Sub(bucket.updatedAt) >= r.limit.Window {
+			delete(r.buckets, key)
+		}
+	}
+
+	r.pruned = now
+}
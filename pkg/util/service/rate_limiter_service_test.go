@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"user-personalize/internal/config"
+)
+
+func TestRateLimiterRefillsOverTheWindow(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    bool
+	}{
+		{name: "first of the burst", elapsed: 0, want: true},
+		{name: "second of the burst", elapsed: 0, want: true},
+		{name: "third of the burst", elapsed: 0, want: true},
+		{name: "burst used up", elapsed: 0, want: false},
+		{name: "not a full token yet", elapsed: 19 * time.Second, want: false},
+		{name: "one token after a third of the window", elapsed: 20 * time.Second, want: true},
+		{name: "that token is taken", elapsed: 20 * time.Second, want: false},
+		{name: "refill stops at the burst", elapsed: 10 * time.Minute, want: true},
+		{name: "second after the refill", elapsed: 10 * time.Minute, want: true},
+		{name: "third after the refill", elapsed: 10 * time.Minute, want: true},
+		{name: "no more than the burst", elapsed: 10 * time.Minute, want: false},
+	}
+
+	limiter := NewRateLimiterService(config.RateLimit{Requests: 3, Window: time.Minute}).(*rateLimiterServiceImpl)
+	for _, test := range tests {
+		got := limiter.allow("alice", start.Add(test.elapsed))
+		if got != test.want {
+			t.Fatalf("%s : got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if !limiter.allow("bob", start.Add(10*time.Minute)) {
+		t.Fatal("bob shares the bucket of alice")
+	}
+}
+
+func TestRateLimiterPrunesRefilledBuckets(t *testing.T) {
+	start := time.Now()
+	limiter := NewRateLimiterService(config.RateLimit{Requests: 2, Window: time.Minute}).(*rateLimiterServiceImpl)
+
+	limiter.allow("alice", start)
+	limiter.allow("bob", start.Add(30*time.Second))
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    []string
+	}{
+		{name: "before a window passed", elapsed: 59 * time.Second, want: []string{"alice", "bob", "carol"}},
+		{name: "alice refilled", elapsed: 80 * time.Second, want: []string{"bob", "carol"}},
+		{name: "at most once per window", elapsed: 100 * time.Second, want: []string{"bob", "carol"}},
+		{name: "bob and carol refilled", elapsed: 3 * time.Minute, want: []string{"carol"}},
+	}
+
+	for _, test := range tests {
+		limiter.allow("carol", start.Add(test.elapsed))
+		if len(limiter.buckets) != len(test.want) {
+			t.Fatalf("%s : got %d buckets, want %v", test.name, len(limiter.buckets), test.want)
+		}
+
+		for _, key := range test.want {
+			if limiter.buckets[key] == nil {
+				t.Fatalf("%s : bucket of %s was pruned", test.name, key)
+			}
+		}
+	}
+}
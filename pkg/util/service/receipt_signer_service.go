@@ -0,0 +1,32 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"user-personalize/internal/config"
+)
+
+type ReceiptSignerService interface {
+	Sign(payload []byte) string
+	Verify(payload []byte, signature string) bool
+}
+
+type receiptSignerServiceImpl struct {
+	cfg config.AccountDeletionConfig
+}
+
+func NewReceiptSignerService(cfg config.AccountDeletionConfig) ReceiptSignerService {
+	return &receiptSignerServiceImpl{cfg: cfg}
+}
+
+// Sign is an HMAC-SHA256 of payload keyed with ERASURE_RECEIPT_SECRET.
+func (r *receiptSignerServiceImpl) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, r.cfg.ReceiptSigningKey)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (r *receiptSignerServiceImpl) Verify(payload []byte, signature string) bool {
+	return hmac.Equal([]byte(r.Sign(payload)), []byte(signature))
+}
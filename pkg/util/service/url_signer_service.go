@@ -0,0 +1,76 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+	"user-personalize/internal/config"
+)
+
+type UrlSignerService interface {
+	SignPhotoUrl(photoId string, variant string, ttl time.Duration) (string, time.Time, error)
+	Verify(photoId string, variant string, expires string, signature string) error
+}
+
+type urlSignerServiceImpl struct {
+	cfg     config.ShareConfig
+	baseUrl string
+}
+
+func NewUrlSignerService(cfg config.ShareConfig, baseUrl string) UrlSignerService {
+	return &urlSignerServiceImpl{cfg: cfg, baseUrl: baseUrl}
+}
+
+// SignPhotoUrl builds a /shared/photos link that is valid for ttl, or for the
+// default expiry when ttl is zero.
+func (u *urlSignerServiceImpl) SignPhotoUrl(photoId string, variant string, ttl time.Duration) (string, time.Time, error) {
+	if ttl == 0 {
+		ttl = u.cfg.DefaultExpiredTime
+	}
+
+	if ttl < 0 || ttl > u.cfg.MaxExpiredTime {
+		return "", time.Time{}, fmt.Errorf("signed url expiry must be between 0 and %s", u.cfg.MaxExpiredTime)
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	if variant != "" {
+		query.Set("variant", variant)
+	}
+	query.Set("expires", expires)
+	query.Set("signature", u.sign(photoId, variant, expires))
+
+	return fmt.Sprintf("%s/shared/photos/%s?%s", u.baseUrl, url.PathEscape(photoId), query.Encode()), expiresAt, nil
+}
+
+func (u *urlSignerServiceImpl) Verify(photoId string, variant string, expires string, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return fmt.Errorf("signed url expires is invalid")
+	}
+
+	expected := u.sign(photoId, variant, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("signed url signature is invalid")
+	}
+
+	if time.Now().Unix() > expiresAt {
+		return fmt.Errorf("signed url is expired")
+	}
+
+	return nil
+}
+
+// sign is an HMAC-SHA256 keyed with SHARE_URL_SECRET, so rotating the secret
+// invalidates every link issued before.
+func (u *urlSignerServiceImpl) sign(photoId string, variant string, expires string) string {
+	mac := hmac.New(sha256.New, u.cfg.SigningKey)
+	mac.Write([]byte(photoId + "\n" + variant + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
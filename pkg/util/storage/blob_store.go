@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"time"
+	"user-personalize/internal/config"
+)
+
+type BlobInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// BlobStore keeps photo files under object keys such as "blobs/ab/<hash>.jpg".
+// Keys always use forward slashes regardless of the backend.
+type BlobStore interface {
+	// Put stores the content of reader under key, size is -1 when it is not known.
+	Put(key string, reader io.Reader, size int64, contentType string) (BlobInfo, error)
+	Get(key string) (io.ReadSeekCloser, BlobInfo, error)
+	Delete(key string) error
+	Stat(key string) (BlobInfo, error)
+	List(prefix string) ([]BlobInfo, error)
+	// ListPage returns at most limit objects under prefix that come after startAfter in
+	// the listing order of the backend, the last key of a page starts the next one.
+	ListPage(prefix string, startAfter string, limit int) ([]BlobInfo, error)
+	Move(srcKey string, dstKey string) error
+}
+
+// Presigner is implemented by backends that let clients upload directly, bypassing the API.
+type Presigner interface {
+	PresignPost(key string, maxSize int64, expiry time.Duration) (PresignedPost, error)
+}
+
+// PresignedPost is a browser-style upload form: the client sends Fields followed by a
+// "file" part as multipart/form-data to Url. The storage refuses files over the size the
+// form was signed for.
+type PresignedPost struct {
+	Url    string
+	Fields map[string]string
+}
+
+func NewBlobStore(cfg config.StorageConfig) (BlobStore, error) {
+	switch cfg.Driver {
+	case config.StorageDriverLocal:
+		return NewLocalBlobStore(cfg.LocalDir)
+	case config.StorageDriverS3:
+		return NewS3BlobStore(cfg)
+	default:
+		return nil, fmt.Errorf("storage driver %q is not supported", cfg.Driver)
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"user-personalize/pkg/util/exception"
+)
+
+// testRoundTrip stores, lists, moves and deletes a few objects under prefix, every
+// backend has to behave the same way.
+func testRoundTrip(t *testing.T, store BlobStore, prefix string) {
+	t.Helper()
+
+	contents := map[string]string{
+		prefix + "a/1.jpg": "first",
+		prefix + "a/2.jpg": "second",
+		prefix + "b/1.jpg": "third",
+	}
+
+	t.Cleanup(func() {
+		for _, key := range []string{prefix + "a/1.jpg", prefix + "a/2.jpg", prefix + "b/1.jpg", prefix + "c/1.jpg"} {
+			store.Delete(key)
+		}
+	})
+
+	for key, content := range contents {
+		// one object of unknown size, as the tus uploads store them
+		size := int64(len(content))
+		if key == prefix+"b/1.jpg" {
+			size = -1
+		}
+
+		info, err := store.Put(key, strings.NewReader(content), size, "image/jpeg")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Key != key || info.Size != int64(len(content)) {
+			t.Fatalf("put %s returned %+v", key, info)
+		}
+	}
+
+	info, err := store.Stat(prefix + "a/1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size != 5 || info.ContentType != "image/jpeg" || info.LastModified.IsZero() {
+		t.Fatalf("stat returned %+v", info)
+	}
+
+	assertContent(t, store, prefix+"a/2.jpg", "second")
+
+	all, err := store.List(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != 3 {
+		t.Fatalf("listed %v, want 3 objects", all)
+	}
+
+	under, err := store.List(prefix + "a/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(under) != 2 {
+		t.Fatalf("listed %v under a/, want 2 objects", under)
+	}
+
+	paged := make([]string, 0)
+	startAfter := ""
+	for {
+		page, err := store.ListPage(prefix, startAfter, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, info := range page {
+			paged = append(paged, info.Key)
+		}
+
+		if len(page) < 2 {
+			break
+		}
+		startAfter = page[len(page)-1].Key
+	}
+
+	if len(paged) != len(all) {
+		t.Fatalf("paged %v, listed %v", paged, all)
+	}
+
+	for i, info := range all {
+		if paged[i] != info.Key {
+			t.Fatalf("paged %v, listed %v", paged, all)
+		}
+	}
+
+	err = store.Move(prefix+"b/1.jpg", prefix+"c/1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.Stat(prefix + "b/1.jpg")
+	if !errors.Is(err, exception.NotFoundErr) {
+		t.Fatalf("moved source : got error %v, want not found", err)
+	}
+
+	assertContent(t, store, prefix+"c/1.jpg", "third")
+
+	err = store.Delete(prefix + "a/1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = store.Get(prefix + "a/1.jpg")
+	if !errors.Is(err, exception.NotFoundErr) {
+		t.Fatalf("deleted object : got error %v, want not found", err)
+	}
+
+	err = store.Delete(prefix + "a/1.jpg")
+	if !errors.Is(err, exception.NotFoundErr) {
+		t.Fatalf("deleting twice : got error %v, want not found", err)
+	}
+}
+
+func assertContent(t *testing.T, store BlobStore, key string, want string) {
+	t.Helper()
+
+	reader, info, err := store.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != want || info.Size != int64(len(want)) {
+		t.Fatalf("%s holds %q of %d bytes, want %q", key, content, info.Size, want)
+	}
+}
@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"user-personalize/pkg/util/exception"
+)
+
+const localTempPrefix = ".tmp-"
+
+type localBlobStoreImpl struct {
+	root string
+}
+
+func NewLocalBlobStore(root string) (BlobStore, error) {
+	err := os.MkdirAll(root, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("NewLocalBlobStore : %w", err)
+	}
+
+	return &localBlobStoreImpl{root: root}, nil
+}
+
+func (l *localBlobStoreImpl) Put(key string, reader io.Reader, size int64, contentType string) (BlobInfo, error) {
+	filePath, err := l.path(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(filePath), 0o755)
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("LocalBlobStorePut : %w", err)
+	}
+
+	// write next to the destination and rename, so readers never observe a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), localTempPrefix)
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("LocalBlobStorePut : %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, reader)
+	if err != nil {
+		tmp.Close()
+		return BlobInfo{}, fmt.Errorf("LocalBlobStorePut : %w", err)
+	}
+
+	if size >= 0 && written != size {
+		tmp.Close()
+		return BlobInfo{}, fmt.Errorf("LocalBlobStorePut : expected %d bytes, got %d", size, written)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("LocalBlobStorePut : %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), filePath)
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("LocalBlobStorePut : %w", err)
+	}
+
+	return l.Stat(key)
+}
+
+func (l *localBlobStoreImpl) Get(key string) (io.ReadSeekCloser, BlobInfo, error) {
+	filePath, err := l.path(key)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, BlobInfo{}, l.wrapErr("LocalBlobStoreGet", err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, BlobInfo{}, l.wrapErr("LocalBlobStoreGet", err)
+	}
+
+	return file, l.info(key, stat), nil
+}
+
+func (l *localBlobStoreImpl) Delete(key string) error {
+	filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filePath)
+	if err != nil {
+		return l.wrapErr("LocalBlobStoreDelete", err)
+	}
+
+	return nil
+}
+
+func (l *localBlobStoreImpl) Stat(key string) (BlobInfo, error) {
+	filePath, err := l.path(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return BlobInfo{}, l.wrapErr("LocalBlobStoreStat", err)
+	}
+
+	return l.info(key, stat), nil
+}
+
+// List only walks the directory the prefix names, e.g. "variants/ab/" or the parent of "staging/abc".
+func (l *localBlobStoreImpl) List(prefix string) ([]BlobInfo, error) {
+	return l.list(prefix, "", 0)
+}
+
+// ListPage lists in the order filepath.WalkDir visits files, which compares keys path
+// segment by path segment rather than byte by byte.
+func (l *localBlobStoreImpl) ListPage(prefix string, startAfter string, limit int) ([]BlobInfo, error) {
+	return l.list(prefix, startAfter, limit)
+}
+
+// list walks the directory of prefix, skipping the subtrees that end before startAfter.
+// A limit of zero lists everything.
+func (l *localBlobStoreImpl) list(prefix string, startAfter string, limit int) ([]BlobInfo, error) {
+	result := make([]BlobInfo, 0)
+
+	dir := l.root
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		var err error
+		dir, err = l.path(prefix[:i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && filePath == dir {
+			return fs.SkipAll
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if filePath == dir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, filePath)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if startAfter != "" && compareKeys(key, startAfter) <= 0 {
+			if entry.IsDir() && !strings.HasPrefix(startAfter, key+"/") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), localTempPrefix) || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		stat, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		result = append(result, l.info(key, stat))
+		if len(result) == limit {
+			return fs.SkipAll
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("LocalBlobStoreList : %w", err)
+	}
+
+	return result, nil
+}
+
+// compareKeys orders keys the way filepath.WalkDir visits them, segment by segment.
+func compareKeys(a string, b string) int {
+	aSegments, bSegments := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(aSegments) && i < len(bSegments); i++ {
+		if c := strings.Compare(aSegments[i], bSegments[i]); c != 0 {
+			return c
+		}
+	}
+
+	return len(aSegments) - len(bSegments)
+}
+
+func (l *localBlobStoreImpl) Move(srcKey string, dstKey string) error {
+	srcPath, err := l.path(srcKey)
+	if err != nil {
+		return err
+	}
+
+	dstPath, err := l.path(dstKey)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(dstPath), 0o755)
+	if err != nil {
+		return fmt.Errorf("LocalBlobStoreMove : %w", err)
+	}
+
+	err = os.Rename(srcPath, dstPath)
+	if err != nil {
+		return l.wrapErr("LocalBlobStoreMove", err)
+	}
+
+	return nil
+}
+
+func (l *localBlobStoreImpl) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || cleaned[1:] != key {
+		return "", fmt.Errorf("blob key %q is invalid", key)
+	}
+
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+// info guesses ContentType from the extension of key, the local filesystem keeps no
+// metadata. It is a hint only, photos are served with the type sniffed on upload.
+func (l *localBlobStoreImpl) info(key string, stat fs.FileInfo) BlobInfo {
+	return BlobInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+		LastModified: stat.ModTime(),
+	}
+}
+
+func (l *localBlobStoreImpl) wrapErr(op string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s : %w", op, exception.NotFoundErr)
+	}
+
+	return fmt.Errorf("%s : %w", op, err)
+}
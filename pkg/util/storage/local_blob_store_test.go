@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocalListPageResumesAfterKey(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{"blobs/a-b/1", "blobs/a/1", "blobs/a/2", "blobs/b/1", "blobs/b/c/1", "blobsx/1", "other/1"}
+	for _, key := range keys {
+		_, err := store.Put(key, strings.NewReader(key), -1, "application/octet-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := store.List("blobs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, limit := range []int{1, 2, 3, 10} {
+		listed := make([]string, 0)
+		startAfter := ""
+		for {
+			page, err := store.ListPage("blobs/", startAfter, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, info := range page {
+				listed = append(listed, info.Key)
+			}
+
+			if len(page) < limit {
+				break
+			}
+			startAfter = page[len(page)-1].Key
+		}
+
+		if len(listed) != 5 || len(listed) != len(all) {
+			t.Fatalf("limit %d : listed %v, want the 5 keys under blobs/", limit, listed)
+		}
+
+		for i, info := range all {
+			if listed[i] != info.Key {
+				t.Fatalf("limit %d : listed %v, want %v", limit, listed, all)
+			}
+		}
+	}
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testRoundTrip(t, store, "blobs/")
+}
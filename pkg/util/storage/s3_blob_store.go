@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io"
+	"time"
+	"user-personalize/internal/config"
+	"user-personalize/pkg/util/exception"
+)
+
+// s3UnknownSizePartSize is the part size of an upload whose size is not known. Left to
+// itself the client sizes the parts for the largest object S3 allows and buffers a part of
+// over 500 MiB for every such upload. 16 MiB parts still allow objects of about 156 GiB.
+const s3UnknownSizePartSize = 16 << 20
+
+// s3BlobStoreImpl talks to any S3-compatible service (AWS S3, MinIO, ...).
+type s3BlobStoreImpl struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3BlobStore(cfg config.StorageConfig) (BlobStore, error) {
+	client, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		Secure: cfg.S3UseSSL,
+		Region: cfg.S3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("NewS3BlobStore : %w", err)
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, cfg.S3Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("NewS3BlobStore : %w", err)
+	}
+
+	if !exists {
+		err = client.MakeBucket(ctx, cfg.S3Bucket, minio.MakeBucketOptions{Region: cfg.S3Region})
+		if err != nil {
+			return nil, fmt.Errorf("NewS3BlobStore : %w", err)
+		}
+	}
+
+	return &s3BlobStoreImpl{client: client, bucket: cfg.S3Bucket}, nil
+}
+
+func (s *s3BlobStoreImpl) Put(key string, reader io.Reader, size int64, contentType string) (BlobInfo, error) {
+	options := minio.PutObjectOptions{ContentType: contentType}
+	if size < 0 {
+		options.PartSize = s3UnknownSizePartSize
+	}
+
+	_, err := s.client.PutObject(context.Background(), s.bucket, key, reader, size, options)
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("S3BlobStorePut : %w", err)
+	}
+
+	return s.Stat(key)
+}
+
+func (s *s3BlobStoreImpl) Get(key string) (io.ReadSeekCloser, BlobInfo, error) {
+	object, err := s.client.GetObject(context.Background(), s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, BlobInfo{}, s.wrapErr("S3BlobStoreGet", err)
+	}
+
+	// GetObject is lazy, stat forces the request so a missing key is reported here
+	stat, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, BlobInfo{}, s.wrapErr("S3BlobStoreGet", err)
+	}
+
+	return object, s.info(stat), nil
+}
+
+func (s *s3BlobStoreImpl) Delete(key string) error {
+	_, err := s.Stat(key)
+	if err != nil {
+		return err
+	}
+
+	err = s.client.RemoveObject(context.Background(), s.bucket, key, minio.RemoveObjectOptions{})
+	if err != nil {
+		return s.wrapErr("S3BlobStoreDelete", err)
+	}
+
+	return nil
+}
+
+func (s *s3BlobStoreImpl) Stat(key string) (BlobInfo, error) {
+	stat, err := s.client.StatObject(context.Background(), s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return BlobInfo{}, s.wrapErr("S3BlobStoreStat", err)
+	}
+
+	return s.info(stat), nil
+}
+
+func (s *s3BlobStoreImpl) List(prefix string) ([]BlobInfo, error) {
+	result := make([]BlobInfo, 0)
+
+	objects := s.client.ListObjects(context.Background(), s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true})
+	for object := range objects {
+		if object.Err != nil {
+			return nil, fmt.Errorf("S3BlobStoreList : %w", object.Err)
+		}
+
+		result = append(result, s.info(object))
+	}
+
+	return result, nil
+}
+
+// ListPage stops the listing once limit objects arrived, S3 lists keys in byte order.
+func (s *s3BlobStoreImpl) ListPage(prefix string, startAfter string, limit int) ([]BlobInfo, error) {
+	result := make([]BlobInfo, 0, limit)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	objects := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true, StartAfter: startAfter, MaxKeys: limit})
+	for object := range objects {
+		if object.Err != nil {
+			return nil, fmt.Errorf("S3BlobStoreListPage : %w", object.Err)
+		}
+
+		result = append(result, s.info(object))
+		if len(result) == limit {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// PresignPost signs a POST policy rather than a PUT url, a presigned PUT cannot limit
+// the size of the object.
+func (s *s3BlobStoreImpl) PresignPost(key string, maxSize int64, expiry time.Duration) (PresignedPost, error) {
+	policy := minio.NewPostPolicy()
+	err := errors.Join(
+		policy.SetBucket(s.bucket),
+		policy.SetKey(key),
+		policy.SetExpires(time.Now().UTC().Add(expiry)),
+		policy.SetContentLengthRange(1, maxSize),
+	)
+	if err != nil {
+		return PresignedPost{}, fmt.Errorf("S3BlobStorePresignPost : %w", err)
+	}
+
+	presigned, fields, err := s.client.PresignedPostPolicy(context.Background(), policy)
+	if err != nil {
+		return PresignedPost{}, fmt.Errorf("S3BlobStorePresignPost : %w", err)
+	}
+
+	return PresignedPost{Url: presigned.String(), Fields: fields}, nil
+}
+
+// Move copies server side and removes the source, S3 has no rename.
+func (s *s3BlobStoreImpl) Move(srcKey string, dstKey string) error {
+	ctx := context.Background()
+	_, err := s.client.CopyObject(ctx, minio.CopyDestOptions{Bucket: s.bucket, Object: dstKey}, minio.CopySrcOptions{Bucket: s.bucket, Object: srcKey})
+	if err != nil {
+		return s.wrapErr("S3BlobStoreMove", err)
+	}
+
+	err = s.client.RemoveObject(ctx, s.bucket, srcKey, minio.RemoveObjectOptions{})
+	if err != nil {
+		return s.wrapErr("S3BlobStoreMove", err)
+	}
+
+	return nil
+}
+
+func (s *s3BlobStoreImpl) info(object minio.ObjectInfo) BlobInfo {
+	return BlobInfo{
+		Key:          object.Key,
+		Size:         object.Size,
+		ContentType:  object.ContentType,
+		LastModified: object.LastModified,
+	}
+}
+
+func (s *s3BlobStoreImpl) wrapErr(op string, err error) error {
+	code := minio.ToErrorResponse(err).Code
+	if code == "NoSuchKey" || code == "NotFound" {
+		return fmt.Errorf("%s : %w", op, exception.NotFoundErr)
+	}
+
+	return fmt.Errorf("%s : %w", op, err)
+}
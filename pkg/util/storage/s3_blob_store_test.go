@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"os"
+	"testing"
+	"time"
+	"user-personalize/internal/config"
+)
+
+func TestPresignPostLimitsSize(t *testing.T) {
+	// with the region set the client signs offline
+	client, err := minio.New("127.0.0.1:9000", &minio.Options{
+		Creds:  credentials.NewStaticV4("access", "secret", ""),
+		Region: "us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &s3BlobStoreImpl{client: client, bucket: "photos"}
+
+	form, err := store.PresignPost("direct/upload", 1024, 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if form.Fields["key"] != "direct/upload" {
+		t.Fatalf("key field is %q", form.Fields["key"])
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(form.Fields["policy"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var policy struct {
+		Conditions []json.RawMessage `json:"conditions"`
+	}
+	err = json.Unmarshal(raw, &policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, condition := range policy.Conditions {
+		var values []any
+		if json.Unmarshal(condition, &values) == nil && len(values) == 3 && values[0] == "content-length-range" {
+			if values[1] != float64(1) || values[2] != float64(1024) {
+				t.Fatalf("content-length-range is %v, want 1 to 1024", values[1:])
+			}
+			return
+		}
+	}
+
+	t.Fatalf("policy has no content-length-range : %s", raw)
+}
+
+// TestS3RoundTrip runs against the S3-compatible service at STORAGE_TEST_S3_ENDPOINT, e.g.
+// a local MinIO. The bucket is created when missing and the objects go under a prefix of
+// their own, which the test removes.
+func TestS3RoundTrip(t *testing.T) {
+	endpoint := os.Getenv("STORAGE_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("STORAGE_TEST_S3_ENDPOINT is not set")
+	}
+
+	store, err := NewS3BlobStore(config.StorageConfig{
+		S3Endpoint:  endpoint,
+		S3AccessKey: os.Getenv("STORAGE_TEST_S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("STORAGE_TEST_S3_SECRET_KEY"),
+		S3Bucket:    "storage-test",
+		S3Region:    "us-east-1",
+		S3UseSSL:    os.Getenv("STORAGE_TEST_S3_USE_SSL") == "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testRoundTrip(t, store, "test-"+uuid.NewString()+"/")
+}